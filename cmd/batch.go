@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/AryaLabsHQ/agentree/internal/agent"
+	"github.com/AryaLabsHQ/agentree/internal/config"
+	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/internal/manifest"
+	"github.com/AryaLabsHQ/agentree/pkg/agentree"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fromFile    string
+	batchJobs   int
+	batchStrict bool
+)
+
+// batchResult is the outcome of one manifest item
+type batchResult struct {
+	item   manifest.Item
+	record *agentree.Worktree
+	output string
+	err    error
+}
+
+// syncBuffer collects the output of one item; setup scripts write their
+// stdout and stderr to it at the same time
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.TrimRight(b.buf.String(), " \t\n")
+}
+
+// runBatch creates the worktrees listed in --from-file. Every item is a
+// separate create, run a few at a time; failed items don't stop the others.
+func runBatch(cmd *cobra.Command) error {
+	for _, name := range []string{"branch", "task", "task-file", "issue", "interactive", "tmux", "cd", "dest", "checkout", "from-pr"} {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+			err := fmt.Errorf("--from-file can't be combined with --%s", name)
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+	}
+
+	items, err := manifest.Load(fromFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	repo, err := openRepository(cmd.Context())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	scopeDir, err := resolveScope(repo.Root, scope)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	layers, err := loadConfigs(repo.Root, scopeDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	mergedConfig := config.MergeLayers(append(layers, flagLayer(cmd))...)
+
+	// Fetch once for all items rather than once per item
+	refreshRemotes(repo, "")
+
+	jobs := min(max(batchJobs, 1), len(items))
+	fmt.Println(infoStyle.Render(fmt.Sprintf("Creating %d worktrees, %d at a time...", len(items), jobs)))
+
+	results := make([]batchResult, len(items))
+	slots := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	for i, item := range items {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			results[i] = createBatchItem(cmd, repo, mergedConfig, item)
+
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			if r := results[i]; r.err != nil {
+				fmt.Println(errorStyle.Render(fmt.Sprintf("✗ [%d/%d] %s: %v", done, len(items), item.Name(), r.err)))
+			} else {
+				fmt.Println(successStyle.Render(fmt.Sprintf("✓ [%d/%d] %s", done, len(items), r.record.Branch)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Summary in manifest order, then the output of every failed item
+	failed := 0
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tBRANCH\tAGENT\tPATH")
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+			fmt.Fprintf(w, "✗\t%s\t%s\t%v\n", r.item.Name(), valueOr(r.item.Agent, "-"), r.err)
+			continue
+		}
+		fmt.Fprintf(w, "✓\t%s\t%s\t%s\n", r.record.Branch, valueOr(r.record.Agent, "-"), r.record.Path)
+	}
+	w.Flush()
+
+	for _, r := range results {
+		if r.err != nil && r.output != "" {
+			fmt.Printf("\n%s\n", labelStyle.Render(r.item.Name()))
+			for _, line := range strings.Split(r.output, "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+
+	fmt.Println()
+	if failed == 0 {
+		fmt.Println(successStyle.Render(fmt.Sprintf("✅ Created %d worktrees", len(items))))
+		return nil
+	}
+	err = fmt.Errorf("%d of %d worktrees failed", failed, len(items))
+	if batchStrict {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	return nil
+}
+
+// createBatchItem creates the worktree of one item, collecting its output.
+// Item fields win over the flags shared by every item.
+func createBatchItem(cmd *cobra.Command, repo *git.Repository, cfg *config.Config, item manifest.Item) (result batchResult) {
+	result.item = item
+	output := &syncBuffer{}
+	defer func() { result.output = output.String() }()
+
+	agentFor := item.Agent
+	if agentFor == "" {
+		agentFor = cfg.Agent
+	}
+	var profile *agent.Profile
+	if agentFor != "" {
+		var err error
+		if profile, err = agent.Resolve(agentFor, cfg); err != nil {
+			result.err = err
+			return result
+		}
+	}
+	// Agents can't share the terminal, so they all run in the background
+	if launchAgent && (profile == nil || profile.Launch == "") {
+		result.err = errors.New("--launch needs an agent with a launch command")
+		return result
+	}
+
+	itemBase := item.Base
+	if itemBase == "" {
+		itemBase = base
+	}
+	scripts := item.Scripts
+	if len(scripts) == 0 {
+		scripts = customScripts
+	}
+	opts := agentree.Options{
+		Scope:         scope,
+		Branch:        item.Branch,
+		Base:          itemBase,
+		Task:          item.Task,
+		Ticket:        ticket,
+		Agent:         item.Agent,
+		SkipEnv:       !copyEnv,
+		SkipArtifacts: !copyArtifacts,
+		SkipSetup:     !runSetup,
+		Scripts:       scripts,
+		Push:          push || pr,
+		Offline:       true,
+		Settings:      flagSettings(cmd),
+		Verbose:       verbose,
+		Stdout:        output,
+		Stderr:        output,
+		OnEvent: func(event agentree.Event) {
+			writeEvent(output, output, event)
+		},
+	}
+	wt, err := agentree.Create(cmd.Context(), opts)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	result.record = wt
+
+	if pr {
+		if err := createPullRequest(wt.Path); err != nil {
+			fmt.Fprintf(output, "Warning: %v\n", err)
+		}
+	}
+	if launchAgent {
+		result.err = launchWorktreeAgent(repo, wt, profile, true)
+	}
+	return result
+}
@@ -20,13 +20,98 @@ func TestCommandStructure(t *testing.T) {
 		{
 			name:        "create command exists", 
 			commandName: "create",
-			hasFlags:    []string{"branch", "from", "push", "env"},
+			hasFlags:    []string{"branch", "from", "push", "env", "scope", "agent", "launch", "detach", "tmux", "cd", "task", "task-file", "issue", "from-file", "jobs", "strict", "checkout", "from-pr"},
 		},
 		{
 			name:        "remove command exists",
 			commandName: "rm",
 			hasFlags:    []string{"yes", "delete-branch"},
 		},
+		{
+			name:        "env plan command exists",
+			commandName: "env plan",
+			hasFlags:    []string{"show-keys"},
+		},
+		{
+			name:        "run command exists",
+			commandName: "run",
+			hasFlags:    []string{"agent", "detach"},
+		},
+		{
+			name:        "session command exists",
+			commandName: "session",
+			hasFlags:    []string{"no-attach"},
+		},
+		{
+			name:        "attach command exists",
+			commandName: "attach",
+			hasFlags:    []string{},
+		},
+		{
+			name:        "shell-init command exists",
+			commandName: "shell-init",
+			hasFlags:    []string{},
+		},
+		{
+			name:        "cd command exists",
+			commandName: "cd",
+			hasFlags:    []string{},
+		},
+		{
+			name:        "sync command exists",
+			commandName: "sync",
+			hasFlags:    []string{"onto"},
+		},
+		{
+			name:        "serve command exists",
+			commandName: "serve",
+			hasFlags:    []string{"socket", "listen", "token-file"},
+		},
+		{
+			name:        "mcp command exists",
+			commandName: "mcp",
+			hasFlags:    []string{},
+		},
+		{
+			name:        "path command exists",
+			commandName: "path",
+			hasFlags:    []string{},
+		},
+		{
+			name:        "fanout command exists",
+			commandName: "fanout",
+			hasFlags:    []string{"count", "agents", "agent", "task", "task-file", "issue", "from", "launch"},
+		},
+		{
+			name:        "compare command exists",
+			commandName: "compare",
+			hasFlags:    []string{"test"},
+		},
+		{
+			name:        "pick command exists",
+			commandName: "pick",
+			hasFlags:    []string{"yes"},
+		},
+		{
+			name:        "ls command exists",
+			commandName: "ls",
+			hasFlags:    []string{},
+		},
+		{
+			name:        "config migrate command exists",
+			commandName: "config migrate",
+			hasFlags:    []string{"global", "force", "dry-run"},
+		},
+		{
+			name:        "config set command exists",
+			commandName: "config set",
+			hasFlags:    []string{"global", "project"},
+		},
+		{
+			name:        "config explain command exists",
+			commandName: "config explain",
+			hasFlags:    []string{"env", "script"},
+		},
 	}
 	
 	for _, tt := range tests {
@@ -40,6 +125,40 @@ func TestCommandStructure(t *testing.T) {
 				cmd = createCmd
 			case "rm":
 				cmd = removeCmd
+			case "run":
+				cmd = runCmd
+			case "ls":
+				cmd = listCmd
+			case "session":
+				cmd = sessionCmd
+			case "attach":
+				cmd = attachCmd
+			case "shell-init":
+				cmd = shellInitCmd
+			case "cd":
+				cmd = cdCmd
+			case "path":
+				cmd = pathCmd
+			case "sync":
+				cmd = syncCmd
+			case "serve":
+				cmd = serveCmd
+			case "mcp":
+				cmd = mcpCmd
+			case "fanout":
+				cmd = fanoutCmd
+			case "compare":
+				cmd = compareCmd
+			case "pick":
+				cmd = pickCmd
+			case "env plan":
+				cmd = envPlanCmd
+			case "config migrate":
+				cmd = configMigrateCmd
+			case "config set":
+				cmd = configSetCmd
+			case "config explain":
+				cmd = configExplainCmd
 			}
 			
 			if cmd == nil {
@@ -4,18 +4,19 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/internal/agent"
+	"github.com/AryaLabsHQ/agentree/internal/config"
 	"github.com/spf13/cobra"
 )
 
-// getBranchCompletions returns all available git branches for completion
+// getBranchCompletions returns all local git branches for completion
 func getBranchCompletions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-	repo, err := git.NewRepository()
+	repo, err := openRepository(cmd.Context())
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveError
 	}
 
-	branches, err := repo.ListBranches()
+	branches, err := repo.LocalBranches()
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveError
 	}
@@ -31,9 +32,31 @@ func getBranchCompletions(cmd *cobra.Command, args []string, toComplete string)
 	return completions, cobra.ShellCompDirectiveNoFileComp
 }
 
+// getRefCompletions returns local and remote-tracking branches for flags
+// that take a branch to start from or check out
+func getRefCompletions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	repo, err := openRepository(cmd.Context())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	branches, err := repo.ListBranches()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var completions []string
+	for _, branch := range branches {
+		if strings.HasPrefix(branch, toComplete) {
+			completions = append(completions, branch)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
 // getWorktreeCompletions returns all existing worktrees for completion
 func getWorktreeCompletions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-	repo, err := git.NewRepository()
+	repo, err := openRepository(cmd.Context())
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveError
 	}
@@ -56,48 +79,39 @@ func getWorktreeCompletions(cmd *cobra.Command, args []string, toComplete string
 	return completions, cobra.ShellCompDirectiveNoFileComp
 }
 
-// Commented out for future use when agent types and config commands are implemented
-
-// // getAgentTypeCompletions returns available agent types for completion
-// func getAgentTypeCompletions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-// 	// These are placeholder agent types - will be expanded in the future
-// 	agentTypes := []string{
-// 		"claude",
-// 		"cursor",
-// 		"copilot",
-// 		"generic",
-// 	}
-
-// 	var completions []string
-// 	for _, agent := range agentTypes {
-// 		if strings.HasPrefix(agent, toComplete) {
-// 			completions = append(completions, agent)
-// 		}
-// 	}
-
-// 	return completions, cobra.ShellCompDirectiveNoFileComp
-// }
-
-// // getConfigKeyCompletions returns available configuration keys for completion
-// func getConfigKeyCompletions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-// 	// Configuration keys that can be set
-// 	configKeys := []string{
-// 		"defaultSetup",
-// 		"npmSetup",
-// 		"pnpmSetup",
-// 		"yarnSetup",
-// 		"postCreateScripts",
-// 		"env.enabled",
-// 		"env.includePatterns",
-// 		"env.excludePatterns",
-// 	}
-
-// 	var completions []string
-// 	for _, key := range configKeys {
-// 		if strings.HasPrefix(key, toComplete) {
-// 			completions = append(completions, key)
-// 		}
-// 	}
-
-// 	return completions, cobra.ShellCompDirectiveNoFileComp
-// }
\ No newline at end of file
+// getAgentTypeCompletions returns the built-in and configured agent profiles for completion
+func getAgentTypeCompletions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// Profiles from config files are included when the repository's config loads
+	var cfg *config.Config
+	if repo, err := openRepository(cmd.Context()); err == nil {
+		if layers, err := loadConfigs(repo.Root, ""); err == nil {
+			cfg = config.MergeLayers(layers...)
+		}
+	}
+
+	var completions []string
+	for _, name := range agent.Names(cfg) {
+		if strings.HasPrefix(name, toComplete) {
+			completions = append(completions, name)
+		}
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// getConfigKeyCompletions returns available configuration keys for completion
+func getConfigKeyCompletions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// Only the first argument is a key
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completions []string
+	for _, key := range config.KeyNames() {
+		if strings.HasPrefix(key, toComplete) {
+			completions = append(completions, key)
+		}
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
\ No newline at end of file
@@ -0,0 +1,446 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/AryaLabsHQ/agentree/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// configCmd groups commands that manage agentree configuration
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage agentree configuration",
+}
+
+// configMigrateCmd represents the config migrate command
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Convert .agentreerc into .agentree.toml",
+	Long: `Convert the legacy bash-style configuration into the structured TOML format.
+
+By default the project's .agentreerc is converted into .agentree.toml in the
+repository root. With --global, ~/.config/agentree/config is converted into
+~/.config/agentree/config.toml instead.
+
+Once the TOML file exists it takes precedence and the legacy file is ignored.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigMigrate,
+}
+
+// configGetCmd represents the config get command
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a configuration key",
+	Long: `Print the value of a configuration key.
+
+Without --global or --project the effective value after merging all layers
+is printed. List values are printed one per line.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: getConfigKeyCompletions,
+	RunE:              runConfigGet,
+}
+
+// configSetCmd represents the config set command
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>...",
+	Short: "Set a configuration key",
+	Long: `Set a configuration key in the project config (default) or the global config.
+
+List keys take any number of values, which replace the current list:
+
+  agentree config set env.include .env.example config/*.env
+  agentree config set --global env.default_strategy symlink
+
+The value is written to .agentree.toml or the global config.toml
+($AGENTREE_CONFIG if it names a .toml file).
+If only a legacy config exists, its settings are carried over into the new file.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: getConfigKeyCompletions,
+	RunE:              runConfigSet,
+}
+
+// configUnsetCmd represents the config unset command
+var configUnsetCmd = &cobra.Command{
+	Use:               "unset <key>",
+	Short:             "Remove a configuration key so lower layers apply",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: getConfigKeyCompletions,
+	RunE:              runConfigUnset,
+}
+
+// configListCmd represents the config list command
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configuration keys and values",
+	Long: `List every configuration key with its effective value.
+
+With --global or --project only the keys set in that file are listed.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigList,
+}
+
+// configExplainCmd represents the config explain command
+var configExplainCmd = &cobra.Command{
+	Use:   "explain [key]",
+	Short: "Show effective values and which layer set them",
+	Long: `Show the effective value of each configuration key and the layer it came from:
+default, system, global, project, env or flag.
+
+Pass the same flags you would pass to create to see how they change the result:
+
+  agentree config explain --env=false env.enabled`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: getConfigKeyCompletions,
+	RunE:              runConfigExplain,
+}
+
+var (
+	migrateGlobal bool
+	migrateForce  bool
+	migrateDryRun bool
+
+	scopeGlobal  bool
+	scopeProject bool
+)
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configMigrateCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configExplainCmd)
+
+	configMigrateCmd.Flags().BoolVar(&migrateGlobal, "global", false, "Migrate the global config instead of the project config")
+	configMigrateCmd.Flags().BoolVar(&migrateForce, "force", false, "Overwrite an existing TOML config")
+	configMigrateCmd.Flags().BoolVarP(&migrateDryRun, "dry-run", "n", false, "Print the converted config instead of writing it")
+
+	for _, c := range []*cobra.Command{configGetCmd, configSetCmd, configUnsetCmd, configListCmd} {
+		c.Flags().BoolVar(&scopeGlobal, "global", false, "Use the global config (~/.config/agentree)")
+		c.Flags().BoolVar(&scopeProject, "project", false, "Use the project config (.agentree.toml)")
+		c.MarkFlagsMutuallyExclusive("global", "project")
+	}
+
+	// The create flags that override configuration
+	configExplainCmd.Flags().BoolVarP(&copyEnv, "env", "e", true, "Copy .env and .dev.vars files")
+	configExplainCmd.Flags().StringArrayVarP(&customScripts, "script", "S", nil, "Custom post-create script")
+	configExplainCmd.Flags().StringVar(&scope, "scope", "", "Subdirectory whose config files apply (default: repository root)")
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	key, err := config.LookupKey(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	var cfg *config.Config
+	if scopeGlobal || scopeProject {
+		layer, err := loadScopeLayer(cmd, scopeGlobal)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+		if !layer.IsSet(key) {
+			return fmt.Errorf("%s is not set in the %s config", key.Name, layer.Name)
+		}
+		cfg = layer.Config
+	} else {
+		layers, err := loadEffectiveLayers(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+		cfg = config.MergeLayers(layers...)
+	}
+
+	for _, value := range key.Values(cfg) {
+		fmt.Println(value)
+	}
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	layer, err := loadScopeLayer(cmd, scopeGlobal)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	if err := layer.Set(args[0], args[1:]...); err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	return saveScopeLayer(layer, fmt.Sprintf("Set %s in %s", args[0], layer.Path))
+}
+
+func runConfigUnset(cmd *cobra.Command, args []string) error {
+	layer, err := loadScopeLayer(cmd, scopeGlobal)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	key, err := config.LookupKey(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	if !layer.IsSet(key) {
+		fmt.Println(infoStyle.Render(fmt.Sprintf("%s is not set in the %s config", key.Name, layer.Name)))
+		return nil
+	}
+
+	if err := layer.Unset(key.Name); err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	return saveScopeLayer(layer, fmt.Sprintf("Unset %s in %s", key.Name, layer.Path))
+}
+
+func runConfigList(cmd *cobra.Command, args []string) error {
+	if scopeGlobal || scopeProject {
+		layer, err := loadScopeLayer(cmd, scopeGlobal)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+		if layer.Source == "" {
+			fmt.Println(infoStyle.Render(fmt.Sprintf("No %s config file", layer.Name)))
+			return nil
+		}
+		fmt.Println(infoStyle.Render(fmt.Sprintf("# %s", layer.Source)))
+		for _, key := range layer.SetKeys() {
+			fmt.Printf("%s = %s\n", key.Name, key.Format(layer.Config))
+		}
+		return nil
+	}
+
+	layers, err := loadEffectiveLayers(cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	merged := config.MergeLayers(layers...)
+	for _, key := range config.Keys {
+		fmt.Printf("%s = %s  %s\n", key.Name, key.Format(merged), labelStyle.Render("# "+key.Description))
+	}
+	return nil
+}
+
+func runConfigExplain(cmd *cobra.Command, args []string) error {
+	var only *config.Key
+	if len(args) == 1 {
+		key, err := config.LookupKey(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+		only = key
+	}
+
+	layers, err := loadEffectiveLayers(cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	if only == nil {
+		for _, layer := range layers {
+			if layer.Source != "" {
+				fmt.Println(infoStyle.Render(fmt.Sprintf("%-8s %s", layer.Name, layer.Source)))
+			}
+		}
+		fmt.Println()
+	}
+
+	width := 0
+	for _, key := range config.Keys {
+		if len(key.Name) > width {
+			width = len(key.Name)
+		}
+	}
+
+	for _, explanation := range config.Explain(layers...) {
+		if only != nil && explanation.Key != only {
+			continue
+		}
+		fmt.Printf("%-*s  %s  %s\n", width, explanation.Key.Name, explanation.Value,
+			labelStyle.Render("("+strings.Join(explanation.Sources, " + ")+")"))
+	}
+
+	if only == nil || only.Name == "post_create_scripts" {
+		for _, pkg := range config.MergeLayers(layers...).PackageScripts {
+			quoted := make([]string, len(pkg.Scripts))
+			for i, script := range pkg.Scripts {
+				quoted[i] = strconv.Quote(script)
+			}
+			fmt.Printf("%-*s  [%s]  %s\n", width, "post_create_scripts", strings.Join(quoted, ", "),
+				labelStyle.Render("(project ("+pkg.Dir+"), runs there)"))
+		}
+	}
+	return nil
+}
+
+// loadScopeLayer loads the global or project layer for get, set, unset and list
+func loadScopeLayer(cmd *cobra.Command, global bool) (*config.Layer, error) {
+	if global {
+		layer, err := config.LoadGlobalLayer()
+		if err != nil {
+			return nil, fmt.Errorf("invalid global config: %w", err)
+		}
+		return layer, nil
+	}
+
+	repo, err := openRepository(cmd.Context())
+	if err != nil {
+		return nil, err
+	}
+	layer, err := config.LoadProjectLayer(repo.Root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project config: %w", err)
+	}
+	return layer, nil
+}
+
+// saveScopeLayer writes a modified layer and reports what happened
+func saveScopeLayer(layer *config.Layer, message string) error {
+	legacySource := layer.Source != "" && layer.Source != layer.Path
+
+	if err := layer.Save(); err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error writing %s: %v", layer.Path, err)))
+		return err
+	}
+
+	fmt.Println(successStyle.Render("✓ " + message))
+	if legacySource {
+		fmt.Println(infoStyle.Render("Existing legacy settings were carried over; the legacy file is now ignored"))
+	}
+	return nil
+}
+
+// loadEffectiveLayers loads every layer that applies in the current repository.
+// The project layer is skipped outside a git repository.
+func loadEffectiveLayers(cmd *cobra.Command) ([]*config.Layer, error) {
+	repoRoot, scopeDir := "", ""
+	if repo, err := openRepository(cmd.Context()); err == nil {
+		repoRoot = repo.Root
+		if scopeDir, err = resolveScope(repoRoot, scope); err != nil {
+			return nil, err
+		}
+	}
+
+	layers, err := loadConfigs(repoRoot, scopeDir)
+	if err != nil {
+		return nil, err
+	}
+	return append(layers, flagLayer(cmd)), nil
+}
+
+// flagLayer records configuration overridden by create flags on the command line
+func flagLayer(cmd *cobra.Command) *config.Layer {
+	layer := config.NewOverrideLayer(config.LayerFlag)
+	for key, values := range flagSettings(cmd) {
+		_ = layer.Set(key, values...)
+	}
+	return layer
+}
+
+// flagSettings returns the configuration keys set by create flags on the
+// command line
+func flagSettings(cmd *cobra.Command) map[string][]string {
+	settings := map[string][]string{}
+	if f := cmd.Flags().Lookup("env"); f != nil && f.Changed {
+		settings["env.enabled"] = []string{strconv.FormatBool(copyEnv)}
+	}
+	if f := cmd.Flags().Lookup("script"); f != nil && f.Changed {
+		settings["post_create_scripts"] = customScripts
+	}
+	if f := cmd.Flags().Lookup("agent"); f != nil && f.Changed {
+		settings["agent"] = []string{agentName}
+	}
+	return settings
+}
+
+func runConfigMigrate(cmd *cobra.Command, args []string) error {
+	var legacyPath, tomlPath string
+	var legacyConfig *config.Config
+	var err error
+
+	if migrateGlobal {
+		configDir, err := config.GlobalConfigDir()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+		legacyPath = filepath.Join(configDir, config.LegacyGlobalConfigFile)
+		tomlPath = filepath.Join(configDir, config.GlobalConfigFile)
+	} else {
+		repo, err := openRepository(cmd.Context())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+		legacyPath = filepath.Join(repo.Root, config.LegacyProjectConfigFile)
+		tomlPath = filepath.Join(repo.Root, config.ProjectConfigFile)
+	}
+
+	if _, err := os.Stat(legacyPath); err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: nothing to migrate, %s not found", legacyPath)))
+		return fmt.Errorf("legacy config not found")
+	}
+
+	if _, err := os.Stat(tomlPath); err == nil && !migrateForce && !migrateDryRun {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %s already exists (use --force to overwrite)", tomlPath)))
+		return fmt.Errorf("destination exists")
+	}
+
+	if migrateGlobal {
+		legacyConfig, err = config.LoadLegacyGlobalConfig(legacyPath)
+	} else {
+		legacyConfig, err = config.LoadLegacyProjectConfig(legacyPath)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error reading %s: %v", legacyPath, err)))
+		return err
+	}
+
+	data, err := config.EncodeTOML(legacyConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	if migrateDryRun {
+		fmt.Print(string(data))
+		return nil
+	}
+
+	if err := os.WriteFile(tomlPath, data, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error writing %s: %v", tomlPath, err)))
+		return err
+	}
+
+	fmt.Println(successStyle.Render(fmt.Sprintf("✅ Migrated %s to %s", legacyPath, tomlPath)))
+	fmt.Println(infoStyle.Render(fmt.Sprintf("%s is now ignored and can be deleted", filepath.Base(legacyPath))))
+	return nil
+}
+
+// loadConfigs loads every configuration layer for the repository and the
+// config files along scopeDir.
+// Unlike a missing file, an invalid one is an error so typos don't go unnoticed.
+func loadConfigs(repoRoot, scopeDir string) ([]*config.Layer, error) {
+	return config.LoadLayers(repoRoot, scopeDir)
+}
+
+// resolveScope returns the subdirectory, relative to the repository root,
+// whose nested config files apply; see config.ResolveScope
+func resolveScope(repoRoot, scope string) (string, error) {
+	return config.ResolveScope(repoRoot, scope)
+}
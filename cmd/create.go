@@ -1,18 +1,23 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 
+	"github.com/AryaLabsHQ/agentree/internal/agent"
 	"github.com/AryaLabsHQ/agentree/internal/config"
-	"github.com/AryaLabsHQ/agentree/internal/detector"
-	"github.com/AryaLabsHQ/agentree/internal/env"
 	"github.com/AryaLabsHQ/agentree/internal/git"
-	"github.com/AryaLabsHQ/agentree/internal/scripts"
+	"github.com/AryaLabsHQ/agentree/internal/launch"
+	"github.com/AryaLabsHQ/agentree/internal/metadata"
+	"github.com/AryaLabsHQ/agentree/internal/shell"
+	"github.com/AryaLabsHQ/agentree/internal/tmux"
+	"github.com/AryaLabsHQ/agentree/internal/tracker"
 	"github.com/AryaLabsHQ/agentree/internal/tui"
+	"github.com/AryaLabsHQ/agentree/pkg/agentree"
 	"github.com/spf13/cobra"
 )
 
@@ -28,6 +33,19 @@ var (
 	interactive   bool
 	customScripts []string
 	verbose       bool
+	copyArtifacts bool
+	scope         string
+	ticket        string
+	agentName     string
+	launchAgent   bool
+	useTmux       bool
+	changeDir     bool
+	taskText      string
+	taskFile      string
+	issueNumber   int
+	groupID       string
+	checkout      string
+	fromPR        int
 )
 
 // createCmd represents the create command
@@ -36,8 +54,10 @@ var createCmd = &cobra.Command{
 	Short: "Create a new worktree",
 	Long: `Create a new Git worktree with an isolated branch.
 
-By default, branches are prefixed with 'agent/' unless they already contain a slash.
-The worktree is created in a sibling directory named <repo>-worktrees.`,
+A branch name without a slash is expanded with the branch_template setting,
+which defaults to 'agent/{slug}'; names with a slash are used as given.
+Worktrees go where worktree_root and worktree_path_template say, by default
+a sibling directory named <repo>-worktrees.`,
 	RunE: runCreate,
 }
 
@@ -55,13 +75,31 @@ func init() {
 	createCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Interactive wizard to guide through setup")
 	createCmd.Flags().StringArrayVarP(&customScripts, "script", "S", nil, "Custom post-create script (can be used multiple times)")
 	createCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show detailed environment discovery process")
-
-	// Make branch required unless in interactive mode
-	_ = createCmd.MarkFlagRequired("branch")
+	createCmd.Flags().BoolVar(&copyArtifacts, "artifacts", true, "Copy untracked artifacts matching ARTIFACT_PATTERNS")
+	createCmd.Flags().StringVar(&scope, "scope", "", "Subdirectory whose config files apply (default: repository root)")
+	createCmd.Flags().StringVar(&ticket, "ticket", "", "Ticket reference for the {ticket} branch template variable")
+	createCmd.Flags().StringVar(&agentName, "agent", "", "Agent profile to prepare the worktree for (claude, cursor, aider, codex, generic)")
+	createCmd.Flags().BoolVar(&launchAgent, "launch", false, "Start the agent in the worktree once it is ready")
+	createCmd.Flags().BoolVar(&detachAgent, "detach", false, "With --launch, run the agent in the background")
+	createCmd.Flags().BoolVar(&useTmux, "tmux", false, "Open a tmux window with agent, shell and dev server panes")
+	createCmd.Flags().BoolVar(&changeDir, "cd", false, "Change the current shell into the worktree (needs 'agentree shell-init')")
+	createCmd.Flags().StringVar(&taskText, "task", "", "What the worktree is for; names the branch when -b isn't given")
+	createCmd.Flags().StringVar(&taskFile, "task-file", "", "Read the task from a file ('-' for stdin)")
+	createCmd.Flags().IntVar(&issueNumber, "issue", 0, "Take the task from an issue in the configured tracker")
+	createCmd.MarkFlagsMutuallyExclusive("task", "task-file", "issue")
+	createCmd.Flags().StringVar(&checkout, "checkout", "", "Check out an existing local or remote branch instead of creating one")
+	createCmd.Flags().IntVar(&fromPR, "from-pr", 0, "Check out a pull request, fetched from origin")
+	createCmd.MarkFlagsMutuallyExclusive("branch", "checkout", "from-pr")
+	createCmd.Flags().StringVar(&groupID, "group", "", "Fanout group the worktree belongs to")
+	_ = createCmd.Flags().MarkHidden("group")
+	createCmd.Flags().StringVar(&fromFile, "from-file", "", "Create the worktrees listed in a YAML or JSON Lines file")
+	createCmd.Flags().IntVarP(&batchJobs, "jobs", "j", 4, "With --from-file, how many worktrees to create at a time")
+	createCmd.Flags().BoolVar(&batchStrict, "strict", false, "With --from-file, exit with an error when any worktree fails")
 	
 	// Register custom completion functions
-	_ = createCmd.RegisterFlagCompletionFunc("from", getBranchCompletions)
-	_ = createCmd.RegisterFlagCompletionFunc("base", getBranchCompletions)
+	_ = createCmd.RegisterFlagCompletionFunc("from", getRefCompletions)
+	_ = createCmd.RegisterFlagCompletionFunc("checkout", getRefCompletions)
+	_ = createCmd.RegisterFlagCompletionFunc("agent", getAgentTypeCompletions)
 }
 
 // For backward compatibility, also make flags available at root level
@@ -77,11 +115,32 @@ func init() {
 	rootCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Interactive wizard")
 	rootCmd.Flags().StringArrayVarP(&customScripts, "script", "S", nil, "Custom post-create script")
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show detailed discovery process")
+	rootCmd.Flags().BoolVar(&copyArtifacts, "artifacts", true, "Copy untracked artifacts")
+	rootCmd.Flags().StringVar(&scope, "scope", "", "Subdirectory whose config files apply")
+	rootCmd.Flags().StringVar(&ticket, "ticket", "", "Ticket reference for the branch template")
+	rootCmd.Flags().StringVar(&agentName, "agent", "", "Agent profile for the worktree")
+	rootCmd.Flags().BoolVar(&launchAgent, "launch", false, "Start the agent once the worktree is ready")
+	rootCmd.Flags().BoolVar(&detachAgent, "detach", false, "With --launch, run the agent in the background")
+	rootCmd.Flags().BoolVar(&useTmux, "tmux", false, "Open a tmux window for the worktree")
+	rootCmd.Flags().BoolVar(&changeDir, "cd", false, "Change the current shell into the worktree")
+	rootCmd.Flags().StringVar(&taskText, "task", "", "What the worktree is for")
+	rootCmd.Flags().StringVar(&taskFile, "task-file", "", "Read the task from a file")
+	rootCmd.Flags().IntVar(&issueNumber, "issue", 0, "Take the task from an issue")
+	rootCmd.MarkFlagsMutuallyExclusive("task", "task-file", "issue")
+	rootCmd.Flags().StringVar(&checkout, "checkout", "", "Check out an existing branch")
+	rootCmd.Flags().IntVar(&fromPR, "from-pr", 0, "Check out a pull request")
+	rootCmd.MarkFlagsMutuallyExclusive("branch", "checkout", "from-pr")
+	rootCmd.Flags().StringVar(&fromFile, "from-file", "", "Create the worktrees listed in a file")
+	rootCmd.Flags().IntVarP(&batchJobs, "jobs", "j", 4, "With --from-file, worktrees to create at a time")
+	rootCmd.Flags().BoolVar(&batchStrict, "strict", false, "With --from-file, fail when any worktree fails")
+	_ = rootCmd.RegisterFlagCompletionFunc("agent", getAgentTypeCompletions)
+	_ = rootCmd.RegisterFlagCompletionFunc("from", getRefCompletions)
+	_ = rootCmd.RegisterFlagCompletionFunc("checkout", getRefCompletions)
 
 	// If root command is called with flags, run create
 	rootCmd.RunE = func(cmd *cobra.Command, args []string) error {
 		// Check if any create flags are set
-		if branch != "" || interactive || cmd.Flags().Changed("branch") {
+		if branch != "" || interactive || cmd.Flags().Changed("branch") || hasTask(cmd) || fromFile != "" || checkout != "" || fromPR != 0 {
 			return runCreate(cmd, args)
 		}
 		// Otherwise show help
@@ -90,8 +149,51 @@ func init() {
 }
 
 func runCreate(cmd *cobra.Command, args []string) error {
+	if fromFile != "" {
+		return runBatch(cmd)
+	}
+
 	// Create repository instance
-	repo, err := git.NewRepository()
+	repo, err := openRepository(cmd.Context())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	// Load configuration up front so an invalid file fails before anything is created
+	scopeDir, err := resolveScope(repo.Root, scope)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	layers, err := loadConfigs(repo.Root, scopeDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	mergedConfig := config.MergeLayers(append(layers, flagLayer(cmd))...)
+
+	// The agent profile comes from --agent or the agent setting
+	var profile *agent.Profile
+	if mergedConfig.Agent != "" {
+		if profile, err = agent.Resolve(mergedConfig.Agent, mergedConfig); err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+	}
+	if launchAgent && (profile == nil || profile.Launch == "") {
+		err := fmt.Errorf("--launch needs an agent with a launch command; pass --agent or set one with 'agentree config set agent <name>'")
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	if useTmux && !tmux.Available() {
+		err := fmt.Errorf("--tmux needs tmux to be installed")
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	task, issue, err := resolveTask(repo, mergedConfig)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
 		return err
@@ -111,11 +213,13 @@ func runCreate(cmd *cobra.Command, args []string) error {
 			return err
 		}
 
-		// Get default directory for display
-		worktreeDir := repo.GetDefaultWorktreeDir()
-		defaultDest := filepath.Join(worktreeDir, "agent-{branch-name}")
+		layout, err := agentree.Layout(cmd.Context(), agentree.Options{Scope: scope, Settings: flagSettings(cmd)})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
 
-		options, err := tui.RunWizard(branches, currentBranch, defaultDest)
+		options, err := tui.RunWizard(branches, currentBranch, layout)
 		if err != nil {
 			if err == tui.ErrWizardCancelled {
 				// User cancelled - exit gracefully without error message
@@ -137,9 +241,10 @@ func runCreate(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Validate branch
-	if branch == "" {
-		fmt.Fprintln(os.Stderr, errorStyle.Render("Error: -b/--branch is required"))
+	// Without a branch name the task names the branch
+	existing := checkout != "" || fromPR != 0
+	if branch == "" && task == "" && !existing {
+		fmt.Fprintln(os.Stderr, errorStyle.Render("Error: -b/--branch or --task is required"))
 		return fmt.Errorf("branch name required")
 	}
 
@@ -148,170 +253,154 @@ func runCreate(cmd *cobra.Command, args []string) error {
 		push = true
 	}
 
-	// Add agent/ prefix if no slash
-	if !strings.Contains(branch, "/") {
-		branch = "agent/" + branch
+	opts := agentree.Options{
+		Scope:         scope,
+		Branch:        branch,
+		Base:          base,
+		Checkout:      checkout,
+		PullRequest:   fromPR,
+		Dest:          dest,
+		Task:          task,
+		Ticket:        ticket,
+		Group:         groupID,
+		SkipEnv:       !copyEnv,
+		SkipArtifacts: !copyArtifacts,
+		SkipSetup:     !runSetup,
+		Scripts:       customScripts,
+		Push:          push,
+		Offline:       offline,
+		Fetch:         forceFetch,
+		Settings:      flagSettings(cmd),
+		Verbose:       verbose,
+		Stdout:        os.Stdout,
+		Stderr:        os.Stderr,
+		OnEvent: func(event agentree.Event) {
+			if event.Type != agentree.EventCreated {
+				printEvent(event)
+				return
+			}
+			wt := event.Worktree
+			fmt.Println(successStyle.Render("✅ Worktree ready:"))
+			fmt.Printf("    %s %s\n", labelStyle.Render("path"), wt.Path)
+			if existing {
+				fmt.Printf("    %s %s\n", labelStyle.Render("branch"), wt.Branch)
+			} else {
+				fmt.Printf("    %s %s (from %s)\n", labelStyle.Render("branch"), wt.Branch, wt.Base)
+			}
+		},
+	}
+	if issue != nil {
+		opts.Issue = issue.Number
+	}
+	wt, err := agentree.Create(cmd.Context(), opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
 	}
 
-	// Set default base branch if not specified
-	if base == "" {
-		base, err = repo.CurrentBranch()
+	// Create PR if requested
+	if pr {
+		fmt.Println(infoStyle.Render("Creating GitHub PR..."))
+		if err := createPullRequest(wt.Path); err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("⚠️  %v", err)))
+		}
+	}
+
+	// The shell wrapper switches directory once agentree exits, so this also
+	// holds after a foreground agent or tmux session ends
+	if changeDir {
+		if err := shell.ChangeDir(wt.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	// In tmux the agent starts in the window's first pane instead
+	if useTmux {
+		client := tmux.New(mergedConfig.TmuxConfig.Socket)
+		window, err := openTmuxWindow(client, repo, mergedConfig, git.WorktreeInfo{Path: wt.Path, Branch: wt.Branch})
 		if err != nil {
-			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error getting current branch: %v", err)))
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
 			return err
 		}
+		if !isTerminal(os.Stdin) {
+			fmt.Println(infoStyle.Render(fmt.Sprintf("Attach with: agentree attach %s", wt.Branch)))
+			return nil
+		}
+		return client.Attach(window)
 	}
 
-	// Determine destination directory
-	if dest == "" {
-		worktreeDir := repo.GetDefaultWorktreeDir()
-		if err := os.MkdirAll(worktreeDir, 0755); err != nil {
-			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error creating worktree directory: %v", err)))
+	if launchAgent {
+		if err := launchWorktreeAgent(repo, wt, profile, detachAgent); err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
 			return err
 		}
-
-		sanitized := strings.ReplaceAll(branch, "/", "-")
-		dest = filepath.Join(worktreeDir, sanitized)
+	} else if profile != nil && profile.Launch != "" {
+		fmt.Println(infoStyle.Render(fmt.Sprintf("Start %s with: agentree run %s", profile.Name, wt.Branch)))
 	}
 
-	// Check if destination already exists
-	if _, err := os.Stat(dest); err == nil {
-		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: Destination %s already exists", dest)))
-		return fmt.Errorf("destination exists")
+	return nil
+}
+
+// createPullRequest opens a pull request for the branch of the worktree at
+// dir with gh, filled in from its commits
+func createPullRequest(dir string) error {
+	if _, err := exec.LookPath("gh"); err != nil {
+		return errors.New("gh CLI not found; skipping PR creation")
 	}
+	prCmd := exec.Command("gh", "pr", "create", "--fill", "--web")
+	prCmd.Dir = dir
+	if output, err := prCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("creating the PR failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
 
-	// Create the worktree
-	fmt.Println(infoStyle.Render("Creating worktree..."))
-	if err := repo.CreateWorktree(branch, base, dest); err != nil {
-		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+// launchWorktreeAgent starts the agent of profile in the new worktree wt
+func launchWorktreeAgent(repo *git.Repository, wt *agentree.Worktree, profile *agent.Profile, background bool) error {
+	store, err := openStore(repo)
+	if err != nil {
 		return err
 	}
-
-	fmt.Println(successStyle.Render("✅ Worktree ready:"))
-	fmt.Printf("    %s %s\n", labelStyle.Render("path"), dest)
-	fmt.Printf("    %s %s (from %s)\n", labelStyle.Render("branch"), branch, base)
-
-	// Copy environment files if requested
-	if copyEnv {
-		// Load configurations and merge them
-		projectConfig, _ := config.LoadProjectConfig(repo.Root)
-		globalConfig, _ := config.LoadGlobalConfig()
-		mergedConfig := config.MergeConfig(globalConfig, projectConfig)
-		
-		// Check if env copying is enabled in config
-		if !mergedConfig.EnvConfig.Enabled {
-			fmt.Println(infoStyle.Render("Environment file copying disabled by configuration"))
-		} else {
-			// Use the enhanced copier with configuration
-			copier := env.NewEnvFileCopier(repo.Root, dest)
-			copier.SetVerbose(verbose)
-			
-			// Add custom patterns from config
-			if len(mergedConfig.EnvConfig.IncludePatterns) > 0 {
-				copier.AddCustomPatterns(mergedConfig.EnvConfig.IncludePatterns)
-			}
-			
-			// Discover files based on .gitignore and patterns
-			fmt.Println(infoStyle.Render("Discovering environment files..."))
-			files, err := copier.DiscoverFiles()
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: Error discovering files: %v\n", err)
-				files = []string{} // Continue with empty list instead of failing
-			}
-			
-			if len(files) > 0 {
-				// Filter out excluded patterns
-				var filteredFiles []string
-				for _, file := range files {
-					excluded := false
-					for _, pattern := range mergedConfig.EnvConfig.ExcludePatterns {
-						if matched, _ := filepath.Match(pattern, file); matched {
-							excluded = true
-							break
-						}
-					}
-					if !excluded {
-						filteredFiles = append(filteredFiles, file)
-					}
-				}
-				
-				// Copy the filtered files
-				if len(filteredFiles) > 0 {
-					copiedFiles, err := copier.CopyFiles(filteredFiles)
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "Warning: Some files couldn't be copied: %v\n", err)
-					}
-					for _, file := range copiedFiles {
-						fmt.Printf("📋 Copied %s\n", file)
-					}
-				} else {
-					fmt.Println(infoStyle.Render("No environment files found to copy"))
-				}
-			} else {
-				fmt.Println(infoStyle.Render("No environment files found to copy"))
-			}
-		}
+	record, err := store.Get(wt.Branch)
+	if err != nil {
+		record = &metadata.Worktree{Branch: wt.Branch, Path: wt.Path, Base: wt.Base, Task: wt.Task, CreatedAt: wt.CreatedAt}
 	}
+	target := launch.Target{Dir: wt.Path, RepoRoot: repo.Root, Branch: wt.Branch, Base: wt.Base, Agent: profile.Name, Task: wt.Task}
+	return startAgent(store, record, target, profile, nil, background)
+}
 
-	// Run post-create scripts if requested
-	if runSetup || len(customScripts) > 0 {
-		projectConfig, _ := config.LoadProjectConfig(repo.Root)
-		globalConfig, _ := config.LoadGlobalConfig()
-
-		detectedScripts := detector.DetectSetupCommands(dest)
-
-		var globalOverride string
-		if len(detectedScripts) > 0 {
-			if strings.Contains(detectedScripts[0], "pnpm") && globalConfig.PnpmSetup != "" {
-				globalOverride = globalConfig.PnpmSetup
-			} else if strings.Contains(detectedScripts[0], "npm") && globalConfig.NpmSetup != "" {
-				globalOverride = globalConfig.NpmSetup
-			} else if strings.Contains(detectedScripts[0], "yarn") && globalConfig.YarnSetup != "" {
-				globalOverride = globalConfig.YarnSetup
-			} else if globalConfig.DefaultSetup != "" {
-				globalOverride = globalConfig.DefaultSetup
-			}
-		}
+// hasTask reports whether a task flag was given
+func hasTask(cmd *cobra.Command) bool {
+	return cmd.Flags().Changed("task") || cmd.Flags().Changed("task-file") || cmd.Flags().Changed("issue")
+}
 
-		scriptsToRun := scripts.DetermineScripts(
-			customScripts,
-			projectConfig.PostCreateScripts,
-			detectedScripts,
-			globalOverride,
-		)
-
-		runner := scripts.NewRunner(dest)
-		if err := runner.RunScripts(scriptsToRun); err != nil {
-			// Log error but don't fail the command
-			fmt.Fprintf(os.Stderr, "Warning: Some post-create scripts failed: %v\n", err)
+// resolveTask returns the task given with --task, --task-file or --issue,
+// and the issue it came from
+func resolveTask(repo *git.Repository, cfg *config.Config) (string, *tracker.Issue, error) {
+	switch {
+	case taskFile == "-":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read the task: %w", err)
 		}
-	}
-
-	// Push to origin if requested
-	if push {
-		fmt.Println(infoStyle.Render("Pushing to origin..."))
-		pushCmd := exec.Command("git", "push", "-u", "origin", branch)
-		pushCmd.Dir = dest
-		if output, err := pushCmd.CombinedOutput(); err != nil {
-			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error pushing: %s", output)))
-			return err
+		return strings.TrimSpace(string(data)), nil, nil
+	case taskFile != "":
+		data, err := os.ReadFile(taskFile)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read the task: %w", err)
 		}
-		fmt.Println(successStyle.Render("✓ Pushed to origin"))
-	}
-
-	// Create PR if requested
-	if pr {
-		fmt.Println(infoStyle.Render("Creating GitHub PR..."))
-		prCmd := exec.Command("gh", "pr", "create", "--fill", "--web")
-		prCmd.Dir = dest
-		if output, err := prCmd.CombinedOutput(); err != nil {
-			if _, lookupErr := exec.LookPath("gh"); lookupErr != nil {
-				fmt.Fprintln(os.Stderr, errorStyle.Render("⚠️  gh CLI not found; skipping PR creation"))
-			} else {
-				fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error creating PR: %s", string(output))))
-			}
+		return strings.TrimSpace(string(data)), nil, nil
+	case issueNumber != 0:
+		issues, err := tracker.New(cfg.TrackerConfig.Kind, cfg.TrackerConfig.Dir, repo.Root)
+		if err != nil {
+			return "", nil, err
+		}
+		issue, err := issues.Issue(issueNumber)
+		if err != nil {
+			return "", nil, err
 		}
+		fmt.Println(infoStyle.Render(fmt.Sprintf("📋 Issue #%d: %s", issue.Number, issue.Title)))
+		return issue.Task(), issue, nil
 	}
-
-	return nil
+	return strings.TrimSpace(taskText), nil, nil
 }
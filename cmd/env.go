@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AryaLabsHQ/agentree/internal/config"
+	"github.com/AryaLabsHQ/agentree/internal/env"
+	"github.com/spf13/cobra"
+)
+
+// envCmd groups commands that inspect environment file handling
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Inspect environment file handling",
+}
+
+// envPlanCmd represents the env plan command
+var envPlanCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show which environment files a new worktree would receive",
+	Long: `Show which environment files a new worktree would receive and how.
+
+Each file is listed with the strategy that applies to it: copy, symlink
+or hardlink. Secret files are marked, and their values are never printed.
+Use --show-keys to list the variable names of each file with masked values.`,
+	Args: cobra.NoArgs,
+	RunE: runEnvPlan,
+}
+
+var showKeys bool
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+	envCmd.AddCommand(envPlanCmd)
+
+	envPlanCmd.Flags().BoolVar(&showKeys, "show-keys", false, "List variable names with masked values")
+}
+
+func runEnvPlan(cmd *cobra.Command, args []string) error {
+	repo, err := openRepository(cmd.Context())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	scopeDir, err := resolveScope(repo.Root, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	layers, err := loadConfigs(repo.Root, scopeDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	mergedConfig := config.MergeLayers(layers...)
+
+	// The destination is never written to, only used to describe the plan
+	copier, err := env.NewConfiguredCopier(repo.Root, repo.GetDefaultWorktreeDir(), &mergedConfig.EnvConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	// Worktrees inside the repository are never copied from
+	worktrees, err := repo.Worktrees()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	var skip []string
+	for _, wt := range worktrees {
+		if wt.Path != repo.Root {
+			skip = append(skip, wt.Path)
+		}
+	}
+	copier.SetSkipDirs(skip)
+
+	var files []string
+	if mergedConfig.EnvConfig.Enabled {
+		files, err = copier.DiscoverFiles()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error discovering files: %v", err)))
+			return err
+		}
+		files = env.ExcludeFiles(files, mergedConfig.EnvConfig.ExcludePatterns)
+	} else {
+		fmt.Println(infoStyle.Render("Environment file copying disabled by configuration"))
+	}
+
+	tracked, err := repo.TrackedFiles(repo.Root)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	artifacts, err := copier.DiscoverArtifacts(env.ArtifactOptions{
+		Patterns:     mergedConfig.ArtifactConfig.Patterns,
+		MaxFileSize:  mergedConfig.ArtifactConfig.MaxFileSize,
+		MaxTotalSize: mergedConfig.ArtifactConfig.MaxTotalSize,
+		Tracked:      tracked,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error discovering artifacts: %v", err)))
+		return err
+	}
+
+	if len(files) == 0 && len(artifacts) == 0 {
+		fmt.Println(infoStyle.Render("No environment files or artifacts found to copy"))
+		return nil
+	}
+
+	symlinkMode := "absolute"
+	if mergedConfig.EnvConfig.SymlinkMode == "relative" {
+		symlinkMode = "relative"
+	}
+
+	if len(files) > 0 {
+		fmt.Println(infoStyle.Render(fmt.Sprintf("Environment plan for %s:", repo.Root)))
+		printPlanEntries(copier, repo.Root, files, symlinkMode)
+	}
+	if len(artifacts) > 0 {
+		fmt.Println(infoStyle.Render("Artifacts:"))
+		printPlanEntries(copier, repo.Root, artifacts, symlinkMode)
+	}
+
+	return nil
+}
+
+// printPlanEntries prints one line per file with its strategy, masking secret values
+func printPlanEntries(copier *env.EnvFileCopier, root string, files []string, symlinkMode string) {
+	width := 0
+	for _, file := range files {
+		if len(file) > width {
+			width = len(file)
+		}
+	}
+
+	for _, file := range files {
+		strategy := string(copier.StrategyFor(file))
+		if strategy == string(env.StrategySymlink) {
+			strategy += " (" + symlinkMode + ")"
+		}
+
+		marker := ""
+		if env.IsSecretFile(file) {
+			marker = " 🔒"
+		}
+
+		fmt.Printf("    %-*s  %s%s\n", width, file, labelStyle.Render(strategy), marker)
+
+		if showKeys && env.IsSecretFile(file) {
+			data, err := os.ReadFile(filepath.Join(root, file))
+			if err != nil {
+				continue
+			}
+			for _, line := range strings.Split(env.RedactContent(string(data)), "\n") {
+				if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+					fmt.Printf("        %s\n", labelStyle.Render(line))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/AryaLabsHQ/agentree/pkg/agentree"
+)
+
+// printEvent shows the progress of an agentree operation on the terminal
+func printEvent(event agentree.Event) {
+	writeEvent(os.Stdout, os.Stderr, event)
+}
+
+// writeEvent renders an event like printEvent, to stdout and stderr
+func writeEvent(stdout, stderr io.Writer, event agentree.Event) {
+	switch event.Type {
+	case agentree.EventProgress:
+		fmt.Fprintln(stdout, infoStyle.Render(event.Message))
+	case agentree.EventDone, agentree.EventCreated:
+		fmt.Fprintln(stdout, successStyle.Render(event.Message))
+	case agentree.EventWarning:
+		fmt.Fprintf(stderr, "Warning: %s\n", event.Message)
+	default:
+		fmt.Fprintln(stdout, event.Message)
+	}
+}
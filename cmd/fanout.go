@@ -0,0 +1,541 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/AryaLabsHQ/agentree/internal/agent"
+	"github.com/AryaLabsHQ/agentree/internal/config"
+	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/internal/metadata"
+	"github.com/AryaLabsHQ/agentree/internal/naming"
+	"github.com/AryaLabsHQ/agentree/pkg/agentree"
+	"github.com/spf13/cobra"
+)
+
+// fanoutCmd represents the fanout command
+var fanoutCmd = &cobra.Command{
+	Use:   "fanout",
+	Short: "Give one task to several agents in sibling worktrees",
+	Long: `Create several worktrees from the same base for the same task, so
+different agents, or several runs of one agent, can try it side by side.
+
+The branches share a name with a -1, -2, ... suffix, and the worktrees
+share a group ID. Compare the results with 'agentree compare <group>' and
+keep the best one with 'agentree pick <branch>'.
+
+With --agents the worktrees go to the listed agents in turn; -n defaults
+to the number of agents then.
+
+Examples:
+  agentree fanout -n 3 --task "Fix the login redirect" --agent claude
+  agentree fanout --agents claude,codex,aider --issue 42 --launch`,
+	Args: cobra.NoArgs,
+	RunE: runFanout,
+}
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare <group>",
+	Short: "Compare the worktrees of a fanout side by side",
+	Long: `Show the worktrees of a fanout group side by side: commits, diffstat
+against the base, test results and agent status, followed by the files
+each one changed.
+
+Tests run when --test or test_command is set; a worktree passes when the
+command exits with 0.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCompare,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return getGroupCompletions(cmd, toComplete), cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// pickCmd represents the pick command
+var pickCmd = &cobra.Command{
+	Use:   "pick <branch|path>",
+	Short: "Keep one fanout result and remove the others",
+	Long: `Keep the given worktree and remove the other worktrees of its fanout
+group together with their branches. Dirty worktrees are removed as well.`,
+	Args:              cobra.ExactArgs(1),
+	RunE:              runPick,
+	ValidArgsFunction: worktreeArgCompletions,
+}
+
+var (
+	fanoutCount  int
+	fanoutAgents []string
+	compareTest  string
+	pickYes      bool
+)
+
+func init() {
+	rootCmd.AddCommand(fanoutCmd)
+	rootCmd.AddCommand(compareCmd)
+	rootCmd.AddCommand(pickCmd)
+
+	fanoutCmd.Flags().IntVarP(&fanoutCount, "count", "n", 3, "Number of worktrees")
+	fanoutCmd.Flags().StringSliceVar(&fanoutAgents, "agents", nil, "Agents to spread the worktrees over, e.g. claude,codex")
+	fanoutCmd.Flags().StringVar(&agentName, "agent", "", "Agent for every worktree")
+	fanoutCmd.Flags().StringVarP(&branch, "branch", "b", "", "Branch name before the suffix (default: from the task)")
+	fanoutCmd.Flags().StringVarP(&base, "from", "f", "", "Base branch to fork from (default: current branch)")
+	fanoutCmd.Flags().StringVar(&taskText, "task", "", "What the agents should do")
+	fanoutCmd.Flags().StringVar(&taskFile, "task-file", "", "Read the task from a file ('-' for stdin)")
+	fanoutCmd.Flags().IntVar(&issueNumber, "issue", 0, "Take the task from an issue in the configured tracker")
+	fanoutCmd.Flags().BoolVarP(&copyEnv, "env", "e", true, "Copy .env and .dev.vars files")
+	fanoutCmd.Flags().BoolVar(&copyArtifacts, "artifacts", true, "Copy untracked artifacts matching ARTIFACT_PATTERNS")
+	fanoutCmd.Flags().BoolVarP(&runSetup, "setup", "s", true, "Run setup scripts")
+	fanoutCmd.Flags().StringArrayVarP(&customScripts, "script", "S", nil, "Custom post-create script (can be used multiple times)")
+	fanoutCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show detailed environment discovery process")
+	fanoutCmd.Flags().BoolVar(&launchAgent, "launch", false, "Start every agent in the background")
+	fanoutCmd.MarkFlagsMutuallyExclusive("task", "task-file", "issue")
+	fanoutCmd.MarkFlagsMutuallyExclusive("agent", "agents")
+	_ = fanoutCmd.RegisterFlagCompletionFunc("agent", getAgentTypeCompletions)
+	_ = fanoutCmd.RegisterFlagCompletionFunc("agents", getAgentTypeCompletions)
+	_ = fanoutCmd.RegisterFlagCompletionFunc("from", getRefCompletions)
+
+	compareCmd.Flags().StringVar(&compareTest, "test", "", "Command that checks each worktree (default: test_command)")
+
+	pickCmd.Flags().BoolVarP(&pickYes, "yes", "y", false, "Remove the other worktrees without confirmation")
+}
+
+func runFanout(cmd *cobra.Command, args []string) error {
+	repo, err := openRepository(cmd.Context())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	layers, err := loadConfigs(repo.Root, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	mergedConfig := config.MergeLayers(append(layers, flagLayer(cmd))...)
+
+	task, issue, err := resolveTask(repo, mergedConfig)
+	if err == nil && task == "" {
+		err = errors.New("fanout needs a task; pass --task, --task-file or --issue")
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	// Check every agent before creating anything
+	agents := fanoutAgents
+	if len(agents) == 0 && mergedConfig.Agent != "" {
+		agents = []string{mergedConfig.Agent}
+	}
+	profiles := make(map[string]*agent.Profile)
+	for _, name := range agents {
+		profile, err := agent.Resolve(name, mergedConfig)
+		if err == nil && launchAgent && profile.Launch == "" {
+			err = fmt.Errorf("--launch needs agents with a launch command; %s has none", name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+		profiles[name] = profile
+	}
+	if launchAgent && len(agents) == 0 {
+		err := errors.New("--launch needs an agent; pass --agent or --agents")
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	count := fanoutCount
+	if len(fanoutAgents) > 0 && !cmd.Flags().Changed("count") {
+		count = len(fanoutAgents)
+	}
+	if count < 1 {
+		err := fmt.Errorf("-n must be at least 1")
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	name := branch
+	if name == "" {
+		name = naming.Slugify(task)
+	}
+	if base == "" {
+		if base, err = repo.CurrentBranch(); err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error getting current branch: %v", err)))
+			return err
+		}
+	}
+	// Fetch once here; the creates below work from the refs it leaves
+	refreshRemotes(repo, base)
+
+	group, err := newGroupID(name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	// Each worktree is a regular create, so it gets the full setup
+	failed := 0
+	for i := 1; i <= count; i++ {
+		opts := agentree.Options{
+			Branch:        fmt.Sprintf("%s-%d", name, i),
+			Base:          base,
+			Task:          task,
+			Group:         group,
+			SkipEnv:       !copyEnv,
+			SkipArtifacts: !copyArtifacts,
+			SkipSetup:     !runSetup,
+			Scripts:       customScripts,
+			Offline:       true,
+			Settings:      flagSettings(cmd),
+			Verbose:       verbose,
+			Stdout:        os.Stdout,
+			Stderr:        os.Stderr,
+			OnEvent:       printEvent,
+		}
+		label := fmt.Sprintf("[%d/%d]", i, count)
+		if len(agents) > 0 {
+			opts.Agent = agents[(i-1)%len(agents)]
+			label += " " + opts.Agent
+		}
+		if issue != nil {
+			opts.Issue = issue.Number
+		}
+
+		fmt.Println(labelStyle.Render(label))
+		wt, err := agentree.Create(cmd.Context(), opts)
+		if err == nil && launchAgent {
+			err = launchWorktreeAgent(repo, wt, profiles[opts.Agent], true)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			failed++
+		}
+		fmt.Println()
+	}
+
+	store, err := openStore(repo)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	records, err := groupRecords(store, group)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	fmt.Println(successStyle.Render(fmt.Sprintf("✅ Fanout %s: %d of %d worktrees ready", group, len(records), count)))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, record := range records {
+		fmt.Fprintf(w, "    %s\t%s\t%s\n", record.Branch, valueOr(record.Agent, "-"), record.Path)
+	}
+	w.Flush()
+	fmt.Println(infoStyle.Render(fmt.Sprintf("Compare with: agentree compare %s", group)))
+
+	if failed > 0 {
+		err := fmt.Errorf("%d of %d worktrees failed", failed, count)
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	return nil
+}
+
+// newGroupID returns a fanout group ID: name plus a random suffix
+func newGroupID(name string) (string, error) {
+	suffix := make([]byte, 2)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	prefix := naming.Slugify(name)
+	if prefix == "" {
+		prefix = "fanout"
+	}
+	return prefix + "-" + hex.EncodeToString(suffix), nil
+}
+
+// groupRecords returns the worktrees of a fanout group in the order they
+// were created
+func groupRecords(store *metadata.Store, group string) ([]*metadata.Worktree, error) {
+	records, err := store.Group(group)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+	return records, nil
+}
+
+// comparison is what compare found out about one worktree
+type comparison struct {
+	record  *metadata.Worktree
+	commits string
+	changes string
+	tests   string
+	files   map[string]bool
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	repo, err := openRepository(cmd.Context())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	store, err := openStore(repo)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	records, err := groupRecords(store, args[0])
+	if err == nil && len(records) == 0 {
+		err = fmt.Errorf("no worktrees in group %q", args[0])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	testCommand := compareTest
+	if testCommand == "" {
+		layers, err := loadConfigs(repo.Root, "")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+		testCommand = config.MergeLayers(layers...).TestCommand
+	}
+
+	var results []*comparison
+	allFiles := make(map[string]bool)
+	for _, record := range records {
+		result := compareWorktree(repo, record, testCommand)
+		for file := range result.files {
+			allFiles[file] = true
+		}
+		results = append(results, result)
+	}
+
+	if task := records[0].Task; task != "" {
+		title, _, _ := strings.Cut(task, "\n")
+		fmt.Printf("%s %s\n\n", labelStyle.Render("task"), title)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	rows := []struct {
+		label string
+		value func(*comparison) string
+	}{
+		{"", func(c *comparison) string { return c.record.Branch }},
+		{"agent", func(c *comparison) string { return valueOr(c.record.Agent, "-") }},
+		{"commits", func(c *comparison) string { return c.commits }},
+		{"changes", func(c *comparison) string { return c.changes }},
+		{"tests", func(c *comparison) string { return c.tests }},
+		{"status", func(c *comparison) string { return agentStatus(c.record.PID) }},
+	}
+	for _, row := range rows {
+		fmt.Fprint(w, row.label)
+		for _, result := range results {
+			fmt.Fprintf(w, "\t%s", row.value(result))
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(allFiles) > 0 {
+		files := make([]string, 0, len(allFiles))
+		for file := range allFiles {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+
+		// Rows of empty cells keep the columns aligned with the table above
+		fmt.Fprintln(w, strings.Repeat("\t", len(results)))
+		fmt.Fprintln(w, "changed files"+strings.Repeat("\t", len(results)))
+		for _, file := range files {
+			fmt.Fprint(w, file)
+			for _, result := range results {
+				mark := "·"
+				if result.files[file] {
+					mark = "✓"
+				}
+				fmt.Fprintf(w, "\t%s", mark)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	return w.Flush()
+}
+
+// compareWorktree collects the comparison of one worktree, running
+// testCommand in it when set
+func compareWorktree(repo *git.Repository, record *metadata.Worktree, testCommand string) *comparison {
+	result := &comparison{record: record, commits: "?", changes: "?", tests: "-", files: make(map[string]bool)}
+	if _, err := os.Stat(record.Path); err != nil {
+		result.changes = "missing"
+		return result
+	}
+
+	if n, err := repo.CommitsAhead(record.Path, record.Base); err == nil {
+		result.commits = strconv.Itoa(n)
+	}
+	if stat, err := repo.Diff(record.Path, record.Base); err == nil {
+		unit := "files"
+		if stat.Files == 1 {
+			unit = "file"
+		}
+		result.changes = fmt.Sprintf("%d %s +%d -%d", stat.Files, unit, stat.Insertions, stat.Deletions)
+	}
+	if files, err := repo.ChangedFiles(record.Path, record.Base); err == nil {
+		for _, file := range files {
+			result.files[file] = true
+		}
+	}
+
+	if testCommand != "" {
+		fmt.Fprintln(os.Stderr, infoStyle.Render(fmt.Sprintf("Testing %s...", record.Branch)))
+		test := exec.Command("sh", "-c", testCommand)
+		test.Dir = record.Path
+		var exitErr *exec.ExitError
+		switch err := test.Run(); {
+		case err == nil:
+			result.tests = "✓ passed"
+		case errors.As(err, &exitErr):
+			result.tests = fmt.Sprintf("✗ failed (exit %d)", exitErr.ExitCode())
+		default:
+			result.tests = "✗ " + err.Error()
+		}
+	}
+	return result
+}
+
+func runPick(cmd *cobra.Command, args []string) error {
+	repo, err := openRepository(cmd.Context())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	info, err := repo.ResolveWorktree(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	store, err := openStore(repo)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	record, err := store.Get(info.Branch)
+	if errors.Is(err, metadata.ErrNotFound) || (err == nil && record.Group == "") {
+		err = fmt.Errorf("%s isn't part of a fanout group", info.Branch)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	group, err := store.Group(record.Group)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	var others []*metadata.Worktree
+	for _, other := range group {
+		if other.Branch != record.Branch {
+			others = append(others, other)
+		}
+	}
+
+	if len(others) > 0 && !pickYes {
+		fmt.Printf("Keep %s and remove these worktrees and branches?\n", record.Branch)
+		for _, other := range others {
+			fmt.Printf("    %s\n", other.Branch)
+		}
+		fmt.Print("[y/N] ")
+		var response string
+		if _, err := fmt.Scanln(&response); err != nil {
+			response = "n"
+		}
+		if response != "y" && response != "Y" {
+			fmt.Println("Cancelled")
+			return nil
+		}
+	}
+
+	failed := 0
+	for _, other := range others {
+		wt, err := repo.FindWorktree(other.Branch)
+		if err != nil {
+			// The worktree is gone already; forget the rest of it
+			if err := store.Delete(other.Branch); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not remove worktree metadata: %v\n", err)
+			}
+			if repo.BranchExists(other.Branch) {
+				if err := repo.DeleteBranch(other.Branch); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				}
+			}
+			continue
+		}
+		opts := agentree.RemoveOptions{Dir: repo.Root, Force: true, DeleteBranch: true, Stdout: os.Stdout, Stderr: os.Stderr, OnEvent: printEvent}
+		if err := agentree.Remove(cmd.Context(), wt.Path, opts); err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			failed++
+		}
+	}
+
+	record.Group = ""
+	if err := store.Save(record); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not update worktree metadata: %v\n", err)
+	}
+	if failed > 0 {
+		err := fmt.Errorf("%d worktrees could not be removed", failed)
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	fmt.Println(successStyle.Render(fmt.Sprintf("✅ Kept %s", record.Branch)))
+	return nil
+}
+
+// getGroupCompletions completes the IDs of fanout groups
+func getGroupCompletions(cmd *cobra.Command, toComplete string) []string {
+	repo, err := openRepository(cmd.Context())
+	if err != nil {
+		return nil
+	}
+	store, err := openStore(repo)
+	if err != nil {
+		return nil
+	}
+	records, err := store.List()
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var groups []string
+	for _, record := range records {
+		if record.Group != "" && !seen[record.Group] && strings.HasPrefix(record.Group, toComplete) {
+			seen[record.Group] = true
+			groups = append(groups, record.Group)
+		}
+	}
+	return groups
+}
+
+// valueOr returns value, or fallback when value is empty
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
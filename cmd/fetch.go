@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/pkg/agentree"
+)
+
+// Fetch flags, shared by every command that talks to remotes
+var (
+	offline    bool
+	forceFetch bool
+)
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Don't fetch from remotes")
+	rootCmd.PersistentFlags().BoolVar(&forceFetch, "fetch", false, "Fetch from remotes even if that happened recently")
+	rootCmd.MarkFlagsMutuallyExclusive("offline", "fetch")
+}
+
+// refreshRemotes brings the remote refs a worktree starts from up to date,
+// as described in agentree.Fetch, unless --offline is given
+func refreshRemotes(repo *git.Repository, ref string) {
+	if offline {
+		return
+	}
+	opts := agentree.FetchOptions{Dir: repo.Root, Ref: ref, Force: forceFetch, OnEvent: printEvent}
+	if err := agentree.Fetch(repo.Context(), opts); err != nil {
+		printEvent(agentree.Event{Type: agentree.EventWarning, Message: "skipped git fetch: " + err.Error()})
+	}
+}
@@ -4,10 +4,12 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -96,6 +98,68 @@ func TestAgentreeBinaryIntegration(t *testing.T) {
 	}
 }
 
+// TestParallelCreate races creates of the same branch name; the repository
+// lock has to hand each of them a free name and path
+func TestParallelCreate(t *testing.T) {
+	binary := filepath.Join(t.TempDir(), "agentree")
+	buildCmd := exec.Command("go", "build", "-o", binary, "../cmd/agentree")
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build agentree binary: %v", err)
+	}
+
+	repoDir := t.TempDir()
+	setupGitRepo(t, repoDir)
+	t.Setenv("HOME", t.TempDir())
+
+	const count = 12
+	var wg sync.WaitGroup
+	errs := make([]error, count)
+	outputs := make([][]byte, count)
+	for i := range count {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(binary, "-b", "race", "-s=false", "-e=false")
+			cmd.Dir = repoDir
+			outputs[i], errs[i] = cmd.CombinedOutput()
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("create %d failed: %v\n%s", i, err, outputs[i])
+		}
+	}
+
+	list := exec.Command("git", "worktree", "list", "--porcelain")
+	list.Dir = repoDir
+	output, err := list.Output()
+	if err != nil {
+		t.Fatalf("git worktree list: %v", err)
+	}
+	branches := make(map[string]bool)
+	for _, line := range strings.Split(string(output), "\n") {
+		if branch, ok := strings.CutPrefix(line, "branch refs/heads/"); ok {
+			branches[branch] = true
+		}
+	}
+	for i := 1; i <= count; i++ {
+		name := "agent/race"
+		if i > 1 {
+			name = fmt.Sprintf("agent/race-%d", i)
+		}
+		if !branches[name] {
+			t.Errorf("missing worktree for %s; have %v", name, branches)
+		}
+	}
+
+	records, err := filepath.Glob(filepath.Join(repoDir, ".git", "agentree", "worktrees", "agent", "race*.json"))
+	if err != nil || len(records) != count {
+		t.Errorf("got %d metadata records, want %d", len(records), count)
+	}
+}
+
 func setupGitRepo(t *testing.T, dir string) {
 	t.Helper()
 
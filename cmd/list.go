@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/AryaLabsHQ/agentree/internal/launch"
+	"github.com/AryaLabsHQ/agentree/pkg/agentree"
+	"github.com/spf13/cobra"
+)
+
+// listCmd represents the ls command
+var listCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List worktrees and the agents running in them",
+	Long: `List the worktrees of the repository, leaving out the main checkout.
+
+The agent column shows the profile a worktree was created for. The status
+column shows agents started with 'agentree run --detach' or
+'create --launch --detach': running, or stopped once the process is gone.`,
+	Args: cobra.NoArgs,
+	RunE: runList,
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	worktrees, err := agentree.List(cmd.Context(), agentree.ListOptions{OnEvent: printEvent})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	if len(worktrees) == 0 {
+		fmt.Println(infoStyle.Render("No worktrees yet. Create one with 'agentree -b <name>'"))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BRANCH\tAGENT\tSTATUS\tPATH")
+	for _, wt := range worktrees {
+		branch, agentName := wt.Branch, wt.Agent
+		if branch == "" {
+			branch = "(detached)"
+		}
+		if agentName == "" {
+			agentName = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", branch, agentName, agentStatus(wt.PID), wt.Path)
+	}
+	return w.Flush()
+}
+
+// agentStatus describes the background agent of a worktree
+func agentStatus(pid int) string {
+	switch {
+	case pid == 0:
+		return "-"
+	case launch.Running(pid):
+		return fmt.Sprintf("running (pid %d)", pid)
+	default:
+		return "stopped"
+	}
+}
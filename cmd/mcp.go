@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AryaLabsHQ/agentree/internal/mcp"
+	"github.com/spf13/cobra"
+)
+
+// mcpCmd represents the mcp command
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Let coding agents manage worktrees over the Model Context Protocol",
+	Long: `Speak the Model Context Protocol on stdin and stdout, so a coding agent
+can spin off sub-tasks into worktrees of its own. Register it with your
+agent, e.g.:
+
+  claude mcp add agentree -- agentree mcp
+
+The tools create_worktree, list_worktrees, worktree_status, run_setup and
+remove_worktree do what the matching commands do. Two guardrails apply,
+set in the global config only:
+
+  mcp.max_worktrees  create_worktree refuses once the repository has this
+                     many worktrees (default 10, 0 for no limit)
+  mcp.branch_prefix  remove_worktree only removes branches starting with
+                     it (default agent/)
+
+Script output and progress go to stderr.`,
+	Args: cobra.NoArgs,
+	RunE: runMCP,
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+}
+
+func runMCP(cmd *cobra.Command, args []string) error {
+	// Fail before the client connects when there is no repository
+	repo, err := openRepository(cmd.Context())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	srv := &mcp.Server{Dir: repo.Root, Version: version, Log: os.Stderr}
+	if err := srv.Serve(cmd.Context(), os.Stdin, os.Stdout); err != nil && cmd.Context().Err() == nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	return nil
+}
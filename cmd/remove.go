@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/pkg/agentree"
 	"github.com/spf13/cobra"
 )
 
@@ -59,7 +59,7 @@ func runRemove(cmd *cobra.Command, args []string) error {
 	target := args[0]
 
 	// Create repository instance
-	repo, err := git.NewRepository()
+	repo, err := openRepository(cmd.Context())
 	if err != nil {
 		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
 		return err
@@ -86,21 +86,17 @@ func runRemove(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Remove the worktree
-	if err := repo.RemoveWorktree(info.Path, force); err != nil {
+	opts := agentree.RemoveOptions{
+		Dir:          repo.Root,
+		Force:        force,
+		DeleteBranch: deleteBranch,
+		Stdout:       os.Stdout,
+		Stderr:       os.Stderr,
+		OnEvent:      printEvent,
+	}
+	if err := agentree.Remove(cmd.Context(), info.Path, opts); err != nil {
 		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
 		return err
 	}
-	fmt.Println(successStyle.Render(fmt.Sprintf("✅ Removed worktree %s", info.Path)))
-
-	// Delete branch if requested
-	if deleteBranch && info.Branch != "" {
-		if err := repo.DeleteBranch(info.Branch); err != nil {
-			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Warning: %v", err)))
-		} else {
-			fmt.Println(successStyle.Render(fmt.Sprintf("🗑️  Deleted branch %s", info.Branch)))
-		}
-	}
-
 	return nil
 }
@@ -2,6 +2,11 @@
 package cmd
 
 import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/AryaLabsHQ/agentree/internal/git"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
 )
@@ -38,11 +43,33 @@ branches and directories for concurrent work without conflicts.`,
 	Version: version,
 }
 
-// Execute runs the root command
+// debug traces every git command to stderr
+var debug bool
+
+// Execute runs the root command. The first Ctrl-C cancels running git
+// commands so the command can stop cleanly; a second one exits at once.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return rootCmd.ExecuteContext(ctx)
 }
 
 func init() {
 	// Custom completion functions will be registered in individual command files
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Print every git command agentree runs")
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if debug {
+			git.DefaultRunner = git.Trace(git.DefaultRunner, os.Stderr)
+		}
+	}
+}
+
+// openRepository opens the repository of the current directory. Its git
+// commands stop when ctx is cancelled.
+func openRepository(ctx context.Context) (*git.Repository, error) {
+	return git.Open(ctx, "", nil)
 }
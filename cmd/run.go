@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AryaLabsHQ/agentree/internal/agent"
+	"github.com/AryaLabsHQ/agentree/internal/config"
+	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/internal/launch"
+	"github.com/AryaLabsHQ/agentree/internal/metadata"
+	"github.com/spf13/cobra"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run <branch|path> [-- agent args]",
+	Short: "Start the coding agent in a worktree",
+	Long: `Start the agent's launch command with the worktree as working directory.
+
+The agent is the one the worktree was created for, or --agent, or the
+agent setting. Arguments after -- are passed on to the agent. The agent
+also receives AGENTREE_WORKTREE, AGENTREE_REPO_ROOT, AGENTREE_BRANCH,
+AGENTREE_BASE, AGENTREE_AGENT_NAME and AGENTREE_TASK. None of them is a
+config override, so an agent that runs agentree itself doesn't pass them
+on as settings; AGENTREE_AGENT still picks the agent.
+
+By default the agent takes over the terminal. With --detach it runs in the
+background, its output goes to a log file, and 'agentree ls' shows it as
+running.
+
+Examples:
+  agentree run agent/fix-login
+  agentree run agent/fix-login -- --continue
+  agentree run agent/fix-login --agent aider --detach`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runRun,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		branches, _ := getBranchCompletions(cmd, args, toComplete)
+		worktrees, _ := getWorktreeCompletions(cmd, args, toComplete)
+		return append(branches, worktrees...), cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+var detachAgent bool
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().StringVar(&agentName, "agent", "", "Agent profile to start (default: the one the worktree was created for)")
+	runCmd.Flags().BoolVar(&detachAgent, "detach", false, "Run the agent in the background")
+	_ = runCmd.RegisterFlagCompletionFunc("agent", getAgentTypeCompletions)
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	repo, err := openRepository(cmd.Context())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	info, err := repo.FindWorktree(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	store, err := openStore(repo)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	record, err := store.Get(info.Branch)
+	if errors.Is(err, metadata.ErrNotFound) {
+		// Worktrees made by hand or by older versions have no record yet
+		record = &metadata.Worktree{Branch: info.Branch, Path: info.Path}
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	layers, err := loadConfigs(repo.Root, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	mergedConfig := config.MergeLayers(append(layers, flagLayer(cmd))...)
+
+	name := record.Agent
+	if name == "" || cmd.Flags().Changed("agent") {
+		name = mergedConfig.Agent
+	}
+	if name == "" {
+		err := fmt.Errorf("no agent for %s; pass --agent or set one with 'agentree config set agent <name>'", info.Branch)
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	profile, err := agent.Resolve(name, mergedConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	target := launch.Target{
+		Dir:      info.Path,
+		RepoRoot: repo.Root,
+		Branch:   info.Branch,
+		Base:     record.Base,
+		Agent:    profile.Name,
+		Task:     record.Task,
+	}
+	if err := startAgent(store, record, target, profile, args[1:], detachAgent); err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	return nil
+}
+
+// startAgent runs the profile's launch command for a worktree, either in the
+// foreground, replacing agentree, or in the background with its PID recorded
+func startAgent(store *metadata.Store, record *metadata.Worktree, target launch.Target, profile *agent.Profile, args []string, background bool) error {
+	if profile.Launch == "" {
+		return fmt.Errorf("agent %s has no launch command; set agents.%s.launch in your config", profile.Name, profile.Name)
+	}
+	command := launch.Command(target, profile.Launch, args)
+
+	if !background {
+		fmt.Println(infoStyle.Render(fmt.Sprintf("Starting %s in %s", profile.Name, target.Dir)))
+		return launch.Exec(command)
+	}
+
+	if launch.Running(record.PID) {
+		return fmt.Errorf("an agent is already running in %s (pid %d)", target.Dir, record.PID)
+	}
+
+	if store == nil {
+		return fmt.Errorf("can't track a background agent without the metadata store")
+	}
+	logPath := store.LogPath(record.Branch)
+	pid, err := launch.Start(command, logPath)
+	if err != nil {
+		return err
+	}
+
+	record.PID = pid
+	record.LaunchedAt = time.Now()
+	record.Agent = profile.Name
+	if err := store.Save(record); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record the agent's pid: %v\n", err)
+	}
+
+	fmt.Println(successStyle.Render(fmt.Sprintf("✅ Started %s in the background (pid %d)", profile.Name, pid)))
+	fmt.Printf("    %s %s\n", labelStyle.Render("log"), logPath)
+	return nil
+}
+
+// openStore returns the metadata store kept in the repository's git directory
+func openStore(repo *git.Repository) (*metadata.Store, error) {
+	commonDir, err := repo.CommonDir()
+	if err != nil {
+		return nil, err
+	}
+	return metadata.NewStore(filepath.Join(commonDir, "agentree")), nil
+}
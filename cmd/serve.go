@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AryaLabsHQ/agentree/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the agentree API for editors and dashboards",
+	Long: `Serve create, list, status, remove, sync and agent logs as JSON over
+HTTP, for editor extensions and dashboards that drive agentree.
+
+By default the API listens on a Unix socket in the repository's git
+directory that only you can use. With --listen it listens on a localhost
+port instead, and every request needs the token from --token-file, which
+is created when it doesn't exist:
+
+  curl -H "Authorization: Bearer $(cat .git/agentree/serve-token)" \
+    http://127.0.0.1:7777/v1/worktrees
+
+Send "Accept: text/event-stream" to receive the progress of a create,
+remove or sync as server-sent events. The routes are documented in
+docs/api.md.`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+var (
+	serveSocket    string
+	serveListen    string
+	serveTokenFile string
+)
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveSocket, "socket", "", "Unix socket to listen on (default: .git/agentree/serve/api.sock)")
+	serveCmd.Flags().StringVar(&serveListen, "listen", "", "Localhost address to listen on instead, e.g. 127.0.0.1:7777")
+	serveCmd.Flags().StringVar(&serveTokenFile, "token-file", "", "File holding the token clients must send (default with --listen: .git/agentree/serve-token)")
+	serveCmd.MarkFlagsMutuallyExclusive("socket", "listen")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	repo, err := openRepository(cmd.Context())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	commonDir, err := repo.CommonDir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	stateDir := filepath.Join(commonDir, "agentree")
+
+	// A port is open to every local user, so it always needs a token
+	tokenFile := serveTokenFile
+	if tokenFile == "" && serveListen != "" {
+		tokenFile = filepath.Join(stateDir, "serve-token")
+	}
+	srv := &server.Server{Dir: repo.Root}
+	if tokenFile != "" {
+		if srv.Token, err = server.LoadToken(tokenFile); err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+	}
+
+	listener, address, err := serveListener(stateDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	httpServer := &http.Server{Handler: srv.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	fmt.Println(successStyle.Render(fmt.Sprintf("✅ Serving %s on %s", repo.RepoName, address)))
+	if tokenFile != "" {
+		fmt.Printf("    %s %s\n", labelStyle.Render("token"), tokenFile)
+	}
+
+	// Ctrl-C stops accepting requests and lets running ones finish
+	go func() {
+		<-cmd.Context().Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(ctx)
+	}()
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	return nil
+}
+
+// serveListener opens the socket or port to serve on and describes it
+func serveListener(stateDir string) (net.Listener, string, error) {
+	if serveListen != "" {
+		host, _, err := net.SplitHostPort(serveListen)
+		if err != nil {
+			return nil, "", err
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, "", fmt.Errorf("--listen only accepts localhost addresses, not %s", host)
+		}
+		listener, err := net.Listen("tcp", serveListen)
+		if err != nil {
+			return nil, "", err
+		}
+		return listener, "http://" + listener.Addr().String(), nil
+	}
+
+	// The default socket sits in a directory only its owner can enter, so
+	// nobody else can connect before its permissions are narrowed
+	path := serveSocket
+	if path == "" {
+		dir := filepath.Join(stateDir, "serve")
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, "", err
+		}
+		if err := os.Chmod(dir, 0700); err != nil {
+			return nil, "", err
+		}
+		path = filepath.Join(dir, "api.sock")
+	}
+	// A socket left behind by a server that died is taken over; one that
+	// still answers is not
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, "", fmt.Errorf("another server is listening on %s", path)
+	}
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		_ = os.Remove(path)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, "", err
+	}
+	return listener, "unix:" + path, nil
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/AryaLabsHQ/agentree/internal/agent"
+	"github.com/AryaLabsHQ/agentree/internal/config"
+	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/internal/metadata"
+	"github.com/AryaLabsHQ/agentree/internal/tmux"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+// sessionCmd represents the session command
+var sessionCmd = &cobra.Command{
+	Use:   "session [branch|path...]",
+	Short: "Open a tmux layout with a window per worktree",
+	Long: `Open a tmux session for the repository with one window per worktree,
+or for the given worktrees only, and attach to it.
+
+Each window has a pane running the worktree's agent, a shell, and a dev
+server pane when tmux.dev_command is set. Windows that already exist are
+left as they are, so running it again only adds new worktrees. With
+tmux.mode = "session" every worktree gets a session of its own.`,
+	RunE: runSession,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return getWorktreeCompletions(cmd, args, toComplete)
+	},
+}
+
+// attachCmd represents the attach command
+var attachCmd = &cobra.Command{
+	Use:   "attach <branch|path>",
+	Short: "Switch to the tmux window of a worktree",
+	Long: `Switch to the tmux window of a worktree, opening it first if needed.
+
+Inside tmux the current client switches to the window; outside tmux the
+terminal attaches to its session.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAttach,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		branches, _ := getBranchCompletions(cmd, args, toComplete)
+		worktrees, _ := getWorktreeCompletions(cmd, args, toComplete)
+		return append(branches, worktrees...), cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+var noAttach bool
+
+func init() {
+	rootCmd.AddCommand(sessionCmd)
+	rootCmd.AddCommand(attachCmd)
+
+	sessionCmd.Flags().BoolVar(&noAttach, "no-attach", false, "Only create the windows")
+}
+
+func runSession(cmd *cobra.Command, args []string) error {
+	repo, cfg, err := loadTmuxContext(cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	var worktrees []git.WorktreeInfo
+	if len(args) > 0 {
+		for _, target := range args {
+			info, err := repo.FindWorktree(target)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+				return err
+			}
+			worktrees = append(worktrees, *info)
+		}
+	} else {
+		all, err := repo.Worktrees()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+		worktrees = all[1:] // Leave out the main checkout
+	}
+	if len(worktrees) == 0 {
+		fmt.Println(infoStyle.Render("No worktrees yet. Create one with 'agentree -b <name> --tmux'"))
+		return nil
+	}
+
+	client := tmux.New(cfg.TmuxConfig.Socket)
+	var first tmux.Window
+	for i, info := range worktrees {
+		window, err := openTmuxWindow(client, repo, cfg, info)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+		if i == 0 {
+			first = window
+		}
+	}
+
+	if noAttach {
+		return nil
+	}
+	return client.Attach(first)
+}
+
+func runAttach(cmd *cobra.Command, args []string) error {
+	repo, cfg, err := loadTmuxContext(cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	info, err := repo.FindWorktree(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	client := tmux.New(cfg.TmuxConfig.Socket)
+	window, err := openTmuxWindow(client, repo, cfg, *info)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	return client.Attach(window)
+}
+
+// loadTmuxContext returns the repository and its configuration after
+// making sure tmux can be used
+func loadTmuxContext(cmd *cobra.Command) (*git.Repository, *config.Config, error) {
+	if !tmux.Available() {
+		return nil, nil, errors.New("tmux is not installed")
+	}
+
+	repo, err := openRepository(cmd.Context())
+	if err != nil {
+		return nil, nil, err
+	}
+	layers, err := loadConfigs(repo.Root, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return repo, config.MergeLayers(layers...), nil
+}
+
+// openTmuxWindow creates the tmux window of a worktree unless it exists
+func openTmuxWindow(client *tmux.Client, repo *git.Repository, cfg *config.Config, info git.WorktreeInfo) (tmux.Window, error) {
+	name := info.Branch
+	if name == "" {
+		name = filepath.Base(info.Path)
+	}
+
+	window := tmux.Window{
+		Session:  tmux.Name(repo.RepoName),
+		Name:     tmux.Name(name),
+		Dir:      info.Path,
+		Commands: []string{agentPaneCommand(repo, cfg, info), ""},
+	}
+	if cfg.TmuxConfig.Mode == "session" {
+		window.Session = tmux.Name(repo.RepoName + "/" + name)
+		window.Name = "agent"
+	}
+	if cfg.TmuxConfig.DevCommand != "" {
+		window.Commands = append(window.Commands, cfg.TmuxConfig.DevCommand)
+	}
+
+	created, err := client.Ensure(window)
+	if err != nil {
+		return window, err
+	}
+	if created {
+		fmt.Println(successStyle.Render(fmt.Sprintf("🪟 Opened tmux window %s:%s", window.Session, window.Name)))
+	}
+	return window, nil
+}
+
+// agentPaneCommand returns the command that starts the worktree's agent,
+// or "" for a plain shell when there is no agent with a launch command
+func agentPaneCommand(repo *git.Repository, cfg *config.Config, info git.WorktreeInfo) string {
+	if info.Branch == "" {
+		return ""
+	}
+
+	name := cfg.Agent
+	if store, err := openStore(repo); err == nil {
+		if record, err := store.Get(info.Branch); err == nil && record.Agent != "" {
+			name = record.Agent
+		} else if err != nil && !errors.Is(err, metadata.ErrNotFound) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	if name == "" {
+		return ""
+	}
+	profile, err := agent.Resolve(name, cfg)
+	if err != nil || profile.Launch == "" {
+		return ""
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		executable = "agentree"
+	}
+	return shellQuote(executable) + " run " + shellQuote(info.Branch)
+}
+
+// shellSafe matches words that need no quoting in sh
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote quotes s for sh
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// isTerminal reports whether f is connected to a terminal
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
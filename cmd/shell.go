@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/internal/shell"
+	"github.com/spf13/cobra"
+)
+
+// shellInitCmd represents the shell-init command
+var shellInitCmd = &cobra.Command{
+	Use:   "shell-init <bash|zsh|fish>",
+	Short: "Print the shell integration that lets agentree change directory",
+	Long: `Print a shell function that wraps agentree so 'agentree cd' and
+'agentree create --cd' can switch the current shell into a worktree.
+
+Add it to your shell's rc file:
+  bash:  eval "$(agentree shell-init bash)"      # ~/.bashrc
+  zsh:   eval "$(agentree shell-init zsh)"       # ~/.zshrc
+  fish:  agentree shell-init fish | source       # ~/.config/fish/config.fish`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: shell.Shells,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		script, err := shell.Init(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+		fmt.Print(script)
+		return nil
+	},
+}
+
+// cdCmd represents the cd command
+var cdCmd = &cobra.Command{
+	Use:   "cd <branch|path>",
+	Short: "Change the current shell into a worktree",
+	Long: `Change the current shell into a worktree. Needs the shell integration
+from 'agentree shell-init'; without it the path is printed instead.
+
+Worktrees match on their branch or directory name, also partially:
+'agentree cd login' finds agent/fix-login.`,
+	Args:              cobra.ExactArgs(1),
+	RunE:              runCd,
+	ValidArgsFunction: worktreeArgCompletions,
+}
+
+// pathCmd represents the path command
+var pathCmd = &cobra.Command{
+	Use:   "path <branch|path>",
+	Short: "Print the path of a worktree",
+	Long: `Print the path of a worktree, for use in scripts.
+
+Worktrees match on their branch or directory name, also partially. A name
+that matches several worktrees equally well is an error.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info, err := resolveWorktreeArg(cmd, args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+		fmt.Println(info.Path)
+		return nil
+	},
+	ValidArgsFunction: worktreeArgCompletions,
+}
+
+func init() {
+	rootCmd.AddCommand(shellInitCmd)
+	rootCmd.AddCommand(cdCmd)
+	rootCmd.AddCommand(pathCmd)
+}
+
+func runCd(cmd *cobra.Command, args []string) error {
+	info, err := resolveWorktreeArg(cmd, args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+
+	if !shell.Active() {
+		// Still useful as cd "$(agentree cd <branch>)"
+		fmt.Println(info.Path)
+		fmt.Fprintln(os.Stderr, infoStyle.Render("To change directory directly, set up the shell integration: agentree shell-init --help"))
+		return nil
+	}
+	if err := shell.ChangeDir(info.Path); err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	return nil
+}
+
+// resolveWorktreeArg finds the worktree named by a command argument
+func resolveWorktreeArg(cmd *cobra.Command, query string) (*git.WorktreeInfo, error) {
+	repo, err := openRepository(cmd.Context())
+	if err != nil {
+		return nil, err
+	}
+	return repo.ResolveWorktree(query)
+}
+
+// worktreeArgCompletions completes the single branch or worktree argument
+func worktreeArgCompletions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	branches, _ := getBranchCompletions(cmd, args, toComplete)
+	worktrees, _ := getWorktreeCompletions(cmd, args, toComplete)
+	return append(branches, worktrees...), cobra.ShellCompDirectiveNoFileComp
+}
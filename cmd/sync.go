@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AryaLabsHQ/agentree/pkg/agentree"
+	"github.com/spf13/cobra"
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync [branch|path]",
+	Short: "Rebase a worktree onto its base",
+	Long: `Rebase the branch of a worktree onto the base it was created from, after
+fetching the base. A base that tracks a remote branch is replaced by the
+remote branch, so the worktree picks up what was pushed there.
+
+Worktrees of branches that were checked out sync with their upstream;
+those without one, such as pull requests, need --onto.
+
+Without an argument the worktree of the current directory is synced.
+Worktrees with uncommitted changes are left alone, and a rebase that runs
+into conflicts is undone.`,
+	Args:              cobra.MaximumNArgs(1),
+	RunE:              runSync,
+	ValidArgsFunction: worktreeArgCompletions,
+}
+
+var syncOnto string
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().StringVar(&syncOnto, "onto", "", "Branch or revision to rebase onto (default: the worktree's base)")
+	_ = syncCmd.RegisterFlagCompletionFunc("onto", getRefCompletions)
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	var target string
+	if len(args) > 0 {
+		info, err := resolveWorktreeArg(cmd, args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+		target = info.Path
+	} else {
+		repo, err := openRepository(cmd.Context())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return err
+		}
+		target = repo.Root
+	}
+
+	opts := agentree.SyncOptions{
+		Onto:    syncOnto,
+		Offline: offline,
+		Fetch:   forceFetch,
+		Stdout:  os.Stdout,
+		Stderr:  os.Stderr,
+		OnEvent: printEvent,
+	}
+	if _, err := agentree.Sync(cmd.Context(), target, opts); err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render(fmt.Sprintf("Error: %v", err)))
+		return err
+	}
+	return nil
+}
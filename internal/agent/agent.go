@@ -0,0 +1,130 @@
+// Package agent describes the coding agents agentree prepares worktrees for
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/AryaLabsHQ/agentree/internal/config"
+	"github.com/AryaLabsHQ/agentree/internal/env"
+)
+
+// Profile is an agent profile after merging the built-in definition with
+// the ones from config files
+type Profile struct {
+	Name string
+	config.AgentProfile
+}
+
+// builtins are the profiles available without any configuration
+var builtins = map[string]config.AgentProfile{
+	"claude": {
+		BranchPrefix: "agent/claude",
+		Files:        []string{".claude/settings.local.json"},
+		ContextFiles: []string{"CLAUDE.md"},
+		Launch:       "claude",
+	},
+	"cursor": {
+		BranchPrefix: "agent/cursor",
+		ContextFiles: []string{".cursorrules"},
+		Launch:       "cursor .",
+	},
+	"aider": {
+		BranchPrefix: "agent/aider",
+		Files:        []string{".aider.conf.yml", ".aiderignore"},
+		ContextFiles: []string{"CONVENTIONS.md"},
+		Launch:       "aider --read CONVENTIONS.md",
+	},
+	"codex": {
+		BranchPrefix: "agent/codex",
+		ContextFiles: []string{"AGENTS.md"},
+		Launch:       "codex",
+	},
+	"generic": {
+		BranchPrefix: "agent",
+		ContextFiles: []string{"AGENTS.md"},
+	},
+}
+
+// Names returns the built-in profiles and those defined in cfg, sorted
+func Names(cfg *config.Config) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for name := range builtins {
+		seen[name] = true
+		names = append(names, name)
+	}
+	if cfg != nil {
+		for name := range cfg.Agents {
+			if !seen[name] {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the profile called name. Profiles in cfg override the
+// fields of a built-in profile with the same name or define new agents.
+func Resolve(name string, cfg *config.Config) (*Profile, error) {
+	profile, known := builtins[name]
+	if cfg != nil {
+		if custom, ok := cfg.Agents[name]; ok {
+			profile = profile.Overlay(custom)
+			known = true
+		}
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown agent %q (available: %s)", name, strings.Join(Names(cfg), ", "))
+	}
+	return &Profile{Name: name, AgentProfile: profile}, nil
+}
+
+// BranchTemplate returns the branch template implied by the profile's prefix
+func (p *Profile) BranchTemplate() string {
+	if p.BranchPrefix == "" {
+		return ""
+	}
+	return strings.TrimSuffix(p.BranchPrefix, "/") + "/{slug}"
+}
+
+// CopyFiles copies the profile's files from the main checkout into the
+// worktree with copier, so they are written like env files: atomically,
+// without group or other permissions and recorded in the audit log.
+// Patterns may contain globs; missing files are skipped and files already in
+// the worktree are left alone. It returns the copied paths relative to the
+// main checkout.
+func (p *Profile) CopyFiles(copier *env.EnvFileCopier) ([]string, error) {
+	srcDir, destDir := copier.Dirs()
+	var files []string
+	for _, pattern := range p.Files {
+		matches, err := filepath.Glob(filepath.Join(srcDir, filepath.FromSlash(pattern)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		for _, src := range matches {
+			rel, err := filepath.Rel(srcDir, src)
+			if err != nil {
+				return nil, err
+			}
+			info, err := os.Stat(src)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(destDir, rel)); err == nil {
+				continue
+			}
+			files = append(files, rel)
+		}
+	}
+
+	copied, err := copier.CopyFiles(files)
+	for i, file := range copied {
+		copied[i] = filepath.ToSlash(file)
+	}
+	return copied, err
+}
@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AryaLabsHQ/agentree/internal/config"
+	"github.com/AryaLabsHQ/agentree/internal/env"
+)
+
+func TestResolve(t *testing.T) {
+	cfg := &config.Config{Agents: map[string]config.AgentProfile{
+		"claude":  {Launch: "claude --continue"},
+		"copilot": {BranchPrefix: "bot/copilot", Setup: []string{"make deps"}},
+	}}
+
+	claude, err := Resolve("claude", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claude.Launch != "claude --continue" {
+		t.Errorf("Launch = %q, the config should override it", claude.Launch)
+	}
+	if !reflect.DeepEqual(claude.ContextFiles, []string{"CLAUDE.md"}) {
+		t.Errorf("ContextFiles = %v, the built-in value should survive", claude.ContextFiles)
+	}
+
+	copilot, err := Resolve("copilot", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := copilot.BranchTemplate(); got != "bot/copilot/{slug}" {
+		t.Errorf("BranchTemplate() = %q", got)
+	}
+
+	if _, err := Resolve("unknown", cfg); err == nil || !strings.Contains(err.Error(), "copilot") {
+		t.Errorf("Expected an error listing the available agents, got %v", err)
+	}
+
+	names := Names(cfg)
+	if want := []string{"aider", "claude", "codex", "copilot", "cursor", "generic"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Names() = %v, want %v", names, want)
+	}
+}
+
+func TestCopyFiles(t *testing.T) {
+	src := t.TempDir()
+	dest := t.TempDir()
+	files := map[string]string{
+		filepath.Join(src, ".claude", "settings.local.json"): "{}",
+		filepath.Join(src, "notes", "a.md"):                  "a",
+		filepath.Join(dest, "notes", "b.md"):                 "keep",
+		filepath.Join(src, "notes", "b.md"):                  "b",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	profile := &Profile{Name: "test", AgentProfile: config.AgentProfile{
+		Files: []string{".claude/settings.local.json", "notes/*.md", "missing.txt"},
+	}}
+	copied, err := profile.CopyFiles(env.NewEnvFileCopier(src, dest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{".claude/settings.local.json", "notes/a.md"}; !reflect.DeepEqual(copied, want) {
+		t.Errorf("CopyFiles() = %v, want %v", copied, want)
+	}
+
+	info, err := os.Stat(filepath.Join(dest, ".claude", "settings.local.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Permissions = %v, want 0600", info.Mode().Perm())
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "notes", "b.md")); string(data) != "keep" {
+		t.Error("Existing files in the worktree must not be overwritten")
+	}
+}
+
+func TestWriteContextFiles(t *testing.T) {
+	dest := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dest, "AGENTS.md"), []byte("# Project rules\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	profile := &Profile{Name: "codex", AgentProfile: config.AgentProfile{
+		ContextFiles: []string{"AGENTS.md", "docs/CONTEXT.md"},
+	}}
+	info := ContextInfo{
+		Task:    "Fix the login redirect",
+		Branch:  "agent/codex/fix",
+		Base:    "main",
+		Commits: []string{"abc1234 Add login page"},
+		Files:   []string{"src/ (3 files)", "go.mod"},
+		Setup:   []string{"npm ci"},
+	}
+	written, err := profile.WriteContextFiles(dest, info, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ContextFile{{Name: "AGENTS.md", Extended: true}, {Name: "docs/CONTEXT.md"}}
+	if !reflect.DeepEqual(written, want) {
+		t.Errorf("WriteContextFiles() = %+v, want %+v", written, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dest, "docs", "CONTEXT.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"for codex", "agent/codex/fix", "`main`", "Fix the login redirect", "- `npm ci`", "- abc1234 Add login page", "- src/ (3 files)"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Context file missing %q:\n%s", want, data)
+		}
+	}
+
+	// A second run replaces the section instead of adding another one
+	info.Task = "Fix the logout redirect"
+	if _, err := profile.WriteContextFiles(dest, info, "Task: {{.Task}}"); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(filepath.Join(dest, "AGENTS.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantExtended := "# Project rules\n\n" + sectionBegin + "\nTask: Fix the logout redirect\n" + sectionEnd + "\n"
+	if string(data) != wantExtended {
+		t.Errorf("AGENTS.md = %q, want %q", data, wantExtended)
+	}
+
+	if _, err := profile.WriteContextFiles(dest, info, "{{.Missing}}"); err == nil {
+		t.Error("Expected an error for a template with an unknown field")
+	}
+}
+
+func TestFileOverview(t *testing.T) {
+	files := []string{"src/a.go", "README.md", "src/b/c.go", "docs/x.md", "go.mod"}
+	want := []string{"docs/ (1 file)", "src/ (2 files)", "README.md", "go.mod"}
+	if got := FileOverview(files, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("FileOverview() = %v, want %v", got, want)
+	}
+
+	want = []string{"docs/ (1 file)", "src/ (2 files)", "... and 2 more"}
+	if got := FileOverview(files, 3); !reflect.DeepEqual(got, want) {
+		t.Errorf("FileOverview() with limit = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// ContextInfo is what agentree knows about a new worktree when it writes
+// the agent's context files. Templates see its fields.
+type ContextInfo struct {
+	// Agent is the profile name; WriteContextFiles fills it in
+	Agent string
+	// Task describes what the worktree was created for, if anything
+	Task   string
+	Branch string
+	Base   string
+	// Commits are the latest commits of the base as "<hash> <subject>"
+	Commits []string
+	// Files outlines the repository, one top-level entry per line
+	Files []string
+	// Setup lists the commands that prepared the worktree
+	Setup []string
+}
+
+// DefaultContextTemplate is used when no context_template is configured
+const DefaultContextTemplate = `# Worktree {{.Branch}}
+
+This is an isolated git worktree created by agentree for {{.Agent}}.
+Work on branch ` + "`{{.Branch}}`" + `{{if .Base}}, which was created from ` + "`{{.Base}}`" + `{{end}}. Other agents work in
+their own worktrees, so only change files inside this directory.
+{{- if .Task}}
+
+## Task
+
+{{.Task}}
+{{- end}}
+{{- if .Setup}}
+
+## Setup
+
+The worktree was prepared with:
+{{range .Setup}}
+- ` + "`{{.}}`" + `
+{{- end}}
+{{- end}}
+{{- if .Commits}}
+
+## Recent commits on {{.Base}}
+{{range .Commits}}
+- {{.}}
+{{- end}}
+{{- end}}
+{{- if .Files}}
+
+## Repository layout
+{{range .Files}}
+- {{.}}
+{{- end}}
+{{- end}}
+`
+
+// Markers around the section added to context files that already exist
+const (
+	sectionBegin = "<!-- agentree:begin -->"
+	sectionEnd   = "<!-- agentree:end -->"
+)
+
+// ContextFile is a context file written by WriteContextFiles
+type ContextFile struct {
+	Name string
+	// Extended is set when the file existed and got an agentree section
+	// instead of being created
+	Extended bool
+}
+
+// WriteContextFiles renders tmpl, or DefaultContextTemplate when it is
+// empty, into the profile's context files in destDir. Missing files are
+// created. Files the branch already has keep their content and get the
+// rendered text in a marked section, which is replaced on later runs.
+func (p *Profile) WriteContextFiles(destDir string, info ContextInfo, tmpl string) ([]ContextFile, error) {
+	if len(p.ContextFiles) == 0 {
+		return nil, nil
+	}
+	if tmpl == "" {
+		tmpl = DefaultContextTemplate
+	}
+	parsed, err := template.New("context").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid context template: %w", err)
+	}
+	if info.Agent == "" {
+		info.Agent = p.Name
+	}
+	var rendered bytes.Buffer
+	if err := parsed.Execute(&rendered, info); err != nil {
+		return nil, fmt.Errorf("invalid context template: %w", err)
+	}
+	text := strings.TrimRight(rendered.String(), "\n") + "\n"
+
+	var written []ContextFile
+	for _, name := range p.ContextFiles {
+		path := filepath.Join(destDir, filepath.FromSlash(name))
+		existing, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := os.WriteFile(path, []byte(withSection(string(existing), text)), 0644); err != nil {
+				return written, fmt.Errorf("failed to extend %s: %w", name, err)
+			}
+			written = append(written, ContextFile{Name: name, Extended: true})
+		case os.IsNotExist(err):
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return written, err
+			}
+			if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+				return written, fmt.Errorf("failed to write %s: %w", name, err)
+			}
+			written = append(written, ContextFile{Name: name})
+		default:
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// withSection puts text between the agentree markers at the end of
+// content, replacing an earlier section
+func withSection(content, text string) string {
+	if begin := strings.Index(content, sectionBegin); begin >= 0 {
+		if end := strings.Index(content[begin:], sectionEnd); end >= 0 {
+			rest := strings.TrimPrefix(content[begin+end+len(sectionEnd):], "\n")
+			content = content[:begin] + rest
+		}
+	}
+	content = strings.TrimRight(content, "\n")
+	if content != "" {
+		content += "\n\n"
+	}
+	return content + sectionBegin + "\n" + text + sectionEnd + "\n"
+}
+
+// FileOverview outlines a file list by its top level: directories with the
+// number of files below them, then files at the root. At most limit
+// entries are returned, the last one saying how many were left out.
+func FileOverview(files []string, limit int) []string {
+	counts := make(map[string]int)
+	var dirs, rootFiles []string
+	for _, file := range files {
+		dir, _, nested := strings.Cut(file, "/")
+		if !nested {
+			rootFiles = append(rootFiles, file)
+			continue
+		}
+		if counts[dir] == 0 {
+			dirs = append(dirs, dir)
+		}
+		counts[dir]++
+	}
+	sort.Strings(dirs)
+	sort.Strings(rootFiles)
+
+	var overview []string
+	for _, dir := range dirs {
+		unit := "files"
+		if counts[dir] == 1 {
+			unit = "file"
+		}
+		overview = append(overview, fmt.Sprintf("%s/ (%d %s)", dir, counts[dir], unit))
+	}
+	overview = append(overview, rootFiles...)
+
+	if limit > 0 && len(overview) > limit {
+		more := len(overview) - limit + 1
+		overview = append(overview[:limit-1], fmt.Sprintf("... and %d more", more))
+	}
+	return overview
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -17,9 +18,95 @@ type Config struct {
 	NpmSetup     string
 	YarnSetup    string
 	DefaultSetup string
-	
+
+	// Template for branch names given without a slash (default: agent/{slug})
+	BranchTemplate string
+
+	// Directory worktrees are created in (default: ../<repo>-worktrees).
+	// Relative paths are resolved against the repository root.
+	WorktreeRoot string
+	// Template for the worktree path below WorktreeRoot (default: {branch})
+	WorktreePathTemplate string
+
+	// Agent profile used when --agent isn't given
+	Agent string
+	// Agent profiles defined in config files, by name. They extend or
+	// override the built-in profiles field by field.
+	Agents map[string]AgentProfile
+	// Template file for generated context files (default: built in).
+	// Relative paths are resolved against the repository root.
+	ContextTemplate string
+
+	// Command that checks a worktree, e.g. make test; used by compare
+	TestCommand string
+
+	// Code host that serves pull requests for create --from-pr, github or
+	// gitlab; detected from the origin URL when empty
+	Forge string
+
 	// Environment file configuration
 	EnvConfig EnvConfig
+
+	// Untracked artifact configuration
+	ArtifactConfig ArtifactConfig
+
+	// tmux layout for create --tmux, session and attach
+	TmuxConfig TmuxConfig
+
+	// Where create --issue looks up issues
+	TrackerConfig TrackerConfig
+
+	// Guardrails for agents that manage worktrees through agentree mcp
+	MCPConfig MCPConfig
+
+	// Commands run around creating, removing and syncing worktrees
+	HooksConfig HooksConfig
+
+	// Scripts contributed by config files in subdirectories, in the order
+	// the directories were merged
+	PackageScripts []PackageScripts
+}
+
+// PackageScripts are post-create scripts from a config file in a
+// subdirectory of the repository. They run in that directory.
+type PackageScripts struct {
+	// Dir is relative to the repository root
+	Dir     string
+	Scripts []string
+}
+
+// AgentProfile describes how worktrees are prepared for one coding agent
+type AgentProfile struct {
+	// Prefix of branch names when no branch_template is set, e.g. agent/claude
+	BranchPrefix string
+	// Untracked files copied from the main checkout, e.g. local agent settings
+	Files []string
+	// Context files generated in new worktrees, e.g. CLAUDE.md
+	ContextFiles []string
+	// Commands run after the project's setup scripts
+	Setup []string
+	// Command that starts the agent inside the worktree
+	Launch string
+}
+
+// Overlay returns p with every field that other sets replaced
+func (p AgentProfile) Overlay(other AgentProfile) AgentProfile {
+	if other.BranchPrefix != "" {
+		p.BranchPrefix = other.BranchPrefix
+	}
+	if other.Files != nil {
+		p.Files = other.Files
+	}
+	if other.ContextFiles != nil {
+		p.ContextFiles = other.ContextFiles
+	}
+	if other.Setup != nil {
+		p.Setup = other.Setup
+	}
+	if other.Launch != "" {
+		p.Launch = other.Launch
+	}
+	return p
 }
 
 // EnvConfig holds environment file copying configuration
@@ -36,20 +123,140 @@ type EnvConfig struct {
 	UseGitignore bool
 	// Custom environment file patterns (overrides defaults if set)
 	CustomPatterns []string
+	// Whether to record copied secret files in the audit log (default: true).
+	// Only honored from the global config so a project can't opt out.
+	AuditEnabled bool
+	// Custom audit log location (default: audit.log next to the global config)
+	AuditLogPath string
+	// How files land in the worktree: copy, symlink or hardlink (default: copy)
+	DefaultStrategy string
+	// Patterns of files to symlink to the main checkout instead of copying
+	SymlinkPatterns []string
+	// Patterns of files to hardlink instead of copying
+	HardlinkPatterns []string
+	// Whether symlinks are absolute or relative (default: absolute)
+	SymlinkMode string
+}
+
+// ArtifactConfig holds configuration for copying untracked build artifacts
+// that are not environment files, such as generated code or test fixtures
+type ArtifactConfig struct {
+	// Files or directories to copy into new worktrees
+	Patterns []string
+	// Largest single file to copy in bytes (0 = no limit)
+	MaxFileSize int64
+	// Largest total size to copy in bytes (0 = no limit)
+	MaxTotalSize int64
+}
+
+// TmuxConfig holds the tmux layout settings
+type TmuxConfig struct {
+	// One window per worktree in a session per repository ("window", the
+	// default) or one session per worktree ("session")
+	Mode string
+	// Command for an optional dev server pane
+	DevCommand string
+	// Private tmux server socket name (tmux -L); empty uses the default server
+	Socket string
 }
 
-// LoadProjectConfig loads configuration from .agentreerc in the project root
+// TrackerConfig selects the issue tracker behind create --issue
+type TrackerConfig struct {
+	// "github" (the default) asks the gh CLI; "file" reads <dir>/<number>.md
+	Kind string
+	// Directory of issue files for the file tracker, relative to the
+	// repository root
+	Dir string
+}
+
+// MCPConfig limits what agents may do through agentree mcp. Both settings
+// are only honored from the global config so a project can't loosen them.
+type MCPConfig struct {
+	// Most linked worktrees the repository may have before create_worktree
+	// refuses (default: 10, 0 = no limit)
+	MaxWorktrees int
+	// Prefix of the branches remove_worktree may remove (default: agent/)
+	BranchPrefix string
+}
+
+// HooksConfig lists the commands run at each lifecycle event of a
+// worktree. Hooks from every layer run, global ones first.
+type HooksConfig struct {
+	// Run before a worktree is added; one that fails stops the creation
+	PreCreate []string
+	// Run once a new worktree is set up
+	PostCreate []string
+	// Run before a worktree is removed; one that fails stops the removal
+	PreRemove []string
+	// Run after a worktree was removed
+	PostRemove []string
+	// Run after a worktree was synced with its base
+	PostSync []string
+}
+
+// Commands returns the hooks of an event such as "pre_create"
+func (h HooksConfig) Commands(event string) []string {
+	switch event {
+	case "pre_create":
+		return h.PreCreate
+	case "post_create":
+		return h.PostCreate
+	case "pre_remove":
+		return h.PreRemove
+	case "post_remove":
+		return h.PostRemove
+	case "post_sync":
+		return h.PostSync
+	}
+	return nil
+}
+
+// LoadProjectConfig loads configuration from the project root.
+// .agentree.toml takes precedence; the legacy .agentreerc is read otherwise.
 func LoadProjectConfig(projectRoot string) (*Config, error) {
-	cfg := &Config{
-		// Set defaults for env config
-		EnvConfig: EnvConfig{
-			Enabled:      true,
-			Recursive:    true,
-			UseGitignore: true,
-		},
+	layer, err := LoadProjectLayer(projectRoot)
+	if err != nil {
+		return nil, err
 	}
-	agentreercPath := filepath.Join(projectRoot, ".agentreerc")
+	return layer.Config, nil
+}
+
+// legacyKeys maps the names used in .agentreerc and the legacy global
+// config onto configuration keys
+var legacyKeys = map[string]string{
+	"POST_CREATE_SCRIPTS":     "post_create_scripts",
+	"PNPM_SETUP":              "pnpm_setup",
+	"NPM_SETUP":               "npm_setup",
+	"YARN_SETUP":              "yarn_setup",
+	"DEFAULT_POST_CREATE":     "default_setup",
+	"BRANCH_TEMPLATE":         "branch_template",
+	"WORKTREE_ROOT":           "worktree_root",
+	"WORKTREE_PATH_TEMPLATE":  "worktree_path_template",
+	"ENV_COPY_ENABLED":        "env.enabled",
+	"ENV_RECURSIVE":           "env.recursive",
+	"ENV_USE_GITIGNORE":       "env.use_gitignore",
+	"ENV_INCLUDE_PATTERNS":    "env.include",
+	"ENV_EXCLUDE_PATTERNS":    "env.exclude",
+	"ENV_DEFAULT_STRATEGY":    "env.default_strategy",
+	"ENV_SYMLINK_MODE":        "env.symlink_mode",
+	"ENV_SYMLINK_PATTERNS":    "env.symlink",
+	"ENV_HARDLINK_PATTERNS":   "env.hardlink",
+	"ENV_AUDIT_ENABLED":       "env.audit_enabled",
+	"ENV_AUDIT_LOG":           "env.audit_log",
+	"ARTIFACT_PATTERNS":       "artifacts.patterns",
+	"ARTIFACT_MAX_FILE_SIZE":  "artifacts.max_file_size",
+	"ARTIFACT_MAX_TOTAL_SIZE": "artifacts.max_total_size",
+}
 
+// LoadLegacyProjectConfig loads a bash-style .agentreerc file
+func LoadLegacyProjectConfig(agentreercPath string) (*Config, error) {
+	return loadLegacyProjectConfig(agentreercPath, func(string) {})
+}
+
+// loadLegacyProjectConfig parses .agentreerc, calling markSet with the
+// configuration key of every setting it finds
+func loadLegacyProjectConfig(agentreercPath string, markSet func(string)) (*Config, error) {
+	cfg := defaultConfig()
 	file, err := os.Open(agentreercPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -64,10 +271,18 @@ func LoadProjectConfig(projectRoot string) (*Config, error) {
 		}
 	}()
 
+	// Bash-style arrays and the fields they populate
+	arrays := map[string]*[]string{
+		"POST_CREATE_SCRIPTS":   &cfg.PostCreateScripts,
+		"ENV_INCLUDE_PATTERNS":  &cfg.EnvConfig.IncludePatterns,
+		"ENV_EXCLUDE_PATTERNS":  &cfg.EnvConfig.ExcludePatterns,
+		"ENV_SYMLINK_PATTERNS":  &cfg.EnvConfig.SymlinkPatterns,
+		"ENV_HARDLINK_PATTERNS": &cfg.EnvConfig.HardlinkPatterns,
+		"ARTIFACT_PATTERNS":     &cfg.ArtifactConfig.Patterns,
+	}
+
 	scanner := bufio.NewScanner(file)
-	inPostCreateScripts := false
-	inEnvIncludePatterns := false
-	inEnvExcludePatterns := false
+	var currentArray *[]string
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -77,61 +292,42 @@ func LoadProjectConfig(projectRoot string) (*Config, error) {
 			continue
 		}
 
-		// Handle array endings
-		if strings.Contains(line, ")") {
-			if inPostCreateScripts {
-				inPostCreateScripts = false
-			} else if inEnvIncludePatterns {
-				inEnvIncludePatterns = false
-			} else if inEnvExcludePatterns {
-				inEnvExcludePatterns = false
+		// Look for the start of an array. Arrays of unknown settings are
+		// read too, so their values aren't taken for settings.
+		if idx := strings.Index(line, "=("); idx != -1 && currentArray == nil {
+			name := strings.TrimSpace(line[:idx])
+			if target, ok := arrays[name]; ok {
+				markSet(legacyKeys[name])
+				currentArray = target
+			} else {
+				currentArray = new([]string)
 			}
-			continue
+			line = line[idx+2:]
 		}
 
-		// Look for POST_CREATE_SCRIPTS array
-		if strings.Contains(line, "POST_CREATE_SCRIPTS=(") {
-			inPostCreateScripts = true
-			continue
-		}
-
-		// Look for ENV_INCLUDE_PATTERNS array
-		if strings.Contains(line, "ENV_INCLUDE_PATTERNS=(") {
-			inEnvIncludePatterns = true
-			continue
-		}
-
-		// Look for ENV_EXCLUDE_PATTERNS array
-		if strings.Contains(line, "ENV_EXCLUDE_PATTERNS=(") {
-			inEnvExcludePatterns = true
-			continue
-		}
-
-		// Handle array contents
-		if inPostCreateScripts {
-			// Extract script from quotes
-			script := strings.Trim(line, ` "',`)
-			if script != "" {
-				cfg.PostCreateScripts = append(cfg.PostCreateScripts, script)
-			}
-		} else if inEnvIncludePatterns {
-			pattern := strings.Trim(line, ` "',`)
-			if pattern != "" {
-				cfg.EnvConfig.IncludePatterns = append(cfg.EnvConfig.IncludePatterns, pattern)
-			}
-		} else if inEnvExcludePatterns {
-			pattern := strings.Trim(line, ` "',`)
-			if pattern != "" {
-				cfg.EnvConfig.ExcludePatterns = append(cfg.EnvConfig.ExcludePatterns, pattern)
+		// Handle array contents, which may close the array on the same line
+		if currentArray != nil {
+			values, closed := splitArrayValues(line)
+			*currentArray = append(*currentArray, values...)
+			if closed {
+				currentArray = nil
 			}
 		} else {
-			// Handle key=value pairs for env config
-			if strings.HasPrefix(line, "ENV_") {
+			// Handle key=value pairs for env and artifact config
+			if strings.HasPrefix(line, "ENV_") || strings.HasPrefix(line, "ARTIFACT_") || strings.HasPrefix(line, "BRANCH_") ||
+				strings.HasPrefix(line, "WORKTREE_") {
 				parts := strings.SplitN(line, "=", 2)
 				if len(parts) == 2 {
 					key := strings.TrimSpace(parts[0])
 					value := strings.Trim(strings.TrimSpace(parts[1]), `"'`)
 					
+					switch key {
+					case "ENV_COPY_ENABLED", "ENV_RECURSIVE", "ENV_USE_GITIGNORE", "ENV_DEFAULT_STRATEGY",
+						"ENV_SYMLINK_MODE", "ARTIFACT_MAX_FILE_SIZE", "ARTIFACT_MAX_TOTAL_SIZE", "BRANCH_TEMPLATE",
+						"WORKTREE_ROOT", "WORKTREE_PATH_TEMPLATE":
+						markSet(legacyKeys[key])
+					}
+
 					switch key {
 					case "ENV_COPY_ENABLED":
 						cfg.EnvConfig.Enabled = value == "true" || value == "1"
@@ -139,6 +335,20 @@ func LoadProjectConfig(projectRoot string) (*Config, error) {
 						cfg.EnvConfig.Recursive = value == "true" || value == "1"
 					case "ENV_USE_GITIGNORE":
 						cfg.EnvConfig.UseGitignore = value == "true" || value == "1"
+					case "ENV_DEFAULT_STRATEGY":
+						cfg.EnvConfig.DefaultStrategy = value
+					case "ENV_SYMLINK_MODE":
+						cfg.EnvConfig.SymlinkMode = value
+					case "BRANCH_TEMPLATE":
+						cfg.BranchTemplate = value
+					case "WORKTREE_ROOT":
+						cfg.WorktreeRoot = value
+					case "WORKTREE_PATH_TEMPLATE":
+						cfg.WorktreePathTemplate = value
+					case "ARTIFACT_MAX_FILE_SIZE":
+						cfg.ArtifactConfig.MaxFileSize = parseSizeSetting(key, value)
+					case "ARTIFACT_MAX_TOTAL_SIZE":
+						cfg.ArtifactConfig.MaxTotalSize = parseSizeSetting(key, value)
 					}
 				}
 			}
@@ -148,23 +358,38 @@ func LoadProjectConfig(projectRoot string) (*Config, error) {
 	return cfg, scanner.Err()
 }
 
-// LoadGlobalConfig loads configuration from ~/.config/agentree/config
+// LoadGlobalConfig loads the global configuration, see LoadGlobalLayer
 func LoadGlobalConfig() (*Config, error) {
-	cfg := &Config{
-		// Set defaults for env config
-		EnvConfig: EnvConfig{
-			Enabled:      true,
-			Recursive:    true,
-			UseGitignore: true,
-		},
+	layer, err := LoadGlobalLayer()
+	if err != nil {
+		return nil, err
+	}
+	return layer.Config, nil
+}
+
+// GlobalConfigDir returns the directory holding the global config files:
+// $XDG_CONFIG_HOME/agentree, or ~/.config/agentree when it isn't set
+func GlobalConfigDir() (string, error) {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, "agentree"), nil
 	}
 
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return cfg, nil // Ignore if can't get home dir
+		return "", err
 	}
+	return filepath.Join(homeDir, ".config", "agentree"), nil
+}
+
+// LoadLegacyGlobalConfig loads a KEY=value style global config file
+func LoadLegacyGlobalConfig(configPath string) (*Config, error) {
+	return loadLegacyGlobalConfig(configPath, func(string) {})
+}
 
-	configPath := filepath.Join(homeDir, ".config", "agentree", "config")
+// loadLegacyGlobalConfig parses the legacy global config, calling markSet
+// with the configuration key of every setting it finds
+func loadLegacyGlobalConfig(configPath string, markSet func(string)) (*Config, error) {
+	cfg := defaultConfig()
 
 	file, err := os.Open(configPath)
 	if err != nil {
@@ -211,6 +436,10 @@ func LoadGlobalConfig() (*Config, error) {
 			}
 		}
 
+		if name, ok := legacyKeys[key]; ok && key != "POST_CREATE_SCRIPTS" {
+			markSet(name)
+		}
+
 		switch key {
 		case "PNPM_SETUP":
 			cfg.PnpmSetup = value
@@ -220,103 +449,162 @@ func LoadGlobalConfig() (*Config, error) {
 			cfg.YarnSetup = value
 		case "DEFAULT_POST_CREATE":
 			cfg.DefaultSetup = value
+		case "BRANCH_TEMPLATE":
+			cfg.BranchTemplate = value
+		case "WORKTREE_ROOT":
+			cfg.WorktreeRoot = value
+		case "WORKTREE_PATH_TEMPLATE":
+			cfg.WorktreePathTemplate = value
 		case "ENV_COPY_ENABLED":
 			cfg.EnvConfig.Enabled = value == "true" || value == "1"
 		case "ENV_RECURSIVE":
 			cfg.EnvConfig.Recursive = value == "true" || value == "1"
 		case "ENV_USE_GITIGNORE":
 			cfg.EnvConfig.UseGitignore = value == "true" || value == "1"
+		case "ENV_DEFAULT_STRATEGY":
+			cfg.EnvConfig.DefaultStrategy = value
+		case "ENV_SYMLINK_MODE":
+			cfg.EnvConfig.SymlinkMode = value
+		case "ENV_SYMLINK_PATTERNS":
+			cfg.EnvConfig.SymlinkPatterns = append(cfg.EnvConfig.SymlinkPatterns, splitPatterns(value)...)
+		case "ENV_HARDLINK_PATTERNS":
+			cfg.EnvConfig.HardlinkPatterns = append(cfg.EnvConfig.HardlinkPatterns, splitPatterns(value)...)
+		case "ARTIFACT_PATTERNS":
+			cfg.ArtifactConfig.Patterns = append(cfg.ArtifactConfig.Patterns, splitPatterns(value)...)
+		case "ARTIFACT_MAX_FILE_SIZE":
+			cfg.ArtifactConfig.MaxFileSize = parseSizeSetting(key, value)
+		case "ARTIFACT_MAX_TOTAL_SIZE":
+			cfg.ArtifactConfig.MaxTotalSize = parseSizeSetting(key, value)
+		case "ENV_AUDIT_ENABLED":
+			cfg.EnvConfig.AuditEnabled = value == "true" || value == "1"
+		case "ENV_AUDIT_LOG":
+			cfg.EnvConfig.AuditLogPath = value
 		case "ENV_INCLUDE_PATTERNS":
 			// Support comma-separated patterns in global config
-			patterns := strings.Split(value, ",")
-			for _, pattern := range patterns {
-				pattern = strings.TrimSpace(pattern)
-				if pattern != "" {
-					cfg.EnvConfig.IncludePatterns = append(cfg.EnvConfig.IncludePatterns, pattern)
-				}
-			}
+			cfg.EnvConfig.IncludePatterns = append(cfg.EnvConfig.IncludePatterns, splitPatterns(value)...)
 		case "ENV_EXCLUDE_PATTERNS":
 			// Support comma-separated patterns in global config
-			patterns := strings.Split(value, ",")
-			for _, pattern := range patterns {
-				pattern = strings.TrimSpace(pattern)
-				if pattern != "" {
-					cfg.EnvConfig.ExcludePatterns = append(cfg.EnvConfig.ExcludePatterns, pattern)
-				}
-			}
+			cfg.EnvConfig.ExcludePatterns = append(cfg.EnvConfig.ExcludePatterns, splitPatterns(value)...)
 		}
 	}
 
 	return cfg, scanner.Err()
 }
 
-// MergeConfig merges configurations with proper precedence:
-// CLI flags > project config > global config > defaults
-func MergeConfig(globalCfg, projectCfg *Config) *Config {
-	merged := &Config{
-		// Start with defaults
-		EnvConfig: EnvConfig{
-			Enabled:      true,
-			Recursive:    true,
-			UseGitignore: true,
-		},
-	}
-	
-	// Apply global config
-	if globalCfg != nil {
-		if globalCfg.PnpmSetup != "" {
-			merged.PnpmSetup = globalCfg.PnpmSetup
+// splitArrayValues splits a line of a bash-style array into its values,
+// honouring quotes. Only a closing parenthesis outside of quotes ends the
+// array, so scripts like "echo $(date)" survive.
+func splitArrayValues(line string) (values []string, closed bool) {
+	var value strings.Builder
+	var quote rune
+	inValue := false
+	flush := func() {
+		if inValue {
+			if v := strings.TrimSuffix(value.String(), ","); v != "" {
+				values = append(values, v)
+			}
 		}
-		if globalCfg.NpmSetup != "" {
-			merged.NpmSetup = globalCfg.NpmSetup
+		value.Reset()
+		inValue = false
+	}
+
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				value.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inValue = true
+		case r == ')':
+			flush()
+			return values, true
+		case r == '#' && !inValue:
+			return values, false
+		case r == ' ' || r == '\t':
+			flush()
+		default:
+			value.WriteRune(r)
+			inValue = true
 		}
-		if globalCfg.YarnSetup != "" {
-			merged.YarnSetup = globalCfg.YarnSetup
+	}
+	flush()
+	return values, false
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// splitPatterns splits a comma-separated list of patterns, dropping empty entries
+func splitPatterns(value string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern != "" {
+			patterns = append(patterns, pattern)
 		}
-		if globalCfg.DefaultSetup != "" {
-			merged.DefaultSetup = globalCfg.DefaultSetup
+	}
+	return patterns
+}
+
+// ParseSize parses a size such as "512", "10KB", "50MB" or "1GB" into bytes
+func ParseSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	multipliers := []struct {
+		suffix string
+		factor int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"G", 1 << 30},
+		{"M", 1 << 20},
+		{"K", 1 << 10},
+		{"B", 1},
+	}
+
+	factor := int64(1)
+	for _, m := range multipliers {
+		if strings.HasSuffix(value, m.suffix) {
+			factor = m.factor
+			value = strings.TrimSpace(strings.TrimSuffix(value, m.suffix))
+			break
 		}
-		
-		// Merge env config
-		merged.EnvConfig.Enabled = globalCfg.EnvConfig.Enabled
-		merged.EnvConfig.Recursive = globalCfg.EnvConfig.Recursive
-		merged.EnvConfig.UseGitignore = globalCfg.EnvConfig.UseGitignore
-		merged.EnvConfig.IncludePatterns = append(merged.EnvConfig.IncludePatterns, globalCfg.EnvConfig.IncludePatterns...)
-		merged.EnvConfig.ExcludePatterns = append(merged.EnvConfig.ExcludePatterns, globalCfg.EnvConfig.ExcludePatterns...)
-		merged.EnvConfig.CustomPatterns = append(merged.EnvConfig.CustomPatterns, globalCfg.EnvConfig.CustomPatterns...)
 	}
-	
-	// Apply project config (overrides global)
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return n * factor, nil
+}
+
+// parseSizeSetting parses a size setting, warning and returning 0 (no limit) when invalid
+func parseSizeSetting(key, value string) int64 {
+	size, err := ParseSize(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring %s: %v\n", key, err)
+		return 0
+	}
+	return size
+}
+
+// MergeConfig merges a global and a project config built in code.
+// Use MergeLayers to include the system, environment and flag layers; the
+// full precedence is system < global < project < env < flags.
+func MergeConfig(globalCfg, projectCfg *Config) *Config {
+	var layers []*Layer
+	if globalCfg != nil {
+		layers = append(layers, NewLayer(LayerGlobal, globalCfg))
+	}
 	if projectCfg != nil {
-		merged.PostCreateScripts = projectCfg.PostCreateScripts
-		
-		if projectCfg.PnpmSetup != "" {
-			merged.PnpmSetup = projectCfg.PnpmSetup
-		}
-		if projectCfg.NpmSetup != "" {
-			merged.NpmSetup = projectCfg.NpmSetup
-		}
-		if projectCfg.YarnSetup != "" {
-			merged.YarnSetup = projectCfg.YarnSetup
-		}
-		if projectCfg.DefaultSetup != "" {
-			merged.DefaultSetup = projectCfg.DefaultSetup
-		}
-		
-		// Project env config overrides global
-		merged.EnvConfig.Enabled = projectCfg.EnvConfig.Enabled
-		merged.EnvConfig.Recursive = projectCfg.EnvConfig.Recursive
-		merged.EnvConfig.UseGitignore = projectCfg.EnvConfig.UseGitignore
-		
-		// Append patterns (don't replace, allow both to contribute)
-		merged.EnvConfig.IncludePatterns = append(merged.EnvConfig.IncludePatterns, projectCfg.EnvConfig.IncludePatterns...)
-		merged.EnvConfig.ExcludePatterns = append(merged.EnvConfig.ExcludePatterns, projectCfg.EnvConfig.ExcludePatterns...)
-		
-		// Custom patterns from project replace global ones
-		if len(projectCfg.EnvConfig.CustomPatterns) > 0 {
-			merged.EnvConfig.CustomPatterns = projectCfg.EnvConfig.CustomPatterns
-		}
+		layers = append(layers, NewLayer(LayerProject, projectCfg))
 	}
-	
-	return merged
+	return MergeLayers(layers...)
 }
@@ -24,6 +24,8 @@ func TestLoadProjectConfig_EnvConfig(t *testing.T) {
 ENV_COPY_ENABLED=true
 ENV_RECURSIVE=false
 ENV_USE_GITIGNORE=true
+ENV_DEFAULT_STRATEGY=copy
+ENV_SYMLINK_MODE=relative
 
 # Include patterns
 ENV_INCLUDE_PATTERNS=(
@@ -38,6 +40,14 @@ ENV_EXCLUDE_PATTERNS=(
   "temp/*"
 )
 
+# Link strategies
+ENV_SYMLINK_PATTERNS=(
+  ".env"
+)
+ENV_HARDLINK_PATTERNS=(
+  "*.pem"
+)
+
 # Post create scripts
 POST_CREATE_SCRIPTS=(
   "npm install"
@@ -83,6 +93,18 @@ POST_CREATE_SCRIPTS=(
 			cfg.EnvConfig.ExcludePatterns, expectedExclude)
 	}
 	
+	// Test link strategies
+	if cfg.EnvConfig.DefaultStrategy != "copy" || cfg.EnvConfig.SymlinkMode != "relative" {
+		t.Errorf("Strategy settings mismatch. Got: %q, %q",
+			cfg.EnvConfig.DefaultStrategy, cfg.EnvConfig.SymlinkMode)
+	}
+	if !reflect.DeepEqual(cfg.EnvConfig.SymlinkPatterns, []string{".env"}) {
+		t.Errorf("SymlinkPatterns mismatch. Got: %v", cfg.EnvConfig.SymlinkPatterns)
+	}
+	if !reflect.DeepEqual(cfg.EnvConfig.HardlinkPatterns, []string{"*.pem"}) {
+		t.Errorf("HardlinkPatterns mismatch. Got: %v", cfg.EnvConfig.HardlinkPatterns)
+	}
+	
 	// Test post create scripts
 	expectedScripts := []string{"npm install", "npm run setup"}
 	if !reflect.DeepEqual(cfg.PostCreateScripts, expectedScripts) {
@@ -102,6 +124,8 @@ func TestLoadGlobalConfig_EnvConfig(t *testing.T) {
 	}()
 	
 	// Set HOME environment variable temporarily
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv(ConfigPathEnvVar, "")
 	oldHome := os.Getenv("HOME")
 	_ = os.Setenv("HOME", tmpHome)
 	defer func() {
@@ -128,6 +152,8 @@ ENV_RECURSIVE=true
 ENV_USE_GITIGNORE=false
 ENV_INCLUDE_PATTERNS=.env.global,.secrets
 ENV_EXCLUDE_PATTERNS=*.temp,*.bak
+ENV_AUDIT_ENABLED=false
+ENV_AUDIT_LOG=/var/log/agentree/audit.log
 
 # Default post create
 DEFAULT_POST_CREATE=echo "Worktree created"
@@ -174,6 +200,15 @@ DEFAULT_POST_CREATE=echo "Worktree created"
 		t.Errorf("ExcludePatterns mismatch. Got: %v, Want: %v", 
 			cfg.EnvConfig.ExcludePatterns, expectedExclude)
 	}
+
+	// Test audit settings
+	if cfg.EnvConfig.AuditEnabled {
+		t.Error("EnvConfig.AuditEnabled should be false")
+	}
+
+	if cfg.EnvConfig.AuditLogPath != "/var/log/agentree/audit.log" {
+		t.Errorf("AuditLogPath mismatch. Got: %s", cfg.EnvConfig.AuditLogPath)
+	}
 }
 
 func TestMergeConfig(t *testing.T) {
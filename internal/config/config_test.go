@@ -71,6 +71,21 @@ POST_CREATE_SCRIPTS=(
 			},
 			wantErr: false,
 		},
+		{
+			name: "agentreerc with parentheses inside values",
+			agentreerc: `POST_CREATE_SCRIPTS=(
+  "echo $(date)"
+  "echo 'ready!'"
+  "npm test")`,
+			expected: Config{
+				PostCreateScripts: []string{
+					"echo $(date)",
+					"echo 'ready!'",
+					"npm test",
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name:       "no agentreerc file",
 			agentreerc: "", // special case - won't create file
@@ -117,8 +132,86 @@ POST_CREATE_SCRIPTS=(
 	}
 }
 
+func TestLoadLegacyProjectConfig_Arrays(t *testing.T) {
+	tests := []struct {
+		name       string
+		agentreerc string
+		scripts    []string
+		patterns   []string
+		template   string
+		envEnabled bool
+	}{
+		{
+			name: "single-line arrays",
+			agentreerc: `POST_CREATE_SCRIPTS=("npm install" 'npm run build')
+ARTIFACT_PATTERNS=(gen/ "certs/*.pem")`,
+			scripts:    []string{"npm install", "npm run build"},
+			patterns:   []string{"gen/", "certs/*.pem"},
+			envEnabled: true,
+		},
+		{
+			name: "multi-line arrays",
+			agentreerc: `POST_CREATE_SCRIPTS=(
+  "npm install"
+  "echo $(date)"
+)
+ARTIFACT_PATTERNS=(
+  gen/
+)`,
+			scripts:    []string{"npm install", "echo $(date)"},
+			patterns:   []string{"gen/"},
+			envEnabled: true,
+		},
+		{
+			name: "keys after a single-line array",
+			agentreerc: `POST_CREATE_SCRIPTS=("npm install")
+ENV_COPY_ENABLED=false
+BRANCH_TEMPLATE="agent/{name}"`,
+			scripts:  []string{"npm install"},
+			template: "agent/{name}",
+		},
+		{
+			name: "unrecognised lines are skipped",
+			agentreerc: `export PATH="$PATH:bin"
+EXTRA_SCRIPTS=("ENV_COPY_ENABLED=false")
+if true; then echo hi; fi
+POST_CREATE_SCRIPTS=("npm install") # comment`,
+			scripts:    []string{"npm install"},
+			envEnabled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".agentreerc")
+			if err := os.WriteFile(path, []byte(tt.agentreerc), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := LoadLegacyProjectConfig(path)
+			if err != nil {
+				t.Fatalf("LoadLegacyProjectConfig() error = %v", err)
+			}
+			if !reflect.DeepEqual(cfg.PostCreateScripts, tt.scripts) {
+				t.Errorf("PostCreateScripts = %q, want %q", cfg.PostCreateScripts, tt.scripts)
+			}
+			if !reflect.DeepEqual(cfg.ArtifactConfig.Patterns, tt.patterns) {
+				t.Errorf("ArtifactConfig.Patterns = %q, want %q", cfg.ArtifactConfig.Patterns, tt.patterns)
+			}
+			if cfg.BranchTemplate != tt.template {
+				t.Errorf("BranchTemplate = %q, want %q", cfg.BranchTemplate, tt.template)
+			}
+			if cfg.EnvConfig.Enabled != tt.envEnabled {
+				t.Errorf("EnvConfig.Enabled = %v, want %v", cfg.EnvConfig.Enabled, tt.envEnabled)
+			}
+		})
+	}
+}
+
 func TestLoadGlobalConfig(t *testing.T) {
 	// Save original HOME
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv(ConfigPathEnvVar, "")
 	originalHome := os.Getenv("HOME")
 	defer func() {
 		if err := os.Setenv("HOME", originalHome); err != nil {
@@ -324,3 +417,59 @@ func TestConfigParsing(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{"512", 512, false},
+		{"10KB", 10 << 10, false},
+		{"50mb", 50 << 20, false},
+		{"1G", 1 << 30, false},
+		{" 2 MB ", 2 << 20, false},
+		{"lots", 0, true},
+		{"-1MB", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseSize(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSize(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadProjectConfig_Artifacts(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `ARTIFACT_PATTERNS=(
+  "gen/proto/"
+  ".idea/runConfigurations"
+)
+ARTIFACT_MAX_FILE_SIZE=50MB
+ARTIFACT_MAX_TOTAL_SIZE=1GB
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, ".agentreerc"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadProjectConfig(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := ArtifactConfig{
+		Patterns:     []string{"gen/proto/", ".idea/runConfigurations"},
+		MaxFileSize:  50 << 20,
+		MaxTotalSize: 1 << 30,
+	}
+	if !reflect.DeepEqual(cfg.ArtifactConfig, want) {
+		t.Errorf("ArtifactConfig = %+v, want %+v", cfg.ArtifactConfig, want)
+	}
+}
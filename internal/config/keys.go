@@ -0,0 +1,337 @@
+// Package config handles agentree configuration from .agentreerc and global config
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AryaLabsHQ/agentree/internal/naming"
+)
+
+// mergeMode describes how a list setting combines across layers
+type mergeMode int
+
+const (
+	// mergeReplace lets the highest layer that sets the key win
+	mergeReplace mergeMode = iota
+	// mergeAppend concatenates lists from lower to higher layers
+	mergeAppend
+	// mergePrepend puts higher layers first so their patterns match first
+	mergePrepend
+)
+
+// Key describes a setting addressable by its dotted name in .agentree.toml
+type Key struct {
+	// Name is the dotted TOML name, e.g. "env.enabled"
+	Name string
+	// Description is a one-line summary shown by agentree config list
+	Description string
+	// GlobalOnly keys are ignored in project config so a repository
+	// can't weaken security-related settings
+	GlobalOnly bool
+
+	merge   mergeMode
+	choices []string
+	check   func(string) error
+	field   func(*Config) any
+}
+
+// Keys lists every configuration key in the order they are documented
+var Keys = []*Key{
+	{Name: "post_create_scripts", Description: "Commands to run after creating a worktree",
+		field: func(c *Config) any { return &c.PostCreateScripts }},
+	{Name: "pnpm_setup", Description: "Setup command for pnpm projects",
+		field: func(c *Config) any { return &c.PnpmSetup }},
+	{Name: "npm_setup", Description: "Setup command for npm projects",
+		field: func(c *Config) any { return &c.NpmSetup }},
+	{Name: "yarn_setup", Description: "Setup command for yarn projects",
+		field: func(c *Config) any { return &c.YarnSetup }},
+	{Name: "default_setup", Description: "Setup command when no package manager is detected",
+		field: func(c *Config) any { return &c.DefaultSetup }},
+	{Name: "branch_template", Description: "Branch name template, e.g. ai/{agent}/{ticket}-{slug}",
+		check: naming.Validate,
+		field: func(c *Config) any { return &c.BranchTemplate }},
+	{Name: "worktree_root", Description: "Directory new worktrees are created in, e.g. ~/worktrees or .worktrees",
+		field: func(c *Config) any { return &c.WorktreeRoot }},
+	{Name: "worktree_path_template", Description: "Worktree path below worktree_root, e.g. {repo}/{agent}/{slug}",
+		check: naming.ValidatePath,
+		field: func(c *Config) any { return &c.WorktreePathTemplate }},
+	{Name: "agent", Description: "Agent profile used when --agent isn't given, e.g. claude",
+		field: func(c *Config) any { return &c.Agent }},
+	{Name: "context_template", Description: "Template file for agent context files, e.g. .agentree/context.md.tmpl",
+		field: func(c *Config) any { return &c.ContextTemplate }},
+	{Name: "test_command", Description: "Command that checks a worktree for agentree compare, e.g. make test",
+		field: func(c *Config) any { return &c.TestCommand }},
+	{Name: "forge", Description: "Code host create --from-pr fetches pull requests from (default: detected from origin)",
+		choices: []string{"github", "gitlab"},
+		field:   func(c *Config) any { return &c.Forge }},
+	{Name: "env.enabled", Description: "Copy environment files into new worktrees",
+		field: func(c *Config) any { return &c.EnvConfig.Enabled }},
+	{Name: "env.recursive", Description: "Search subdirectories for environment files",
+		field: func(c *Config) any { return &c.EnvConfig.Recursive }},
+	{Name: "env.use_gitignore", Description: "Discover environment files from .gitignore",
+		field: func(c *Config) any { return &c.EnvConfig.UseGitignore }},
+	{Name: "env.include", Description: "Additional environment file patterns", merge: mergeAppend,
+		field: func(c *Config) any { return &c.EnvConfig.IncludePatterns }},
+	{Name: "env.exclude", Description: "Environment file patterns to skip", merge: mergeAppend,
+		field: func(c *Config) any { return &c.EnvConfig.ExcludePatterns }},
+	{Name: "env.default_strategy", Description: "How files land in the worktree",
+		choices: []string{"copy", "symlink", "hardlink"},
+		field:   func(c *Config) any { return &c.EnvConfig.DefaultStrategy }},
+	{Name: "env.symlink_mode", Description: "Whether symlinks are absolute or relative",
+		choices: []string{"absolute", "relative"},
+		field:   func(c *Config) any { return &c.EnvConfig.SymlinkMode }},
+	{Name: "env.symlink", Description: "Patterns of files to symlink instead of copy", merge: mergePrepend,
+		field: func(c *Config) any { return &c.EnvConfig.SymlinkPatterns }},
+	{Name: "env.hardlink", Description: "Patterns of files to hardlink instead of copy", merge: mergePrepend,
+		field: func(c *Config) any { return &c.EnvConfig.HardlinkPatterns }},
+	{Name: "env.audit_enabled", Description: "Record copied secret files in the audit log", GlobalOnly: true,
+		field: func(c *Config) any { return &c.EnvConfig.AuditEnabled }},
+	{Name: "env.audit_log", Description: "Audit log location", GlobalOnly: true,
+		field: func(c *Config) any { return &c.EnvConfig.AuditLogPath }},
+	{Name: "artifacts.patterns", Description: "Untracked files or directories to copy", merge: mergeAppend,
+		field: func(c *Config) any { return &c.ArtifactConfig.Patterns }},
+	{Name: "artifacts.max_file_size", Description: "Largest single artifact to copy (0 = no limit)",
+		field: func(c *Config) any { return &c.ArtifactConfig.MaxFileSize }},
+	{Name: "artifacts.max_total_size", Description: "Largest total artifact size to copy (0 = no limit)",
+		field: func(c *Config) any { return &c.ArtifactConfig.MaxTotalSize }},
+	{Name: "tmux.mode", Description: "Window per worktree or session per worktree",
+		choices: []string{"window", "session"},
+		field:   func(c *Config) any { return &c.TmuxConfig.Mode }},
+	{Name: "tmux.dev_command", Description: "Command for the dev server pane, e.g. pnpm dev",
+		field: func(c *Config) any { return &c.TmuxConfig.DevCommand }},
+	{Name: "tmux.socket", Description: "tmux server socket name (tmux -L)",
+		field: func(c *Config) any { return &c.TmuxConfig.Socket }},
+	{Name: "tracker.kind", Description: "Issue tracker for create --issue",
+		choices: []string{"github", "file"},
+		field:   func(c *Config) any { return &c.TrackerConfig.Kind }},
+	{Name: "tracker.dir", Description: "Directory of <number>.md issue files for the file tracker",
+		field: func(c *Config) any { return &c.TrackerConfig.Dir }},
+	{Name: "mcp.max_worktrees", Description: "Most worktrees agentree mcp lets agents have (0 = no limit)", GlobalOnly: true,
+		field: func(c *Config) any { return &c.MCPConfig.MaxWorktrees }},
+	{Name: "mcp.branch_prefix", Description: "Prefix of the branches agentree mcp lets agents remove", GlobalOnly: true,
+		check: checkBranchPrefix,
+		field: func(c *Config) any { return &c.MCPConfig.BranchPrefix }},
+	{Name: "hooks.pre_create", Description: "Commands run before creating a worktree; one that fails stops it", merge: mergeAppend,
+		field: func(c *Config) any { return &c.HooksConfig.PreCreate }},
+	{Name: "hooks.post_create", Description: "Commands run once a new worktree is set up", merge: mergeAppend,
+		field: func(c *Config) any { return &c.HooksConfig.PostCreate }},
+	{Name: "hooks.pre_remove", Description: "Commands run before removing a worktree; one that fails stops it", merge: mergeAppend,
+		field: func(c *Config) any { return &c.HooksConfig.PreRemove }},
+	{Name: "hooks.post_remove", Description: "Commands run after removing a worktree", merge: mergeAppend,
+		field: func(c *Config) any { return &c.HooksConfig.PostRemove }},
+	{Name: "hooks.post_sync", Description: "Commands run after syncing a worktree with its base", merge: mergeAppend,
+		field: func(c *Config) any { return &c.HooksConfig.PostSync }},
+}
+
+// checkBranchPrefix rejects an empty prefix, which would let agents remove
+// every worktree
+func checkBranchPrefix(prefix string) error {
+	if strings.TrimSpace(prefix) == "" {
+		return fmt.Errorf("prefix must not be empty")
+	}
+	return nil
+}
+
+// LookupKey finds a configuration key by its dotted name
+func LookupKey(name string) (*Key, error) {
+	for _, key := range Keys {
+		if key.Name == name {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown config key %q (run 'agentree config list' to see all keys)", name)
+}
+
+// KeyNames returns the names of all configuration keys, sorted
+func KeyNames() []string {
+	names := make([]string, 0, len(Keys))
+	for _, key := range Keys {
+		names = append(names, key.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsList reports whether the key holds a list of values
+func (k *Key) IsList() bool {
+	_, ok := k.field(&Config{}).(*[]string)
+	return ok
+}
+
+// Values returns the key's value in cfg as strings, one per list item
+func (k *Key) Values(cfg *Config) []string {
+	switch v := k.field(cfg).(type) {
+	case *[]string:
+		return cloneList(*v)
+	case *string:
+		return []string{*v}
+	case *bool:
+		return []string{strconv.FormatBool(*v)}
+	case *int64:
+		return []string{formatSize(*v)}
+	case *int:
+		return []string{strconv.Itoa(*v)}
+	}
+	return nil
+}
+
+// Format renders the key's value in cfg the way it would appear in TOML
+func (k *Key) Format(cfg *Config) string {
+	switch v := k.field(cfg).(type) {
+	case *[]string:
+		quoted := make([]string, len(*v))
+		for i, item := range *v {
+			quoted[i] = strconv.Quote(item)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	case *string:
+		return strconv.Quote(*v)
+	}
+	return k.Values(cfg)[0]
+}
+
+// set parses values and stores them in cfg.
+// Lists take any number of values, everything else exactly one.
+func (k *Key) set(cfg *Config, values []string) error {
+	field := k.field(cfg)
+	if list, ok := field.(*[]string); ok {
+		*list = cloneList(values)
+		return nil
+	}
+
+	if len(values) != 1 {
+		return fmt.Errorf("%s takes exactly one value", k.Name)
+	}
+	value := values[0]
+
+	switch v := field.(type) {
+	case *string:
+		if len(k.choices) > 0 && !contains(k.choices, value) {
+			return fmt.Errorf("invalid value %q for %s (expected %s)", value, k.Name, strings.Join(k.choices, ", "))
+		}
+		if k.check != nil {
+			if err := k.check(value); err != nil {
+				return fmt.Errorf("invalid value for %s: %w", k.Name, err)
+			}
+		}
+		*v = value
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for %s (expected true or false)", value, k.Name)
+		}
+		*v = b
+	case *int64:
+		size, err := ParseSize(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", k.Name, err)
+		}
+		*v = size
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid value %q for %s (expected a number of 0 or more)", value, k.Name)
+		}
+		*v = n
+	}
+	return nil
+}
+
+// reset restores the key's default value in cfg
+func (k *Key) reset(cfg *Config) {
+	k.copy(cfg, defaultConfig())
+}
+
+// copy assigns the key's value from src to dst
+func (k *Key) copy(dst, src *Config) {
+	switch v := k.field(dst).(type) {
+	case *[]string:
+		*v = cloneList(*k.field(src).(*[]string))
+	case *string:
+		*v = *k.field(src).(*string)
+	case *bool:
+		*v = *k.field(src).(*bool)
+	case *int64:
+		*v = *k.field(src).(*int64)
+	case *int:
+		*v = *k.field(src).(*int)
+	}
+}
+
+// mergeInto combines the key's value from a higher layer into merged
+func (k *Key) mergeInto(merged, layer *Config) {
+	list, ok := k.field(merged).(*[]string)
+	if !ok || k.merge == mergeReplace {
+		k.copy(merged, layer)
+		return
+	}
+
+	values := *k.field(layer).(*[]string)
+	if k.merge == mergePrepend {
+		*list = append(append([]string{}, values...), *list...)
+	} else {
+		*list = append(*list, values...)
+	}
+}
+
+// changed reports whether the key's value in cfg differs from its default
+func (k *Key) changed(cfg *Config) bool {
+	if k.IsList() {
+		return len(k.Values(cfg)) > 0
+	}
+	return k.Values(cfg)[0] != k.Values(defaultConfig())[0]
+}
+
+// present reports whether a config built in code carries a value for the key.
+// Booleans are always considered present, other values when non-zero.
+func (k *Key) present(cfg *Config) bool {
+	switch v := k.field(cfg).(type) {
+	case *[]string:
+		return len(*v) > 0
+	case *string:
+		return *v != ""
+	case *int64:
+		return *v > 0
+	case *int:
+		return *v > 0
+	}
+	return true
+}
+
+// formatSize renders a byte count using the largest exact unit
+func formatSize(size int64) string {
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+	}
+	for _, u := range units {
+		if size > 0 && size%u.factor == 0 {
+			return fmt.Sprintf("%d%s", size/u.factor, u.suffix)
+		}
+	}
+	return strconv.FormatInt(size, 10)
+}
+
+// cloneList copies a list, keeping empty lists nil
+func cloneList(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	return append([]string{}, values...)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,442 @@
+// Package config handles agentree configuration from .agentreerc and global config
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Layer names in increasing order of precedence
+const (
+	LayerDefault = "default"
+	LayerSystem  = "system"
+	LayerGlobal  = "global"
+	LayerProject = "project"
+	LayerEnv     = "env"
+	LayerFlag    = "flag"
+)
+
+// layerRank orders layers by precedence; unknown names sort last
+var layerRank = map[string]int{
+	LayerDefault: 0,
+	LayerSystem:  1,
+	LayerGlobal:  2,
+	LayerProject: 3,
+	LayerEnv:     4,
+	LayerFlag:    5,
+}
+
+// Layer is one source of configuration together with the keys it sets.
+// Tracking set keys lets a layer override a lower one with a default value
+// and lets agentree config explain say where each value came from.
+type Layer struct {
+	// Name identifies the layer, e.g. LayerGlobal
+	Name string
+	// Path is the TOML file that Save writes to ("" if not file backed)
+	Path string
+	// Source is the file the layer was read from, which may be a legacy
+	// file, or "" if no file exists yet
+	Source string
+	// Config holds the layer's values on top of the defaults
+	Config *Config
+	// Dir is the directory of a nested project config relative to the
+	// repository root, or "" for the root config
+	Dir string
+
+	// keys records explicitly set keys; nil means infer them from values
+	keys map[string]bool
+}
+
+// NewLayer wraps a config built in code. Booleans always count as set,
+// other keys when they hold a non-zero value.
+func NewLayer(name string, cfg *Config) *Layer {
+	return &Layer{Name: name, Config: cfg}
+}
+
+// NewOverrideLayer returns an empty layer whose keys are provided with Set,
+// such as values given on the command line
+func NewOverrideLayer(name string) *Layer {
+	return newFileLayer(name, "")
+}
+
+// newFileLayer returns an empty layer backed by the TOML file at path
+func newFileLayer(name, path string) *Layer {
+	return &Layer{Name: name, Path: path, Config: defaultConfig(), keys: make(map[string]bool)}
+}
+
+// IsSet reports whether the layer provides a value for key
+func (l *Layer) IsSet(key *Key) bool {
+	if l.keys == nil {
+		return key.present(l.Config)
+	}
+	return l.keys[key.Name]
+}
+
+// SetKeys returns the keys the layer provides, in documentation order
+func (l *Layer) SetKeys() []*Key {
+	var keys []*Key
+	for _, key := range Keys {
+		if l.IsSet(key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Set parses and stores a value for the named key
+func (l *Layer) Set(name string, values ...string) error {
+	key, err := LookupKey(name)
+	if err != nil {
+		return err
+	}
+	if err := key.set(l.Config, values); err != nil {
+		return err
+	}
+	l.markSet(key.Name)
+	return nil
+}
+
+// Unset removes the named key from the layer so lower layers apply again
+func (l *Layer) Unset(name string) error {
+	key, err := LookupKey(name)
+	if err != nil {
+		return err
+	}
+	key.reset(l.Config)
+	if l.keys != nil {
+		delete(l.keys, key.Name)
+	}
+	return nil
+}
+
+// Label names the layer for display, including the directory of nested
+// project configs, e.g. "project (packages/web)"
+func (l *Layer) Label() string {
+	if l.Dir != "" {
+		return fmt.Sprintf("%s (%s)", l.Name, l.Dir)
+	}
+	return l.Name
+}
+
+// markSet records that the layer provides a value for the named key
+func (l *Layer) markSet(name string) {
+	if l.keys == nil {
+		l.keys = make(map[string]bool)
+	}
+	l.keys[name] = true
+}
+
+// Save writes the layer's keys to its TOML file atomically.
+// A legacy file the layer was read from is left in place but is ignored
+// from then on because the TOML file takes precedence.
+func (l *Layer) Save() error {
+	if l.Path == "" {
+		return fmt.Errorf("%s config is not backed by a TOML file", l.Name)
+	}
+
+	data, err := encodeTOML(l.Config, l.IsSet)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.Path), "."+filepath.Base(l.Path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, l.Path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	l.Source = l.Path
+	return nil
+}
+
+// LoadProjectLayer loads the project configuration from the repository root.
+// .agentree.toml takes precedence; the legacy .agentreerc is read otherwise.
+func LoadProjectLayer(projectRoot string) (*Layer, error) {
+	layer := newFileLayer(LayerProject, filepath.Join(projectRoot, ProjectConfigFile))
+
+	if fileExists(layer.Path) {
+		return layer, layer.loadTOML(layer.Path)
+	}
+
+	legacyPath := filepath.Join(projectRoot, LegacyProjectConfigFile)
+	cfg, err := loadLegacyProjectConfig(legacyPath, layer.markSet)
+	if err != nil {
+		return nil, err
+	}
+	layer.Config = cfg
+	if fileExists(legacyPath) {
+		layer.Source = legacyPath
+	}
+	return layer, nil
+}
+
+// LoadProjectLayers loads the root project config followed by the configs
+// of every directory on the way to scope, a path relative to the root.
+// Deeper configs override shallower ones. Directories without a config file
+// are skipped.
+func LoadProjectLayers(projectRoot, scope string) ([]*Layer, error) {
+	root, err := LoadProjectLayer(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	layers := []*Layer{root}
+
+	dirs, err := scopeDirs(scope)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		abs := filepath.Join(projectRoot, dir)
+		if !fileExists(filepath.Join(abs, ProjectConfigFile)) && !fileExists(filepath.Join(abs, LegacyProjectConfigFile)) {
+			continue
+		}
+		layer, err := LoadProjectLayer(abs)
+		if err != nil {
+			return nil, err
+		}
+		layer.Dir = filepath.ToSlash(dir)
+		layers = append(layers, layer)
+	}
+
+	return layers, nil
+}
+
+// scopeDirs returns every directory from the top of scope down to scope
+// itself, e.g. packages and packages/web for packages/web
+func scopeDirs(scope string) ([]string, error) {
+	if scope == "" {
+		return nil, nil
+	}
+	clean := filepath.Clean(filepath.FromSlash(scope))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("scope %q must be a directory inside the repository", scope)
+	}
+	if clean == "." {
+		return nil, nil
+	}
+
+	var dirs []string
+	parts := strings.Split(clean, string(filepath.Separator))
+	for i := range parts {
+		dirs = append(dirs, filepath.Join(parts[:i+1]...))
+	}
+	return dirs, nil
+}
+
+// LoadGlobalLayer loads the global configuration.
+// AGENTREE_CONFIG names the file explicitly; otherwise config.toml or the
+// legacy config file is read from GlobalConfigDir.
+func LoadGlobalLayer() (*Layer, error) {
+	if path := os.Getenv(ConfigPathEnvVar); path != "" {
+		return loadFileLayer(LayerGlobal, path)
+	}
+
+	configDir, err := GlobalConfigDir()
+	if err != nil {
+		return newFileLayer(LayerGlobal, ""), nil // Ignore if can't get home dir
+	}
+	return loadDirLayer(LayerGlobal, configDir)
+}
+
+// LoadSystemLayer loads the system-wide configuration from /etc/agentree
+func LoadSystemLayer() (*Layer, error) {
+	return loadDirLayer(LayerSystem, systemConfigDir)
+}
+
+// loadDirLayer reads config.toml from dir, or the legacy config file if
+// there is no TOML file. A missing directory yields an empty layer.
+func loadDirLayer(name, dir string) (*Layer, error) {
+	layer := newFileLayer(name, filepath.Join(dir, GlobalConfigFile))
+
+	if fileExists(layer.Path) {
+		return layer, layer.loadTOML(layer.Path)
+	}
+
+	legacyPath := filepath.Join(dir, LegacyGlobalConfigFile)
+	cfg, err := loadLegacyGlobalConfig(legacyPath, layer.markSet)
+	if err != nil {
+		return nil, err
+	}
+	layer.Config = cfg
+	if fileExists(legacyPath) {
+		layer.Source = legacyPath
+	}
+	return layer, nil
+}
+
+// loadFileLayer reads an explicitly named config file, which must exist.
+// Files ending in .toml are structured; anything else uses the legacy
+// KEY=value format and can't be written back.
+func loadFileLayer(name, path string) (*Layer, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("%s: %w", ConfigPathEnvVar, err)
+	}
+
+	if filepath.Ext(path) == ".toml" {
+		layer := newFileLayer(name, path)
+		return layer, layer.loadTOML(path)
+	}
+
+	layer := newFileLayer(name, "")
+	cfg, err := loadLegacyGlobalConfig(path, layer.markSet)
+	if err != nil {
+		return nil, err
+	}
+	layer.Config = cfg
+	layer.Source = path
+	return layer, nil
+}
+
+// loadTOML reads a structured config file into the layer
+func (l *Layer) loadTOML(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	cfg, err := decodeTOMLConfig(path, data, l.markSet)
+	if err != nil {
+		return err
+	}
+	l.Config = cfg
+	l.Source = path
+	return nil
+}
+
+// MergeLayers combines layers in order of precedence:
+// system < global < project < env < flag. The order of the arguments
+// doesn't matter. Scalars from higher layers win; lists are combined as
+// each key defines. Keys marked GlobalOnly are ignored in the project layer.
+func MergeLayers(layers ...*Layer) *Config {
+	merged := defaultConfig()
+
+	for _, layer := range sortLayers(layers) {
+		if layer == nil || layer.Config == nil {
+			continue
+		}
+		for _, key := range Keys {
+			if !layer.applies(key) {
+				continue
+			}
+			if key.Name == "post_create_scripts" && layer.Dir != "" {
+				// Nested scripts add to the root ones and run in their own directory
+				merged.PackageScripts = append(merged.PackageScripts, PackageScripts{
+					Dir:     layer.Dir,
+					Scripts: cloneList(layer.Config.PostCreateScripts),
+				})
+				continue
+			}
+			key.mergeInto(merged, layer.Config)
+		}
+
+		// Profiles from a higher layer override lower ones field by field
+		for name, profile := range layer.Config.Agents {
+			if merged.Agents == nil {
+				merged.Agents = make(map[string]AgentProfile)
+			}
+			merged.Agents[name] = merged.Agents[name].Overlay(profile)
+		}
+
+		// Custom patterns from a higher layer replace lower ones
+		if len(layer.Config.EnvConfig.CustomPatterns) > 0 {
+			merged.EnvConfig.CustomPatterns = layer.Config.EnvConfig.CustomPatterns
+		}
+	}
+
+	return merged
+}
+
+// applies reports whether the layer's value for key takes part in merging
+func (l *Layer) applies(key *Key) bool {
+	if key.GlobalOnly && l.Name == LayerProject {
+		return false
+	}
+	return l.IsSet(key)
+}
+
+// Explanation describes the effective value of a key and where it came from
+type Explanation struct {
+	Key *Key
+	// Value is the effective value formatted as in TOML
+	Value string
+	// Sources lists the layers that contributed, or LayerDefault.
+	// Only list keys that combine layers have more than one source.
+	Sources []string
+}
+
+// Explain reports the effective value and origin of every key for the
+// given layers
+func Explain(layers ...*Layer) []Explanation {
+	merged := MergeLayers(layers...)
+	layers = sortLayers(layers)
+
+	explanations := make([]Explanation, 0, len(Keys))
+	for _, key := range Keys {
+		var sources []string
+		for _, layer := range layers {
+			if layer == nil || layer.Config == nil || !layer.applies(key) {
+				continue
+			}
+			if key.Name == "post_create_scripts" && layer.Dir != "" {
+				continue // Reported separately as package scripts
+			}
+			if key.merge == mergeReplace {
+				sources = nil
+			}
+			sources = append(sources, layer.Label())
+		}
+		if len(sources) == 0 {
+			sources = []string{LayerDefault}
+		}
+
+		explanations = append(explanations, Explanation{
+			Key:     key,
+			Value:   key.Format(merged),
+			Sources: sources,
+		})
+	}
+
+	return explanations
+}
+
+// sortLayers returns the layers ordered by precedence, dropping nil entries
+func sortLayers(layers []*Layer) []*Layer {
+	sorted := make([]*Layer, 0, len(layers))
+	for _, layer := range layers {
+		if layer != nil {
+			sorted = append(sorted, layer)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank(sorted[i].Name) < rank(sorted[j].Name)
+	})
+	return sorted
+}
+
+func rank(name string) int {
+	if r, ok := layerRank[name]; ok {
+		return r
+	}
+	return len(layerRank)
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeLayers_OnlySetKeysOverride(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// The global config disables env copying; the project config doesn't
+	// mention it, so its default must not win
+	global := newFileLayer(LayerGlobal, filepath.Join(tmpDir, "global.toml"))
+	if err := global.Set("env.enabled", "false"); err != nil {
+		t.Fatal(err)
+	}
+	if err := global.Set("env.include", "global.env"); err != nil {
+		t.Fatal(err)
+	}
+
+	projectRoot := t.TempDir()
+	content := "ENV_INCLUDE_PATTERNS=(\n  \"project.env\"\n)\nENV_RECURSIVE=false\n"
+	if err := os.WriteFile(filepath.Join(projectRoot, LegacyProjectConfigFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	project, err := LoadProjectLayer(projectRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged := MergeLayers(global, project)
+	if merged.EnvConfig.Enabled {
+		t.Error("env.enabled should stay false from the global config")
+	}
+	if merged.EnvConfig.Recursive {
+		t.Error("env.recursive should be false from the project config")
+	}
+	if want := []string{"global.env", "project.env"}; !reflect.DeepEqual(merged.EnvConfig.IncludePatterns, want) {
+		t.Errorf("IncludePatterns = %v, want %v", merged.EnvConfig.IncludePatterns, want)
+	}
+
+	// A flag overrides both
+	flags := NewOverrideLayer(LayerFlag)
+	if err := flags.Set("env.enabled", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if merged := MergeLayers(global, project, flags); !merged.EnvConfig.Enabled {
+		t.Error("env.enabled should be true from the flag layer")
+	}
+}
+
+func TestMergeLayers_GlobalOnlyKeys(t *testing.T) {
+	global := NewOverrideLayer(LayerGlobal)
+	project := NewOverrideLayer(LayerProject)
+	if err := project.Set("env.audit_enabled", "false"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := project.Set("mcp.max_worktrees", "0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := global.Set("mcp.max_worktrees", "3"); err != nil {
+		t.Fatal(err)
+	}
+
+	merged := MergeLayers(global, project)
+	if !merged.EnvConfig.AuditEnabled {
+		t.Error("The project config must not disable the audit log")
+	}
+	if merged.MCPConfig.MaxWorktrees != 3 {
+		t.Errorf("MaxWorktrees = %d, want 3 from the global config", merged.MCPConfig.MaxWorktrees)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	global := NewOverrideLayer(LayerGlobal)
+	project := NewOverrideLayer(LayerProject)
+	flags := NewOverrideLayer(LayerFlag)
+	for _, set := range []struct {
+		layer  *Layer
+		key    string
+		values []string
+	}{
+		{global, "npm_setup", []string{"npm ci"}},
+		{global, "env.exclude", []string{"a.env"}},
+		{project, "npm_setup", []string{"npm install"}},
+		{project, "env.exclude", []string{"b.env"}},
+		{flags, "post_create_scripts", []string{"make"}},
+	} {
+		if err := set.layer.Set(set.key, set.values...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := make(map[string]Explanation)
+	for _, e := range Explain(global, project, flags) {
+		got[e.Key.Name] = e
+	}
+
+	tests := []struct {
+		key     string
+		value   string
+		sources []string
+	}{
+		{"npm_setup", `"npm install"`, []string{LayerProject}},
+		{"env.exclude", `["a.env", "b.env"]`, []string{LayerGlobal, LayerProject}},
+		{"post_create_scripts", `["make"]`, []string{LayerFlag}},
+		{"env.enabled", "true", []string{LayerDefault}},
+	}
+	for _, tt := range tests {
+		e := got[tt.key]
+		if e.Value != tt.value || !reflect.DeepEqual(e.Sources, tt.sources) {
+			t.Errorf("%s = %s %v, want %s %v", tt.key, e.Value, e.Sources, tt.value, tt.sources)
+		}
+	}
+}
+
+func TestLayer_SetUnsetSave(t *testing.T) {
+	tmpDir := t.TempDir()
+	legacy := "POST_CREATE_SCRIPTS=(\n  \"make\"\n)\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, LegacyProjectConfigFile), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	layer, err := LoadProjectLayer(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Setting a key to its default still records it
+	if err := layer.Set("env.enabled", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := layer.Set("artifacts.max_file_size", "50MB"); err != nil {
+		t.Fatal(err)
+	}
+	if err := layer.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, ProjectConfigFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`post_create_scripts = ["make"]`, "enabled = true", `max_file_size = "50MB"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Saved config missing %q:\n%s", want, data)
+		}
+	}
+
+	reloaded, err := LoadProjectLayer(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reloaded.Unset("env.enabled"); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, key := range reloaded.SetKeys() {
+		names = append(names, key.Name)
+	}
+	if want := []string{"post_create_scripts", "artifacts.max_file_size"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("SetKeys() = %v, want %v", names, want)
+	}
+}
+
+func TestLayer_SetValidation(t *testing.T) {
+	layer := NewOverrideLayer(LayerProject)
+
+	tests := []struct {
+		key    string
+		values []string
+	}{
+		{"env.enabled", []string{"maybe"}},
+		{"env.default_strategy", []string{"move"}},
+		{"artifacts.max_total_size", []string{"lots"}},
+		{"npm_setup", []string{"npm", "ci"}},
+		{"mcp.max_worktrees", []string{"-1"}},
+		{"mcp.branch_prefix", []string{""}},
+		{"env.unknown", []string{"x"}},
+	}
+	for _, tt := range tests {
+		if err := layer.Set(tt.key, tt.values...); err == nil {
+			t.Errorf("Set(%s, %v) should fail", tt.key, tt.values)
+		}
+	}
+	if len(layer.SetKeys()) != 0 {
+		t.Errorf("Failed sets should not mark keys, got %v", layer.SetKeys())
+	}
+}
+
+func TestLoadProjectLayers_Scope(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		ProjectConfigFile: "post_create_scripts = [\"make\"]\nnpm_setup = \"npm install\"\n[env]\ninclude = [\"root.env\"]\n",
+		filepath.Join("packages", LegacyProjectConfigFile):  "ENV_INCLUDE_PATTERNS=(\n  \"packages.env\"\n)\n",
+		filepath.Join("packages", "web", ProjectConfigFile): "post_create_scripts = [\"pnpm build\"]\nnpm_setup = \"npm ci\"\n",
+		filepath.Join("packages", "api", ProjectConfigFile): "npm_setup = \"not in scope\"\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	layers, err := LoadProjectLayers(root, "packages/web/src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dirs []string
+	for _, layer := range layers {
+		dirs = append(dirs, layer.Dir)
+	}
+	if want := []string{"", "packages", "packages/web"}; !reflect.DeepEqual(dirs, want) {
+		t.Fatalf("Layer dirs = %v, want %v", dirs, want)
+	}
+
+	merged := MergeLayers(layers...)
+	if merged.NpmSetup != "npm ci" {
+		t.Errorf("NpmSetup = %q, the deepest config should win", merged.NpmSetup)
+	}
+	if want := []string{"root.env", "packages.env"}; !reflect.DeepEqual(merged.EnvConfig.IncludePatterns, want) {
+		t.Errorf("IncludePatterns = %v, want %v", merged.EnvConfig.IncludePatterns, want)
+	}
+	if want := []string{"make"}; !reflect.DeepEqual(merged.PostCreateScripts, want) {
+		t.Errorf("PostCreateScripts = %v, nested scripts must not replace the root ones", merged.PostCreateScripts)
+	}
+	wantPackages := []PackageScripts{{Dir: "packages/web", Scripts: []string{"pnpm build"}}}
+	if !reflect.DeepEqual(merged.PackageScripts, wantPackages) {
+		t.Errorf("PackageScripts = %+v, want %+v", merged.PackageScripts, wantPackages)
+	}
+
+	for _, scope := range []string{"../outside", "/abs/path"} {
+		if _, err := LoadProjectLayers(root, scope); err == nil {
+			t.Errorf("Scope %s should be rejected", scope)
+		}
+	}
+}
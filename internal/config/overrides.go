@@ -0,0 +1,149 @@
+// Package config handles agentree configuration from .agentreerc and global config
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ConfigPathEnvVar names an alternative global config file
+const ConfigPathEnvVar = "AGENTREE_CONFIG"
+
+// EnvVarPrefix starts the name of every environment override
+const EnvVarPrefix = "AGENTREE_"
+
+// systemConfigDir holds the system-wide config; a variable so tests can move it
+var systemConfigDir = "/etc/agentree"
+
+// EnvVarName returns the environment variable that overrides key,
+// e.g. AGENTREE_ENV_ENABLED for env.enabled
+func EnvVarName(key *Key) string {
+	return EnvVarPrefix + strings.ToUpper(strings.ReplaceAll(key.Name, ".", "_"))
+}
+
+// LoadEnvLayer reads AGENTREE_* overrides from environ, a list of
+// KEY=value strings as returned by os.Environ. List values are either
+// comma-separated or a TOML array such as ["make build", "make test"].
+// Variables that don't name a config key are left to other features.
+func LoadEnvLayer(environ []string) (*Layer, error) {
+	layer := NewOverrideLayer(LayerEnv)
+
+	values := make(map[string]string)
+	for _, entry := range environ {
+		if name, value, ok := strings.Cut(entry, "="); ok && strings.HasPrefix(name, EnvVarPrefix) {
+			values[name] = value
+		}
+	}
+
+	var names []string
+	for _, key := range Keys {
+		name := EnvVarName(key)
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+
+		args := []string{value}
+		if key.IsList() {
+			list, err := parseEnvList(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			args = list
+		}
+		if err := layer.Set(key.Name, args...); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		names = append(names, name)
+	}
+
+	if len(names) > 0 {
+		sort.Strings(names)
+		layer.Source = strings.Join(names, ", ")
+	}
+	return layer, nil
+}
+
+// parseEnvList splits a list value from the environment
+func parseEnvList(value string) ([]string, error) {
+	trimmed := strings.TrimSpace(value)
+	if !strings.HasPrefix(trimmed, "[") {
+		return splitPatterns(value), nil
+	}
+
+	var doc struct {
+		Value []string `toml:"value"`
+	}
+	if _, err := toml.Decode("value = "+trimmed, &doc); err != nil {
+		return nil, fmt.Errorf("invalid list %s", trimmed)
+	}
+	return doc.Value, nil
+}
+
+// LoadLayers loads every configuration layer that applies to a repository,
+// in order of precedence: system, global, project and env. Project configs
+// along scope are included as described in LoadProjectLayers. The project
+// layers are skipped when projectRoot is empty. Flags are added by the caller.
+func LoadLayers(projectRoot, scope string) ([]*Layer, error) {
+	system, err := LoadSystemLayer()
+	if err != nil {
+		return nil, fmt.Errorf("invalid system config: %w", err)
+	}
+
+	global, err := LoadGlobalLayer()
+	if err != nil {
+		return nil, fmt.Errorf("invalid global config: %w", err)
+	}
+	layers := []*Layer{system, global}
+
+	if projectRoot != "" {
+		projects, err := LoadProjectLayers(projectRoot, scope)
+		if err != nil {
+			return nil, fmt.Errorf("invalid project config: %w", err)
+		}
+		layers = append(layers, projects...)
+	}
+
+	env, err := LoadEnvLayer(os.Environ())
+	if err != nil {
+		return nil, fmt.Errorf("invalid environment override: %w", err)
+	}
+	return append(layers, env), nil
+}
+
+// ResolveScope returns the subdirectory, relative to the repository root,
+// whose nested config files apply. The scope is relative to the root; an
+// empty scope is the root itself, wherever agentree runs.
+func ResolveScope(repoRoot, scope string) (string, error) {
+	if scope == "" {
+		return "", nil
+	}
+	root, err := filepath.EvalSymlinks(repoRoot)
+	if err != nil {
+		return "", err
+	}
+
+	path := scope
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("scope %s is outside the repository", scope)
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("scope %s is not a directory", scope)
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGlobalConfigDir_XDG(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	dir, err := GlobalConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(xdg, "agentree"); dir != want {
+		t.Errorf("GlobalConfigDir() = %s, want %s", dir, want)
+	}
+
+	// Relative paths are invalid per the XDG spec and ignored
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", "relative/dir")
+	t.Setenv("HOME", home)
+	dir, err = GlobalConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, ".config", "agentree"); dir != want {
+		t.Errorf("GlobalConfigDir() = %s, want %s", dir, want)
+	}
+}
+
+func TestLoadGlobalLayer_ConfigEnvVar(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tomlPath := filepath.Join(tmpDir, "ci.toml")
+	if err := os.WriteFile(tomlPath, []byte("npm_setup = \"npm ci\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(ConfigPathEnvVar, tomlPath)
+
+	layer, err := LoadGlobalLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layer.Config.NpmSetup != "npm ci" || layer.Path != tomlPath {
+		t.Errorf("Expected %s to be loaded, got %+v", tomlPath, layer)
+	}
+
+	// Legacy files load but can't be written back
+	legacyPath := filepath.Join(tmpDir, "ci.conf")
+	if err := os.WriteFile(legacyPath, []byte("NPM_SETUP=npm install\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(ConfigPathEnvVar, legacyPath)
+
+	layer, err = LoadGlobalLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layer.Config.NpmSetup != "npm install" {
+		t.Errorf("NpmSetup = %q, want npm install", layer.Config.NpmSetup)
+	}
+	if err := layer.Save(); err == nil {
+		t.Error("Saving a legacy AGENTREE_CONFIG file should fail")
+	}
+
+	// A file named explicitly must exist
+	t.Setenv(ConfigPathEnvVar, filepath.Join(tmpDir, "missing.toml"))
+	if _, err := LoadGlobalLayer(); err == nil || !strings.Contains(err.Error(), ConfigPathEnvVar) {
+		t.Errorf("Expected an %s error, got %v", ConfigPathEnvVar, err)
+	}
+}
+
+func TestLoadEnvLayer(t *testing.T) {
+	layer, err := LoadEnvLayer([]string{
+		"AGENTREE_ENV_ENABLED=false",
+		"AGENTREE_ENV_INCLUDE=a.env, b.env",
+		`AGENTREE_POST_CREATE_SCRIPTS=["make build, test", "make lint"]`,
+		"AGENTREE_ARTIFACTS_MAX_FILE_SIZE=10MB",
+		"AGENTREE_BRANCH=agent/other-feature",
+		"PATH=/usr/bin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := layer.Config
+	if cfg.EnvConfig.Enabled {
+		t.Error("env.enabled should be false")
+	}
+	if want := []string{"a.env", "b.env"}; !reflect.DeepEqual(cfg.EnvConfig.IncludePatterns, want) {
+		t.Errorf("IncludePatterns = %v, want %v", cfg.EnvConfig.IncludePatterns, want)
+	}
+	if want := []string{"make build, test", "make lint"}; !reflect.DeepEqual(cfg.PostCreateScripts, want) {
+		t.Errorf("PostCreateScripts = %v, want %v", cfg.PostCreateScripts, want)
+	}
+	if cfg.ArtifactConfig.MaxFileSize != 10<<20 {
+		t.Errorf("MaxFileSize = %d, want %d", cfg.ArtifactConfig.MaxFileSize, 10<<20)
+	}
+	if len(layer.SetKeys()) != 4 {
+		t.Errorf("Expected 4 keys to be set, got %d", len(layer.SetKeys()))
+	}
+
+	if _, err := LoadEnvLayer([]string{"AGENTREE_ENV_RECURSIVE=sometimes"}); err == nil ||
+		!strings.Contains(err.Error(), "AGENTREE_ENV_RECURSIVE") {
+		t.Errorf("Expected an error naming the variable, got %v", err)
+	}
+}
+
+func TestLoadLayers_Precedence(t *testing.T) {
+	systemDir := t.TempDir()
+	oldSystemDir := systemConfigDir
+	systemConfigDir = systemDir
+	t.Cleanup(func() { systemConfigDir = oldSystemDir })
+
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv(ConfigPathEnvVar, "")
+	if err := os.MkdirAll(filepath.Join(xdg, "agentree"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	projectRoot := t.TempDir()
+	files := map[string]string{
+		filepath.Join(systemDir, GlobalConfigFile):       "npm_setup = \"system\"\npnpm_setup = \"system\"\nyarn_setup = \"system\"\ndefault_setup = \"system\"\n",
+		filepath.Join(xdg, "agentree", GlobalConfigFile): "npm_setup = \"global\"\npnpm_setup = \"global\"\nyarn_setup = \"global\"\n",
+		filepath.Join(projectRoot, ProjectConfigFile):    "npm_setup = \"project\"\npnpm_setup = \"project\"\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("AGENTREE_NPM_SETUP", "env")
+
+	layers, err := LoadLayers(projectRoot, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Argument order doesn't matter, precedence is fixed
+	reversed := make([]*Layer, len(layers))
+	for i, layer := range layers {
+		reversed[len(layers)-1-i] = layer
+	}
+	merged := MergeLayers(reversed...)
+
+	tests := []struct {
+		name, got, want string
+	}{
+		{"npm_setup", merged.NpmSetup, "env"},
+		{"pnpm_setup", merged.PnpmSetup, "project"},
+		{"yarn_setup", merged.YarnSetup, "global"},
+		{"default_setup", merged.DefaultSetup, "system"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestResolveScope(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "packages", "web"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "README.md"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Running from inside a package doesn't change the scope
+	t.Chdir(filepath.Join(root, "packages", "web"))
+
+	tests := []struct {
+		scope   string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{".", "", false},
+		{"packages/web", "packages/web", false},
+		{filepath.Join(root, "packages"), "packages", false},
+		{"packages/missing", "", true},
+		{"README.md", "", true},
+		{"..", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ResolveScope(root, tt.scope)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ResolveScope(%q) error = %v, wantErr %v", tt.scope, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ResolveScope(%q) = %q, want %q", tt.scope, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,510 @@
+// Package config handles agentree configuration from .agentreerc and global config
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ProjectConfigFile is the structured project configuration file name
+const ProjectConfigFile = ".agentree.toml"
+
+// LegacyProjectConfigFile is the bash-style project configuration file name
+const LegacyProjectConfigFile = ".agentreerc"
+
+// GlobalConfigFile is the structured global configuration file name
+const GlobalConfigFile = "config.toml"
+
+// LegacyGlobalConfigFile is the KEY=value global configuration file name
+const LegacyGlobalConfigFile = "config"
+
+// fileConfig mirrors the layout of .agentree.toml.
+// Pointers distinguish "not set" from zero values so defaults survive.
+type fileConfig struct {
+	PostCreateScripts []string            `toml:"post_create_scripts,omitempty"`
+	PnpmSetup         *string             `toml:"pnpm_setup,omitempty"`
+	NpmSetup          *string             `toml:"npm_setup,omitempty"`
+	YarnSetup         *string             `toml:"yarn_setup,omitempty"`
+	DefaultSetup      *string             `toml:"default_setup,omitempty"`
+	BranchTemplate    *string             `toml:"branch_template,omitempty"`
+	WorktreeRoot      *string             `toml:"worktree_root,omitempty"`
+	WorktreePath      *string             `toml:"worktree_path_template,omitempty"`
+	Agent             *string             `toml:"agent,omitempty"`
+	ContextTemplate   *string             `toml:"context_template,omitempty"`
+	TestCommand       *string             `toml:"test_command,omitempty"`
+	Forge             *string             `toml:"forge,omitempty"`
+	Env               *fileEnvConfig      `toml:"env,omitempty"`
+	Artifacts         *fileArtifactConfig `toml:"artifacts,omitempty"`
+	Tmux              *fileTmuxConfig     `toml:"tmux,omitempty"`
+	Tracker           *fileTrackerConfig  `toml:"tracker,omitempty"`
+	MCP               *fileMCPConfig      `toml:"mcp,omitempty"`
+	Hooks             *fileHooksConfig    `toml:"hooks,omitempty"`
+
+	Agents map[string]fileAgentProfile `toml:"agents,omitempty"`
+}
+
+type fileAgentProfile struct {
+	BranchPrefix string   `toml:"branch_prefix,omitempty"`
+	Files        []string `toml:"files,omitempty"`
+	ContextFiles []string `toml:"context_files,omitempty"`
+	Setup        []string `toml:"setup,omitempty"`
+	Launch       string   `toml:"launch,omitempty"`
+}
+
+type fileEnvConfig struct {
+	Enabled         *bool    `toml:"enabled,omitempty"`
+	Recursive       *bool    `toml:"recursive,omitempty"`
+	UseGitignore    *bool    `toml:"use_gitignore,omitempty"`
+	Include         []string `toml:"include,omitempty"`
+	Exclude         []string `toml:"exclude,omitempty"`
+	DefaultStrategy *string  `toml:"default_strategy,omitempty"`
+	SymlinkMode     *string  `toml:"symlink_mode,omitempty"`
+	Symlink         []string `toml:"symlink,omitempty"`
+	Hardlink        []string `toml:"hardlink,omitempty"`
+	AuditEnabled    *bool    `toml:"audit_enabled,omitempty"`
+	AuditLog        *string  `toml:"audit_log,omitempty"`
+}
+
+type fileTmuxConfig struct {
+	Mode       *string `toml:"mode,omitempty"`
+	DevCommand *string `toml:"dev_command,omitempty"`
+	Socket     *string `toml:"socket,omitempty"`
+}
+
+type fileTrackerConfig struct {
+	Kind *string `toml:"kind,omitempty"`
+	Dir  *string `toml:"dir,omitempty"`
+}
+
+type fileMCPConfig struct {
+	MaxWorktrees *int    `toml:"max_worktrees,omitempty"`
+	BranchPrefix *string `toml:"branch_prefix,omitempty"`
+}
+
+type fileHooksConfig struct {
+	PreCreate  []string `toml:"pre_create,omitempty"`
+	PostCreate []string `toml:"post_create,omitempty"`
+	PreRemove  []string `toml:"pre_remove,omitempty"`
+	PostRemove []string `toml:"post_remove,omitempty"`
+	PostSync   []string `toml:"post_sync,omitempty"`
+}
+
+type fileArtifactConfig struct {
+	Patterns     []string   `toml:"patterns,omitempty"`
+	MaxFileSize  *sizeValue `toml:"max_file_size,omitempty"`
+	MaxTotalSize *sizeValue `toml:"max_total_size,omitempty"`
+}
+
+// sizeValue accepts either a byte count or a string such as "50MB"
+type sizeValue int64
+
+// UnmarshalTOML implements toml.Unmarshaler
+func (s *sizeValue) UnmarshalTOML(value any) error {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return fmt.Errorf("size must not be negative")
+		}
+		*s = sizeValue(v)
+	case string:
+		size, err := ParseSize(v)
+		if err != nil {
+			return err
+		}
+		*s = sizeValue(size)
+	default:
+		return fmt.Errorf("size must be an integer or a string such as \"50MB\", got %T", value)
+	}
+	return nil
+}
+
+// MarshalText writes sizes with a unit where one fits exactly, e.g. "50MB"
+func (s sizeValue) MarshalText() ([]byte, error) {
+	return []byte(formatSize(int64(s))), nil
+}
+
+// ConfigError describes a problem at a specific line of a config file
+type ConfigError struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// LoadTOMLConfig loads a structured config file on top of the defaults.
+// Syntax errors, values of the wrong type and unknown keys are all reported
+// as ConfigErrors carrying the offending line number.
+func LoadTOMLConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseTOMLConfig(path, data)
+}
+
+// parseTOMLConfig decodes and validates the contents of a structured config file
+func parseTOMLConfig(path string, data []byte) (*Config, error) {
+	return decodeTOMLConfig(path, data, func(string) {})
+}
+
+// decodeTOMLConfig is parseTOMLConfig calling markSet with every key the file defines
+func decodeTOMLConfig(path string, data []byte, markSet func(string)) (*Config, error) {
+	var fc fileConfig
+	md, err := toml.NewDecoder(bytes.NewReader(data)).Decode(&fc)
+	if err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			message := parseErr.Message
+			if parseErr.LastKey != "" {
+				message = fmt.Sprintf("%s: %s", parseErr.LastKey, message)
+			}
+			return nil, &ConfigError{Path: path, Line: parseErr.Position.Line, Message: message}
+		}
+		return nil, decodeError(path, err)
+	}
+
+	// Reject keys that don't map onto the schema
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		lines := keyLines(data)
+		var errs []error
+		for _, key := range undecoded {
+			errs = append(errs, &ConfigError{
+				Path:    path,
+				Line:    lines[key.String()],
+				Message: fmt.Sprintf("unknown key %q", key.String()),
+			})
+		}
+		return nil, errors.Join(errs...)
+	}
+
+	for _, key := range md.Keys() {
+		if _, err := LookupKey(key.String()); err == nil {
+			markSet(key.String())
+		}
+	}
+
+	cfg := defaultConfig()
+	fc.applyTo(cfg)
+	return cfg, nil
+}
+
+// decodeErrorPattern matches the type errors returned by the TOML decoder
+var decodeErrorPattern = regexp.MustCompile(`^toml: line (\d+) \(last key "([^"]*)"\): (.*)$`)
+
+// decodeError converts a TOML decoding error into a ConfigError with a line number
+func decodeError(path string, err error) error {
+	if m := decodeErrorPattern.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return &ConfigError{Path: path, Line: line, Message: fmt.Sprintf("%s: %s", m[2], m[3])}
+	}
+	return &ConfigError{Path: path, Message: strings.TrimPrefix(err.Error(), "toml: ")}
+}
+
+// defaultConfig returns a Config with all defaults applied
+func defaultConfig() *Config {
+	return &Config{
+		EnvConfig: EnvConfig{
+			Enabled:      true,
+			Recursive:    true,
+			UseGitignore: true,
+			AuditEnabled: true,
+		},
+		MCPConfig: MCPConfig{
+			MaxWorktrees: 10,
+			BranchPrefix: "agent/",
+		},
+	}
+}
+
+// applyTo copies every value set in the file onto cfg
+func (fc *fileConfig) applyTo(cfg *Config) {
+	cfg.PostCreateScripts = append(cfg.PostCreateScripts, fc.PostCreateScripts...)
+	setString(&cfg.PnpmSetup, fc.PnpmSetup)
+	setString(&cfg.NpmSetup, fc.NpmSetup)
+	setString(&cfg.YarnSetup, fc.YarnSetup)
+	setString(&cfg.DefaultSetup, fc.DefaultSetup)
+	setString(&cfg.BranchTemplate, fc.BranchTemplate)
+	setString(&cfg.WorktreeRoot, fc.WorktreeRoot)
+	setString(&cfg.WorktreePathTemplate, fc.WorktreePath)
+	setString(&cfg.Agent, fc.Agent)
+	setString(&cfg.ContextTemplate, fc.ContextTemplate)
+	setString(&cfg.TestCommand, fc.TestCommand)
+	setString(&cfg.Forge, fc.Forge)
+
+	for name, p := range fc.Agents {
+		if cfg.Agents == nil {
+			cfg.Agents = make(map[string]AgentProfile)
+		}
+		cfg.Agents[name] = AgentProfile(p)
+	}
+
+	if e := fc.Env; e != nil {
+		setBool(&cfg.EnvConfig.Enabled, e.Enabled)
+		setBool(&cfg.EnvConfig.Recursive, e.Recursive)
+		setBool(&cfg.EnvConfig.UseGitignore, e.UseGitignore)
+		setBool(&cfg.EnvConfig.AuditEnabled, e.AuditEnabled)
+		setString(&cfg.EnvConfig.AuditLogPath, e.AuditLog)
+		setString(&cfg.EnvConfig.DefaultStrategy, e.DefaultStrategy)
+		setString(&cfg.EnvConfig.SymlinkMode, e.SymlinkMode)
+		cfg.EnvConfig.IncludePatterns = append(cfg.EnvConfig.IncludePatterns, e.Include...)
+		cfg.EnvConfig.ExcludePatterns = append(cfg.EnvConfig.ExcludePatterns, e.Exclude...)
+		cfg.EnvConfig.SymlinkPatterns = append(cfg.EnvConfig.SymlinkPatterns, e.Symlink...)
+		cfg.EnvConfig.HardlinkPatterns = append(cfg.EnvConfig.HardlinkPatterns, e.Hardlink...)
+	}
+
+	if t := fc.Tmux; t != nil {
+		setString(&cfg.TmuxConfig.Mode, t.Mode)
+		setString(&cfg.TmuxConfig.DevCommand, t.DevCommand)
+		setString(&cfg.TmuxConfig.Socket, t.Socket)
+	}
+	if t := fc.Tracker; t != nil {
+		setString(&cfg.TrackerConfig.Kind, t.Kind)
+		setString(&cfg.TrackerConfig.Dir, t.Dir)
+	}
+	if m := fc.MCP; m != nil {
+		if m.MaxWorktrees != nil {
+			cfg.MCPConfig.MaxWorktrees = *m.MaxWorktrees
+		}
+		setString(&cfg.MCPConfig.BranchPrefix, m.BranchPrefix)
+	}
+	if h := fc.Hooks; h != nil {
+		cfg.HooksConfig.PreCreate = append(cfg.HooksConfig.PreCreate, h.PreCreate...)
+		cfg.HooksConfig.PostCreate = append(cfg.HooksConfig.PostCreate, h.PostCreate...)
+		cfg.HooksConfig.PreRemove = append(cfg.HooksConfig.PreRemove, h.PreRemove...)
+		cfg.HooksConfig.PostRemove = append(cfg.HooksConfig.PostRemove, h.PostRemove...)
+		cfg.HooksConfig.PostSync = append(cfg.HooksConfig.PostSync, h.PostSync...)
+	}
+
+	if a := fc.Artifacts; a != nil {
+		cfg.ArtifactConfig.Patterns = append(cfg.ArtifactConfig.Patterns, a.Patterns...)
+		if a.MaxFileSize != nil {
+			cfg.ArtifactConfig.MaxFileSize = int64(*a.MaxFileSize)
+		}
+		if a.MaxTotalSize != nil {
+			cfg.ArtifactConfig.MaxTotalSize = int64(*a.MaxTotalSize)
+		}
+	}
+}
+
+func setString(dst *string, value *string) {
+	if value != nil {
+		*dst = *value
+	}
+}
+
+func setBool(dst *bool, value *bool) {
+	if value != nil {
+		*dst = *value
+	}
+}
+
+// keyLines maps each dotted key in a TOML document to the line defining it.
+// It understands table headers and bare or quoted keys, which is enough to
+// point at unknown keys without a full parser.
+func keyLines(data []byte) map[string]int {
+	lines := make(map[string]int)
+	table := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			header := strings.Trim(line, "[] \t")
+			if idx := strings.Index(header, "#"); idx != -1 {
+				header = strings.TrimSpace(strings.TrimRight(header[:idx], "] \t"))
+			}
+			table = normalizeKey(header)
+			if _, ok := lines[table]; !ok {
+				lines[table] = lineNo
+			}
+			continue
+		}
+
+		idx := strings.Index(line, "=")
+		if idx == -1 {
+			continue
+		}
+		key := normalizeKey(line[:idx])
+		if table != "" {
+			key = table + "." + key
+		}
+		if _, ok := lines[key]; !ok {
+			lines[key] = lineNo
+		}
+	}
+
+	return lines
+}
+
+// normalizeKey strips whitespace and quotes around each part of a dotted key
+func normalizeKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+	return strings.Join(parts, ".")
+}
+
+// EncodeTOML renders a Config in the .agentree.toml format.
+// Values equal to their defaults are omitted to keep the file short.
+func EncodeTOML(cfg *Config) ([]byte, error) {
+	return encodeTOML(cfg, func(key *Key) bool { return key.changed(cfg) })
+}
+
+// encodeTOML renders the keys of cfg selected by include
+func encodeTOML(cfg *Config, include func(*Key) bool) ([]byte, error) {
+	keys := make(map[string]bool)
+	for _, key := range Keys {
+		keys[key.Name] = include(key)
+	}
+	str := func(name, value string) *string {
+		if !keys[name] {
+			return nil
+		}
+		return &value
+	}
+	flag := func(name string, value bool) *bool {
+		if !keys[name] {
+			return nil
+		}
+		return &value
+	}
+	list := func(name string, values []string) []string {
+		if !keys[name] {
+			return nil
+		}
+		return values
+	}
+	size := func(name string, value int64) *sizeValue {
+		if !keys[name] {
+			return nil
+		}
+		s := sizeValue(value)
+		return &s
+	}
+	number := func(name string, value int) *int {
+		if !keys[name] {
+			return nil
+		}
+		return &value
+	}
+
+	fc := fileConfig{
+		PostCreateScripts: list("post_create_scripts", cfg.PostCreateScripts),
+		PnpmSetup:         str("pnpm_setup", cfg.PnpmSetup),
+		NpmSetup:          str("npm_setup", cfg.NpmSetup),
+		YarnSetup:         str("yarn_setup", cfg.YarnSetup),
+		DefaultSetup:      str("default_setup", cfg.DefaultSetup),
+		BranchTemplate:    str("branch_template", cfg.BranchTemplate),
+		WorktreeRoot:      str("worktree_root", cfg.WorktreeRoot),
+		WorktreePath:      str("worktree_path_template", cfg.WorktreePathTemplate),
+		Agent:             str("agent", cfg.Agent),
+		ContextTemplate:   str("context_template", cfg.ContextTemplate),
+		TestCommand:       str("test_command", cfg.TestCommand),
+		Forge:             str("forge", cfg.Forge),
+	}
+
+	// Profiles aren't keys; keep them so saving a layer doesn't drop them
+	for name, p := range cfg.Agents {
+		if fc.Agents == nil {
+			fc.Agents = make(map[string]fileAgentProfile)
+		}
+		fc.Agents[name] = fileAgentProfile(p)
+	}
+
+	e := &fileEnvConfig{
+		Enabled:         flag("env.enabled", cfg.EnvConfig.Enabled),
+		Recursive:       flag("env.recursive", cfg.EnvConfig.Recursive),
+		UseGitignore:    flag("env.use_gitignore", cfg.EnvConfig.UseGitignore),
+		AuditEnabled:    flag("env.audit_enabled", cfg.EnvConfig.AuditEnabled),
+		AuditLog:        str("env.audit_log", cfg.EnvConfig.AuditLogPath),
+		DefaultStrategy: str("env.default_strategy", cfg.EnvConfig.DefaultStrategy),
+		SymlinkMode:     str("env.symlink_mode", cfg.EnvConfig.SymlinkMode),
+		Include:         list("env.include", cfg.EnvConfig.IncludePatterns),
+		Exclude:         list("env.exclude", cfg.EnvConfig.ExcludePatterns),
+		Symlink:         list("env.symlink", cfg.EnvConfig.SymlinkPatterns),
+		Hardlink:        list("env.hardlink", cfg.EnvConfig.HardlinkPatterns),
+	}
+	if !e.isEmpty() {
+		fc.Env = e
+	}
+
+	a := &fileArtifactConfig{
+		Patterns:     list("artifacts.patterns", cfg.ArtifactConfig.Patterns),
+		MaxFileSize:  size("artifacts.max_file_size", cfg.ArtifactConfig.MaxFileSize),
+		MaxTotalSize: size("artifacts.max_total_size", cfg.ArtifactConfig.MaxTotalSize),
+	}
+	if len(a.Patterns) > 0 || a.MaxFileSize != nil || a.MaxTotalSize != nil {
+		fc.Artifacts = a
+	}
+
+	t := &fileTmuxConfig{
+		Mode:       str("tmux.mode", cfg.TmuxConfig.Mode),
+		DevCommand: str("tmux.dev_command", cfg.TmuxConfig.DevCommand),
+		Socket:     str("tmux.socket", cfg.TmuxConfig.Socket),
+	}
+	if t.Mode != nil || t.DevCommand != nil || t.Socket != nil {
+		fc.Tmux = t
+	}
+
+	tr := &fileTrackerConfig{
+		Kind: str("tracker.kind", cfg.TrackerConfig.Kind),
+		Dir:  str("tracker.dir", cfg.TrackerConfig.Dir),
+	}
+	if tr.Kind != nil || tr.Dir != nil {
+		fc.Tracker = tr
+	}
+
+	m := &fileMCPConfig{
+		MaxWorktrees: number("mcp.max_worktrees", cfg.MCPConfig.MaxWorktrees),
+		BranchPrefix: str("mcp.branch_prefix", cfg.MCPConfig.BranchPrefix),
+	}
+	if m.MaxWorktrees != nil || m.BranchPrefix != nil {
+		fc.MCP = m
+	}
+
+	h := &fileHooksConfig{
+		PreCreate:  list("hooks.pre_create", cfg.HooksConfig.PreCreate),
+		PostCreate: list("hooks.post_create", cfg.HooksConfig.PostCreate),
+		PreRemove:  list("hooks.pre_remove", cfg.HooksConfig.PreRemove),
+		PostRemove: list("hooks.post_remove", cfg.HooksConfig.PostRemove),
+		PostSync:   list("hooks.post_sync", cfg.HooksConfig.PostSync),
+	}
+	if len(h.PreCreate) > 0 || len(h.PostCreate) > 0 || len(h.PreRemove) > 0 || len(h.PostRemove) > 0 || len(h.PostSync) > 0 {
+		fc.Hooks = h
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# agentree configuration\n")
+	buf.WriteString("# Schema: https://raw.githubusercontent.com/AryaLabsHQ/agentree/main/docs/agentree.schema.json\n\n")
+	if err := toml.NewEncoder(&buf).Encode(fc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// isEmpty reports whether no env setting is present
+func (e *fileEnvConfig) isEmpty() bool {
+	return e.Enabled == nil && e.Recursive == nil && e.UseGitignore == nil &&
+		e.AuditEnabled == nil && e.AuditLog == nil && e.DefaultStrategy == nil &&
+		e.SymlinkMode == nil && len(e.Include) == 0 && len(e.Exclude) == 0 &&
+		len(e.Symlink) == 0 && len(e.Hardlink) == 0
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLoadProjectConfig_TOML(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `post_create_scripts = ["pnpm install", "echo $(date)"]
+pnpm_setup = "pnpm install --frozen-lockfile"
+
+[env]
+enabled = true
+recursive = false
+include = ["*.env.example"]
+exclude = ["*.test.env"]
+symlink = [".env"]
+symlink_mode = "relative"
+
+[artifacts]
+patterns = ["gen/proto/"]
+max_file_size = "50MB"
+max_total_size = 1024
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, ProjectConfigFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The TOML file takes precedence over a legacy file
+	if err := os.WriteFile(filepath.Join(tmpDir, LegacyProjectConfigFile), []byte("POST_CREATE_SCRIPTS=(\n\"legacy\"\n)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadProjectConfig(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Config{
+		PostCreateScripts: []string{"pnpm install", "echo $(date)"},
+		PnpmSetup:         "pnpm install --frozen-lockfile",
+		EnvConfig: EnvConfig{
+			Enabled:         true,
+			Recursive:       false,
+			UseGitignore:    true,
+			AuditEnabled:    true,
+			IncludePatterns: []string{"*.env.example"},
+			ExcludePatterns: []string{"*.test.env"},
+			SymlinkPatterns: []string{".env"},
+			SymlinkMode:     "relative",
+		},
+		ArtifactConfig: ArtifactConfig{
+			Patterns:     []string{"gen/proto/"},
+			MaxFileSize:  50 << 20,
+			MaxTotalSize: 1024,
+		},
+		MCPConfig: MCPConfig{MaxWorktrees: 10, BranchPrefix: "agent/"},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("LoadProjectConfig() = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestParseTOMLConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "unknown keys",
+			content: "post_create_scripts = []\nfoo = 1\n\n[env]\nbogus = true\n",
+			want:    []string{`test.toml:2: unknown key "foo"`, `test.toml:5: unknown key "env.bogus"`},
+		},
+		{
+			name:    "wrong type",
+			content: "[env]\n\nenabled = \"yes\"\n",
+			want:    []string{"test.toml:3: env.enabled: incompatible types"},
+		},
+		{
+			name:    "invalid size",
+			content: "[artifacts]\nmax_file_size = \"lots\"\n",
+			want:    []string{"test.toml:2: artifacts.max_file_size: invalid size"},
+		},
+		{
+			name:    "syntax error",
+			content: "post_create_scripts = [\"a\"\n[env\n",
+			want:    []string{"test.toml:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTOMLConfig("test.toml", []byte(tt.content))
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Error %q does not contain %q", err, want)
+				}
+			}
+
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) {
+				t.Errorf("Expected a *ConfigError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestEncodeTOML_RoundTrip(t *testing.T) {
+	original := defaultConfig()
+	original.PostCreateScripts = []string{"npm install", "echo 'ready!'"}
+	original.NpmSetup = "npm ci"
+	original.EnvConfig.Recursive = false
+	original.EnvConfig.IncludePatterns = []string{"*.env.example"}
+	original.EnvConfig.HardlinkPatterns = []string{"*.pem"}
+	original.ArtifactConfig.Patterns = []string{".idea/"}
+	original.ArtifactConfig.MaxTotalSize = 1 << 30
+
+	data, err := EncodeTOML(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := parseTOMLConfig("roundtrip.toml", data)
+	if err != nil {
+		t.Fatalf("Encoded config does not parse: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("Round trip mismatch.\nGot:  %+v\nWant: %+v\n%s", decoded, original, data)
+	}
+
+	// Defaults are left out
+	if strings.Contains(string(data), "enabled") {
+		t.Errorf("Default values should not be encoded:\n%s", data)
+	}
+}
+
+// TestSchemaMatchesConfig keeps docs/agentree.schema.json in sync with fileConfig
+func TestSchemaMatchesConfig(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "docs", "agentree.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema struct {
+		Properties map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	var schemaKeys []string
+	for name, prop := range schema.Properties {
+		if len(prop.Properties) == 0 {
+			schemaKeys = append(schemaKeys, name)
+		}
+		for child := range prop.Properties {
+			schemaKeys = append(schemaKeys, name+"."+child)
+		}
+	}
+	sort.Strings(schemaKeys)
+
+	structKeys := tomlKeys(reflect.TypeOf(fileConfig{}), "")
+	sort.Strings(structKeys)
+
+	if !reflect.DeepEqual(schemaKeys, structKeys) {
+		t.Errorf("Schema keys %v do not match config keys %v", schemaKeys, structKeys)
+	}
+}
+
+// tomlKeys lists the dotted TOML keys of a struct type
+func tomlKeys(typ reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("toml"), ",")[0]
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct {
+			keys = append(keys, tomlKeys(fieldType, prefix+name+".")...)
+			continue
+		}
+		keys = append(keys, prefix+name)
+	}
+	return keys
+}
@@ -0,0 +1,192 @@
+// Package env handles environment file operations
+package env
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ArtifactOptions configures discovery of untracked build artifacts such as
+// generated code, downloaded fixtures or local certificates
+type ArtifactOptions struct {
+	// Patterns are globs relative to the repository root. A pattern that
+	// matches a directory selects every file below it.
+	Patterns []string
+	// MaxFileSize skips individual files larger than this many bytes (0 = no limit)
+	MaxFileSize int64
+	// MaxTotalSize stops adding files once the total reaches this many bytes (0 = no limit)
+	MaxTotalSize int64
+	// Tracked lists the files in the index, relative to the repository
+	// root. They are never artifacts: the worktree checks them out itself,
+	// and copying would overwrite them with the main checkout's changes.
+	Tracked []string
+}
+
+// DiscoverArtifacts finds files matching the artifact patterns.
+// Unlike DiscoverFiles it does not apply the environment keyword heuristic,
+// so anything the patterns select is a candidate.
+func (c *EnvFileCopier) DiscoverArtifacts(opts ArtifactOptions) ([]string, error) {
+	fileMap := make(map[string]int64)
+	tracked := make(map[string]bool, len(opts.Tracked))
+	for _, file := range opts.Tracked {
+		tracked[filepath.FromSlash(file)] = true
+	}
+
+	if c.verbose && len(opts.Patterns) > 0 {
+		fmt.Fprintln(c.stdout, "📦 Checking artifact patterns:")
+	}
+
+	for _, pattern := range opts.Patterns {
+		matches, err := c.findArtifactsMatchingPattern(pattern)
+		if err != nil {
+			c.warnf("invalid artifact pattern %s: %v", pattern, err)
+			continue
+		}
+		if c.verbose {
+			fmt.Fprintf(c.stdout, "   - %s (%d files)\n", pattern, len(matches))
+		}
+		for file, size := range matches {
+			if !tracked[file] {
+				fileMap[file] = size
+			}
+		}
+	}
+
+	var candidates []string
+	for file := range fileMap {
+		candidates = append(candidates, file)
+	}
+	sort.Strings(candidates)
+
+	// Apply size limits in a stable order
+	var files []string
+	var total, skippedSize int64
+	var skipped int
+	for _, file := range candidates {
+		size := fileMap[file]
+		if opts.MaxFileSize > 0 && size > opts.MaxFileSize {
+			c.warnf("skipping artifact %s (%s exceeds the %s file limit)",
+				file, FormatSize(size), FormatSize(opts.MaxFileSize))
+			continue
+		}
+		if opts.MaxTotalSize > 0 && total+size > opts.MaxTotalSize {
+			skipped++
+			skippedSize += size
+			continue
+		}
+		total += size
+		files = append(files, file)
+	}
+	if skipped > 0 {
+		c.warnf("skipped %d artifacts (%s) that would exceed the %s total limit",
+			skipped, FormatSize(skippedSize), FormatSize(opts.MaxTotalSize))
+	}
+
+	if c.verbose {
+		fmt.Fprintf(c.stdout, "\n📦 Total artifacts discovered: %d (%s)\n", len(files), FormatSize(total))
+	}
+
+	return files, nil
+}
+
+// findArtifactsMatchingPattern returns matching files and their sizes.
+// Directories are expanded recursively without following symlinks.
+func (c *EnvFileCopier) findArtifactsMatchingPattern(pattern string) (map[string]int64, error) {
+	matches := make(map[string]int64)
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	var roots []string
+	if strings.HasPrefix(pattern, "**/") {
+		// Match the remaining pattern against every path component
+		basePattern := strings.TrimPrefix(pattern, "**/")
+		err := filepath.WalkDir(c.srcDir, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() && (d.Name() == ".git" || c.skip[path]) {
+				return filepath.SkipDir
+			}
+			if matched, _ := filepath.Match(basePattern, d.Name()); matched && path != c.srcDir {
+				roots = append(roots, path)
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		globbed, err := filepath.Glob(filepath.Join(c.srcDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		roots = globbed
+	}
+
+	for _, root := range roots {
+		if c.skip.covers(root) {
+			continue
+		}
+		info, err := os.Lstat(root)
+		if err != nil {
+			continue
+		}
+		if dirOnly && !info.IsDir() {
+			continue
+		}
+		if !info.IsDir() {
+			if info.Mode().IsRegular() {
+				if relPath, err := filepath.Rel(c.srcDir, root); err == nil {
+					matches[relPath] = info.Size()
+				}
+			}
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() && (d.Name() == ".git" || c.skip[path]) {
+				return filepath.SkipDir
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			fileInfo, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if relPath, err := filepath.Rel(c.srcDir, path); err == nil {
+				matches[relPath] = fileInfo.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return matches, nil
+}
+
+// FormatSize renders a byte count in human readable units
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
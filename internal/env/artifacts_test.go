@@ -0,0 +1,131 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvFileCopier_DiscoverArtifacts(t *testing.T) {
+	srcDir := t.TempDir()
+
+	files := map[string]int{
+		"gen/proto/api.pb.go":              10,
+		"gen/proto/nested/types.pb.go":     10,
+		"fixtures/model.bin":               2048,
+		"certs/dev.pem":                    10,
+		".idea/runConfigurations/app.xml":  10,
+		"services/api/.idea/workspace.xml": 10,
+		"src/main.go":                      10,
+	}
+	for file, size := range files {
+		path := filepath.Join(srcDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	copier := NewEnvFileCopier(srcDir, t.TempDir())
+
+	tests := []struct {
+		name string
+		opts ArtifactOptions
+		want []string
+	}{
+		{
+			name: "directories are expanded",
+			opts: ArtifactOptions{Patterns: []string{"gen/proto/", ".idea"}},
+			want: []string{
+				".idea/runConfigurations/app.xml",
+				"gen/proto/api.pb.go",
+				"gen/proto/nested/types.pb.go",
+			},
+		},
+		{
+			name: "recursive patterns",
+			opts: ArtifactOptions{Patterns: []string{"**/.idea/"}},
+			want: []string{
+				".idea/runConfigurations/app.xml",
+				"services/api/.idea/workspace.xml",
+			},
+		},
+		{
+			name: "file globs",
+			opts: ArtifactOptions{Patterns: []string{"certs/*.pem", "fixtures/*.bin"}},
+			want: []string{"certs/dev.pem", "fixtures/model.bin"},
+		},
+		{
+			name: "file size limit",
+			opts: ArtifactOptions{Patterns: []string{"certs/", "fixtures/"}, MaxFileSize: 1024},
+			want: []string{"certs/dev.pem"},
+		},
+		{
+			name: "total size limit",
+			opts: ArtifactOptions{Patterns: []string{"gen/"}, MaxTotalSize: 15},
+			want: []string{"gen/proto/api.pb.go"},
+		},
+		{
+			name: "tracked files are skipped",
+			opts: ArtifactOptions{Patterns: []string{"gen/"}, Tracked: []string{"gen/proto/api.pb.go", "src/main.go"}},
+			want: []string{"gen/proto/nested/types.pb.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := copier.DiscoverArtifacts(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiscoverArtifacts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvFileCopier_SetOutput(t *testing.T) {
+	srcDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(srcDir, "model.bin"), []byte(strings.Repeat("x", 2048)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout strings.Builder
+	var warnings []string
+	copier := NewEnvFileCopier(srcDir, t.TempDir())
+	copier.SetOutput(&stdout, func(format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	})
+	copier.SetVerbose(true)
+
+	if _, err := copier.DiscoverArtifacts(ArtifactOptions{Patterns: []string{"*.bin"}, MaxFileSize: 1024}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "*.bin (1 files)") {
+		t.Errorf("stdout = %q, want the checked patterns", stdout.String())
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "model.bin") {
+		t.Errorf("warnings = %q, want the skipped model.bin", warnings)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		512:     "512B",
+		2048:    "2.0KB",
+		5 << 20: "5.0MB",
+		3 << 30: "3.0GB",
+	}
+
+	for size, want := range tests {
+		if got := FormatSize(size); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
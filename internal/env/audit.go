@@ -0,0 +1,98 @@
+// Package env handles environment file operations
+package env
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AryaLabsHQ/agentree/internal/config"
+)
+
+// AuditEntry records a single secret file copied into a worktree
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	Worktree string    `json:"worktree"`
+	File     string    `json:"file"`
+	// SHA256 fingerprints the contents so a leaked key can be traced
+	// without the audit log itself containing the secret
+	SHA256 string `json:"sha256"`
+}
+
+// AuditLog is an append-only JSON lines log of copied secret files
+type AuditLog struct {
+	path string
+}
+
+// NewAuditLog creates an audit log that appends to the given path
+func NewAuditLog(path string) *AuditLog {
+	return &AuditLog{path: path}
+}
+
+// DefaultAuditLogPath returns audit.log next to the global config: in the
+// directory of the file AGENTREE_CONFIG names, or in config.GlobalConfigDir
+func DefaultAuditLogPath() (string, error) {
+	if path := os.Getenv(config.ConfigPathEnvVar); path != "" {
+		return filepath.Join(filepath.Dir(path), "audit.log"), nil
+	}
+
+	configDir, err := config.GlobalConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "audit.log"), nil
+}
+
+// Path returns the location of the audit log
+func (a *AuditLog) Path() string {
+	return a.path
+}
+
+// Record appends an entry to the audit log.
+// The file is opened with O_APPEND so existing entries are never rewritten.
+func (a *AuditLog) Record(entry AuditEntry) (err error) {
+	if err := os.MkdirAll(filepath.Dir(a.path), 0700); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// fingerprintFile returns the hex encoded SHA-256 of a file's contents
+func fingerprintFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
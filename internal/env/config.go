@@ -0,0 +1,66 @@
+package env
+
+import (
+	"path/filepath"
+
+	"github.com/AryaLabsHQ/agentree/internal/config"
+)
+
+// NewConfiguredCopier builds an environment file copier from the merged
+// env config
+func NewConfiguredCopier(srcDir, destDir string, cfg *config.EnvConfig) (*EnvFileCopier, error) {
+	copier := NewEnvFileCopier(srcDir, destDir)
+
+	// Add custom patterns from config
+	if len(cfg.IncludePatterns) > 0 {
+		copier.AddCustomPatterns(cfg.IncludePatterns)
+	}
+
+	// Configure how files land in the worktree
+	defaultStrategy, err := ParseStrategy(cfg.DefaultStrategy)
+	if err != nil {
+		return nil, err
+	}
+	copier.SetDefaultStrategy(defaultStrategy)
+	copier.SetRelativeSymlinks(cfg.SymlinkMode == "relative")
+
+	var rules []StrategyRule
+	for _, pattern := range cfg.SymlinkPatterns {
+		rules = append(rules, StrategyRule{Pattern: pattern, Strategy: StrategySymlink})
+	}
+	for _, pattern := range cfg.HardlinkPatterns {
+		rules = append(rules, StrategyRule{Pattern: pattern, Strategy: StrategyHardlink})
+	}
+	copier.AddStrategyRules(rules)
+
+	// Record copied secrets so security can trace where keys ended up
+	if cfg.AuditEnabled {
+		auditPath := cfg.AuditLogPath
+		if auditPath == "" {
+			auditPath, _ = DefaultAuditLogPath()
+		}
+		if auditPath != "" {
+			copier.SetAuditLog(NewAuditLog(auditPath))
+		}
+	}
+
+	return copier, nil
+}
+
+// ExcludeFiles filters out files matching any of the exclude patterns
+func ExcludeFiles(files []string, patterns []string) []string {
+	var filteredFiles []string
+	for _, file := range files {
+		excluded := false
+		for _, pattern := range patterns {
+			if matched, _ := filepath.Match(pattern, file); matched {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			filteredFiles = append(filteredFiles, file)
+		}
+	}
+	return filteredFiles
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 )
 
 // EnvFileCopier handles intelligent copying of environment files
@@ -17,17 +18,52 @@ type EnvFileCopier struct {
 	parser         *GitignoreParser
 	customPatterns []string
 	verbose        bool
+	auditLog       *AuditLog
+	skip           skipDirs
+	stdout         io.Writer
+	warn           func(format string, args ...any)
+
+	defaultStrategy  Strategy
+	strategyRules    []StrategyRule
+	relativeSymlinks bool
 }
 
 // NewEnvFileCopier creates a new environment file copier
 func NewEnvFileCopier(srcDir, destDir string) *EnvFileCopier {
 	return &EnvFileCopier{
-		srcDir:  srcDir,
-		destDir: destDir,
-		parser:  NewGitignoreParser(srcDir),
+		srcDir:          srcDir,
+		destDir:         destDir,
+		parser:          NewGitignoreParser(srcDir),
+		defaultStrategy: StrategyCopy,
+		stdout:          os.Stdout,
 	}
 }
 
+// Dirs returns the directories files are copied from and to
+func (c *EnvFileCopier) Dirs() (srcDir, destDir string) {
+	return c.srcDir, c.destDir
+}
+
+// SetOutput sends verbose output to stdout and warnings, which are
+// formatted like fmt.Sprintf, to warn. By default they go to the process's
+// stdout and stderr.
+func (c *EnvFileCopier) SetOutput(stdout io.Writer, warn func(format string, args ...any)) {
+	c.stdout = stdout
+	c.warn = warn
+	if c.parser != nil {
+		c.parser.SetOutput(stdout)
+	}
+}
+
+// warnf reports a problem that doesn't stop discovery or copying
+func (c *EnvFileCopier) warnf(format string, args ...any) {
+	if c.warn != nil {
+		c.warn(format, args...)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
+
 // SetVerbose enables verbose logging
 func (c *EnvFileCopier) SetVerbose(verbose bool) {
 	c.verbose = verbose
@@ -36,6 +72,53 @@ func (c *EnvFileCopier) SetVerbose(verbose bool) {
 	}
 }
 
+// SetSkipDirs excludes directories from discovery. Worktrees placed inside
+// the repository must be skipped, or each new worktree would receive copies
+// of the ones created before it.
+func (c *EnvFileCopier) SetSkipDirs(dirs []string) {
+	c.skip = newSkipDirs(dirs)
+	if c.parser != nil {
+		c.parser.SetSkipDirs(dirs)
+	}
+}
+
+// SetAuditLog records every copied secret file in the given audit log
+func (c *EnvFileCopier) SetAuditLog(auditLog *AuditLog) {
+	c.auditLog = auditLog
+}
+
+// SetDefaultStrategy sets the strategy for files that match no strategy rule
+func (c *EnvFileCopier) SetDefaultStrategy(strategy Strategy) {
+	c.defaultStrategy = strategy
+}
+
+// AddStrategyRules adds per-pattern strategies; the first matching rule wins
+func (c *EnvFileCopier) AddStrategyRules(rules []StrategyRule) {
+	c.strategyRules = append(c.strategyRules, rules...)
+}
+
+// SetRelativeSymlinks makes symlinks relative to the worktree instead of absolute
+func (c *EnvFileCopier) SetRelativeSymlinks(relative bool) {
+	c.relativeSymlinks = relative
+}
+
+// StrategyFor returns the strategy that applies to a discovered file.
+// Secret files are copied instead of hardlinked: a hard link shares the
+// main checkout's inode and mode, so it couldn't be restricted to 0600.
+func (c *EnvFileCopier) StrategyFor(file string) Strategy {
+	strategy := c.defaultStrategy
+	for _, rule := range c.strategyRules {
+		if matchesGitignorePattern(file, rule.Pattern) {
+			strategy = rule.Strategy
+			break
+		}
+	}
+	if strategy == StrategyHardlink && IsSecretFile(file) {
+		return StrategyCopy
+	}
+	return strategy
+}
+
 // AddCustomPatterns adds custom patterns to search for
 func (c *EnvFileCopier) AddCustomPatterns(patterns []string) {
 	c.customPatterns = append(c.customPatterns, patterns...)
@@ -46,21 +129,21 @@ func (c *EnvFileCopier) DiscoverFiles() ([]string, error) {
 	fileMap := make(map[string]bool)
 	
 	if c.verbose {
-		fmt.Println("🔍 Starting environment file discovery...")
+		fmt.Fprintln(c.stdout, "🔍 Starting environment file discovery...")
 	}
 	
 	// 1. Find files from .gitignore patterns
 	ignoredFiles, err := c.parser.FindIgnoredEnvFiles()
 	if err != nil {
 		// Don't fail if we can't parse .gitignore, just continue
-		fmt.Fprintf(os.Stderr, "Warning: couldn't parse .gitignore files: %v\n", err)
+		c.warnf("couldn't parse .gitignore files: %v", err)
 		ignoredFiles = []string{}
 	}
 	
 	if c.verbose && len(ignoredFiles) > 0 {
-		fmt.Printf("📄 Found %d files from .gitignore patterns:\n", len(ignoredFiles))
+		fmt.Fprintf(c.stdout, "📄 Found %d files from .gitignore patterns:\n", len(ignoredFiles))
 		for _, file := range ignoredFiles {
-			fmt.Printf("   - %s\n", file)
+			fmt.Fprintf(c.stdout, "   - %s\n", file)
 		}
 	}
 	
@@ -71,9 +154,9 @@ func (c *EnvFileCopier) DiscoverFiles() ([]string, error) {
 	// 2. Add AI tool configuration files
 	aiConfigs := GetDefaultAIConfigPatterns()
 	if c.verbose {
-		fmt.Printf("🤖 Checking AI tool configuration patterns:\n")
+		fmt.Fprintf(c.stdout, "🤖 Checking AI tool configuration patterns:\n")
 		for _, pattern := range aiConfigs {
-			fmt.Printf("   - %s\n", pattern)
+			fmt.Fprintf(c.stdout, "   - %s\n", pattern)
 		}
 	}
 	
@@ -83,7 +166,7 @@ func (c *EnvFileCopier) DiscoverFiles() ([]string, error) {
 			continue
 		}
 		if c.verbose && len(matches) > 0 {
-			fmt.Printf("   ✓ Found %d matches for %s\n", len(matches), pattern)
+			fmt.Fprintf(c.stdout, "   ✓ Found %d matches for %s\n", len(matches), pattern)
 		}
 		for _, match := range matches {
 			fileMap[match] = true
@@ -92,9 +175,9 @@ func (c *EnvFileCopier) DiscoverFiles() ([]string, error) {
 	
 	// 3. Add custom patterns if provided
 	if c.verbose && len(c.customPatterns) > 0 {
-		fmt.Printf("🔧 Checking custom patterns:\n")
+		fmt.Fprintf(c.stdout, "🔧 Checking custom patterns:\n")
 		for _, pattern := range c.customPatterns {
-			fmt.Printf("   - %s\n", pattern)
+			fmt.Fprintf(c.stdout, "   - %s\n", pattern)
 		}
 	}
 	
@@ -104,7 +187,7 @@ func (c *EnvFileCopier) DiscoverFiles() ([]string, error) {
 			continue
 		}
 		if c.verbose && len(matches) > 0 {
-			fmt.Printf("   ✓ Found %d matches for %s\n", len(matches), pattern)
+			fmt.Fprintf(c.stdout, "   ✓ Found %d matches for %s\n", len(matches), pattern)
 		}
 		for _, match := range matches {
 			fileMap[match] = true
@@ -114,16 +197,16 @@ func (c *EnvFileCopier) DiscoverFiles() ([]string, error) {
 	// 4. Add legacy default files for backward compatibility
 	legacyFiles := []string{".env", ".dev.vars"}
 	if c.verbose {
-		fmt.Printf("📦 Checking legacy files for backward compatibility:\n")
+		fmt.Fprintf(c.stdout, "📦 Checking legacy files for backward compatibility:\n")
 	}
 	for _, file := range legacyFiles {
 		if c.fileExists(file) {
 			fileMap[file] = true
 			if c.verbose {
-				fmt.Printf("   ✓ Found %s\n", file)
+				fmt.Fprintf(c.stdout, "   ✓ Found %s\n", file)
 			}
 		} else if c.verbose {
-			fmt.Printf("   ✗ Not found: %s\n", file)
+			fmt.Fprintf(c.stdout, "   ✗ Not found: %s\n", file)
 		}
 	}
 	
@@ -135,13 +218,20 @@ func (c *EnvFileCopier) DiscoverFiles() ([]string, error) {
 	sort.Strings(files)
 	
 	if c.verbose {
-		fmt.Printf("\n📋 Total files discovered: %d\n", len(files))
+		fmt.Fprintf(c.stdout, "\n📋 Total files discovered: %d\n", len(files))
+		for _, file := range files {
+			if IsSecretFile(file) {
+				fmt.Fprintf(c.stdout, "   🔒 %s (secret, contents never printed)\n", file)
+			} else {
+				fmt.Fprintf(c.stdout, "   - %s\n", file)
+			}
+		}
 		if len(files) == 0 {
-			fmt.Println("   ⚠️  No environment files found!")
-			fmt.Println("   💡 Make sure:")
-			fmt.Println("      - Environment files exist in the repository")
-			fmt.Println("      - They are listed in .gitignore")
-			fmt.Println("      - Or use custom patterns with --include flag")
+			fmt.Fprintln(c.stdout, "   ⚠️  No environment files found!")
+			fmt.Fprintln(c.stdout, "   💡 Make sure:")
+			fmt.Fprintln(c.stdout, "      - Environment files exist in the repository")
+			fmt.Fprintln(c.stdout, "      - They are listed in .gitignore")
+			fmt.Fprintln(c.stdout, "      - Or use custom patterns with --include flag")
 		}
 	}
 	
@@ -162,19 +252,60 @@ func (c *EnvFileCopier) CopyFiles(files []string) ([]string, error) {
 			return copiedFiles, fmt.Errorf("failed to create directory %s: %w", destDir, err)
 		}
 		
-		// Copy the file
-		if err := copyFile(srcPath, destPath); err != nil {
+		// Place the file using its strategy
+		if err := c.placeFile(file, srcPath, destPath); err != nil {
 			// Log warning but continue with other files
-			fmt.Fprintf(os.Stderr, "Warning: failed to copy %s: %v\n", file, err)
+			c.warnf("failed to copy %s: %v", file, err)
 			continue
 		}
 		
 		copiedFiles = append(copiedFiles, file)
+		c.recordAudit(file, destPath)
 	}
 	
 	return copiedFiles, nil
 }
 
+// placeFile copies, symlinks or hardlinks a single file into the worktree
+func (c *EnvFileCopier) placeFile(file, srcPath, destPath string) error {
+	switch c.StrategyFor(file) {
+	case StrategySymlink:
+		return linkFile(srcPath, destPath, c.relativeSymlinks)
+	case StrategyHardlink:
+		if err := hardlinkFile(srcPath, destPath); err != nil {
+			// Hard links fail across filesystems, a copy is the closest fallback
+			c.warnf("failed to hardlink %s, copying instead: %v", file, err)
+			return copyFile(srcPath, destPath)
+		}
+		return nil
+	default:
+		return copyFile(srcPath, destPath)
+	}
+}
+
+// recordAudit appends a secret file copy to the audit log, if one is set
+func (c *EnvFileCopier) recordAudit(file, destPath string) {
+	if c.auditLog == nil || !IsSecretFile(file) {
+		return
+	}
+
+	fingerprint, err := fingerprintFile(destPath)
+	if err != nil {
+		c.warnf("failed to fingerprint %s for audit log: %v", file, err)
+	}
+
+	entry := AuditEntry{
+		Time:     time.Now().UTC(),
+		Source:   c.srcDir,
+		Worktree: c.destDir,
+		File:     file,
+		SHA256:   fingerprint,
+	}
+	if err := c.auditLog.Record(entry); err != nil {
+		c.warnf("failed to write audit log: %v", err)
+	}
+}
+
 // CopyAllDiscoveredFiles is a convenience method that discovers and copies files
 func (c *EnvFileCopier) CopyAllDiscoveredFiles() ([]string, error) {
 	files, err := c.DiscoverFiles()
@@ -197,7 +328,7 @@ func (c *EnvFileCopier) findFilesMatchingPattern(pattern string) ([]string, erro
 				return nil
 			}
 			
-			if info.IsDir() && info.Name() == ".git" {
+			if info.IsDir() && (info.Name() == ".git" || c.skip[path]) {
 				return filepath.SkipDir
 			}
 			
@@ -227,6 +358,9 @@ func (c *EnvFileCopier) findFilesMatchingPattern(pattern string) ([]string, erro
 	
 	// Convert to relative paths
 	for _, file := range files {
+		if c.skip.covers(file) {
+			continue
+		}
 		relPath, err := filepath.Rel(c.srcDir, file)
 		if err != nil {
 			continue
@@ -254,7 +388,11 @@ func CopyEnvFilesEnhanced(srcDir, destDir string, customPatterns []string) ([]st
 	return copier.CopyAllDiscoveredFiles()
 }
 
-// copyFile copies a file from src to dst
+// copyFile copies a file from src to dst.
+// The contents are written to a temporary file in the destination directory,
+// which os.CreateTemp creates with 0600 permissions, and then renamed into
+// place so a partially written secret is never visible under its final name.
+// The source mode is preserved minus any group and other permission bits.
 func copyFile(src, dst string) error {
 	// Open source file
 	srcFile, err := os.Open(src)
@@ -262,34 +400,54 @@ func copyFile(src, dst string) error {
 		return err
 	}
 	defer func() {
-		if err := srcFile.Close(); err != nil {
-			// Log error but don't fail since we already read the file
-			fmt.Fprintf(os.Stderr, "Warning: failed to close source file: %v\n", err)
-		}
+		_ = srcFile.Close()
 	}()
-	
-	// Create destination file
-	dstFile, err := os.Create(dst)
+
+	srcInfo, err := srcFile.Stat()
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary file next to the destination
+	tmpFile, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".agentree-*")
 	if err != nil {
 		return err
 	}
+	tmpPath := tmpFile.Name()
+	committed := false
 	defer func() {
-		if err := dstFile.Close(); err != nil {
-			// Log error but don't fail since we already wrote the file
-			fmt.Fprintf(os.Stderr, "Warning: failed to close destination file: %v\n", err)
+		if !committed {
+			_ = os.Remove(tmpPath)
 		}
 	}()
-	
+
 	// Copy the contents
-	if _, err := io.Copy(dstFile, srcFile); err != nil {
+	if _, err := io.Copy(tmpFile, srcFile); err != nil {
+		_ = tmpFile.Close()
 		return err
 	}
-	
-	// Copy file permissions
-	srcInfo, err := os.Stat(src)
-	if err != nil {
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
 		return err
 	}
-	
-	return os.Chmod(dst, srcInfo.Mode())
-}
\ No newline at end of file
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	// Apply the restricted source permissions before the file becomes visible
+	if err := os.Chmod(tmpPath, restrictedMode(srcInfo.Mode())); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, dst); err != nil {
+		return err
+	}
+	committed = true
+
+	return nil
+}
+
+// restrictedMode strips group and other permission bits from mode
+func restrictedMode(mode os.FileMode) os.FileMode {
+	return mode.Perm() &^ 0077
+}
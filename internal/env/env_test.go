@@ -1,8 +1,10 @@
 package env
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -240,4 +242,110 @@ func TestEnvFileCopier_CustomPatterns(t *testing.T) {
 			t.Errorf("Expected custom file %s was not discovered", expected)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestEnvFileCopier_CopyFilesRestrictsPermissions(t *testing.T) {
+	srcDir := t.TempDir()
+	destDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(srcDir, ".env"), []byte("API_KEY=secret123"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	copier := NewEnvFileCopier(srcDir, destDir)
+	if _, err := copier.CopyFiles([]string{".env"}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filepath.Join(destDir, ".env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Expected permissions 0600, got %o", perm)
+	}
+
+	// No temporary files should be left behind
+	entries, err := os.ReadDir(destDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only .env in destination, got %d entries", len(entries))
+	}
+}
+
+func TestEnvFileCopier_AuditLog(t *testing.T) {
+	srcDir := t.TempDir()
+	destDir := t.TempDir()
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+
+	files := map[string]string{
+		".env":                        "API_KEY=secret123",
+		".claude/settings.local.json": `{"theme": "dark"}`,
+	}
+	for file, content := range files {
+		filePath := filepath.Join(srcDir, file)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	copier := NewEnvFileCopier(srcDir, destDir)
+	copier.SetAuditLog(NewAuditLog(auditPath))
+	if _, err := copier.CopyFiles([]string{".env", ".claude/settings.local.json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the secret file is recorded
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 audit entry, got %d: %s", len(lines), data)
+	}
+
+	var entry AuditEntry
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.File != ".env" || entry.Worktree != destDir || entry.Source != srcDir {
+		t.Errorf("Unexpected audit entry: %+v", entry)
+	}
+	if entry.SHA256 == "" {
+		t.Error("Expected audit entry to carry a fingerprint")
+	}
+	if strings.Contains(string(data), "secret123") {
+		t.Error("Audit log must not contain secret values")
+	}
+}
+
+func TestDefaultAuditLogPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("AGENTREE_CONFIG", "")
+
+	tests := []struct {
+		name, xdg, config, want string
+	}{
+		{"home", "", "", filepath.Join(home, ".config", "agentree", "audit.log")},
+		{"XDG_CONFIG_HOME", "/xdg", "", filepath.Join("/xdg", "agentree", "audit.log")},
+		{"AGENTREE_CONFIG", "/xdg", "/etc/ci/agentree.toml", filepath.Join("/etc/ci", "audit.log")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", tt.xdg)
+			t.Setenv("AGENTREE_CONFIG", tt.config)
+			if got, err := DefaultAuditLogPath(); err != nil || got != tt.want {
+				t.Errorf("DefaultAuditLogPath() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
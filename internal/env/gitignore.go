@@ -4,6 +4,7 @@ package env
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,11 +14,13 @@ import (
 type GitignoreParser struct {
 	root    string
 	verbose bool
+	skip    skipDirs
+	stdout  io.Writer
 }
 
 // NewGitignoreParser creates a new parser for the given repository root
 func NewGitignoreParser(root string) *GitignoreParser {
-	return &GitignoreParser{root: root}
+	return &GitignoreParser{root: root, stdout: os.Stdout}
 }
 
 // SetVerbose enables verbose logging
@@ -25,6 +28,40 @@ func (p *GitignoreParser) SetVerbose(verbose bool) {
 	p.verbose = verbose
 }
 
+// SetOutput sends verbose output to stdout instead of the process's
+func (p *GitignoreParser) SetOutput(stdout io.Writer) {
+	p.stdout = stdout
+}
+
+// SetSkipDirs excludes directories, such as worktrees placed inside the
+// repository, from the search
+func (p *GitignoreParser) SetSkipDirs(dirs []string) {
+	p.skip = newSkipDirs(dirs)
+}
+
+// skipDirs is a set of absolute directories that discovery never enters
+type skipDirs map[string]bool
+
+func newSkipDirs(dirs []string) skipDirs {
+	skip := make(skipDirs, len(dirs))
+	for _, dir := range dirs {
+		skip[filepath.Clean(dir)] = true
+	}
+	return skip
+}
+
+// covers reports whether path is one of the directories or lies below one
+func (s skipDirs) covers(path string) bool {
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if s[dir] {
+			return true
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return false
+		}
+	}
+}
+
 // FindIgnoredEnvFiles discovers environment files based on .gitignore patterns
 func (p *GitignoreParser) FindIgnoredEnvFiles() ([]string, error) {
 	// Find all .gitignore files in the repository
@@ -34,10 +71,10 @@ func (p *GitignoreParser) FindIgnoredEnvFiles() ([]string, error) {
 	}
 	
 	if p.verbose {
-		fmt.Printf("📂 Found %d .gitignore files:\n", len(gitignoreFiles))
+		fmt.Fprintf(p.stdout, "📂 Found %d .gitignore files:\n", len(gitignoreFiles))
 		for _, file := range gitignoreFiles {
 			relPath, _ := filepath.Rel(p.root, file)
-			fmt.Printf("   - %s\n", relPath)
+			fmt.Fprintf(p.stdout, "   - %s\n", relPath)
 		}
 	}
 	
@@ -50,9 +87,9 @@ func (p *GitignoreParser) FindIgnoredEnvFiles() ([]string, error) {
 		}
 		if p.verbose && len(filePatterns) > 0 {
 			relPath, _ := filepath.Rel(p.root, gitignorePath)
-			fmt.Printf("\n   Patterns from %s:\n", relPath)
+			fmt.Fprintf(p.stdout, "\n   Patterns from %s:\n", relPath)
 			for _, pattern := range filePatterns {
-				fmt.Printf("     • %s\n", pattern)
+				fmt.Fprintf(p.stdout, "     • %s\n", pattern)
 			}
 		}
 		patterns = append(patterns, filePatterns...)
@@ -62,9 +99,9 @@ func (p *GitignoreParser) FindIgnoredEnvFiles() ([]string, error) {
 	envPatterns := p.filterEnvironmentPatterns(patterns)
 	
 	if p.verbose {
-		fmt.Printf("\n🔍 Filtered to %d environment-related patterns:\n", len(envPatterns))
+		fmt.Fprintf(p.stdout, "\n🔍 Filtered to %d environment-related patterns:\n", len(envPatterns))
 		for _, pattern := range envPatterns {
-			fmt.Printf("   - %s\n", pattern)
+			fmt.Fprintf(p.stdout, "   - %s\n", pattern)
 		}
 	}
 	
@@ -75,7 +112,7 @@ func (p *GitignoreParser) FindIgnoredEnvFiles() ([]string, error) {
 	}
 	
 	if p.verbose {
-		fmt.Printf("\n✅ Matched %d actual files from .gitignore patterns\n", len(matchedFiles))
+		fmt.Fprintf(p.stdout, "\n✅ Matched %d actual files from .gitignore patterns\n", len(matchedFiles))
 	}
 	
 	return matchedFiles, nil
@@ -90,8 +127,8 @@ func (p *GitignoreParser) findGitignoreFiles() ([]string, error) {
 			return nil // Skip directories we can't read
 		}
 		
-		// Skip .git directory
-		if info.IsDir() && info.Name() == ".git" {
+		// Skip .git directory and other worktrees
+		if info.IsDir() && (info.Name() == ".git" || p.skip[path]) {
 			return filepath.SkipDir
 		}
 		
@@ -112,10 +149,7 @@ func (p *GitignoreParser) parseGitignoreFile(path string) ([]string, error) {
 		return nil, err
 	}
 	defer func() {
-		if err := file.Close(); err != nil {
-			// Log error but don't fail since we already read the file
-			fmt.Fprintf(os.Stderr, "Warning: failed to close file: %v\n", err)
-		}
+		_ = file.Close()
 	}()
 	
 	var patterns []string
@@ -179,8 +213,8 @@ func (p *GitignoreParser) findMatchingFiles(patterns []string) ([]string, error)
 			return nil // Skip files we can't read
 		}
 		
-		// Skip .git directory
-		if info.IsDir() && info.Name() == ".git" {
+		// Skip .git directory and other worktrees
+		if info.IsDir() && (info.Name() == ".git" || p.skip[path]) {
 			return filepath.SkipDir
 		}
 		
@@ -0,0 +1,71 @@
+// Package env handles environment file operations
+package env
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maskedValue replaces every secret value that would otherwise be printed
+const maskedValue = "********"
+
+// secretKeywords are substrings of file names that indicate secret material
+var secretKeywords = []string{
+	".env",
+	".vars",
+	"secret",
+	"credential",
+	"token",
+	"password",
+	".pem",
+	".key",
+	".p12",
+	".pfx",
+}
+
+// IsSecretFile reports whether a path looks like it holds secret material.
+// Secret files are marked in verbose output and recorded in the audit log.
+func IsSecretFile(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	for _, keyword := range secretKeywords {
+		if strings.Contains(name, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskValue hides a value so it can be shown in diffs, plans and logs.
+// Empty values stay empty so that "unset" remains distinguishable.
+func MaskValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	return maskedValue
+}
+
+// RedactLine masks the value of a KEY=VALUE line, keeping the key visible.
+// Comments, blank lines and lines without an assignment are returned unchanged.
+func RedactLine(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return line
+	}
+
+	idx := strings.Index(line, "=")
+	if idx == -1 {
+		return line
+	}
+
+	value := strings.TrimSpace(line[idx+1:])
+	return line[:idx+1] + MaskValue(value)
+}
+
+// RedactContent masks every assignment in the contents of an env file
+func RedactContent(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = RedactLine(line)
+	}
+	return strings.Join(lines, "\n")
+}
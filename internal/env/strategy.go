@@ -0,0 +1,81 @@
+// Package env handles environment file operations
+package env
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Strategy describes how a file from the main checkout lands in a worktree
+type Strategy string
+
+const (
+	// StrategyCopy makes an independent snapshot of the file (the default)
+	StrategyCopy Strategy = "copy"
+	// StrategySymlink points the worktree file at the main checkout
+	StrategySymlink Strategy = "symlink"
+	// StrategyHardlink shares the file's inode with the main checkout
+	StrategyHardlink Strategy = "hardlink"
+)
+
+// ParseStrategy converts a config value into a Strategy.
+// An empty value selects StrategyCopy.
+func ParseStrategy(value string) (Strategy, error) {
+	switch Strategy(strings.ToLower(strings.TrimSpace(value))) {
+	case "", StrategyCopy:
+		return StrategyCopy, nil
+	case StrategySymlink:
+		return StrategySymlink, nil
+	case StrategyHardlink:
+		return StrategyHardlink, nil
+	default:
+		return "", fmt.Errorf("unknown file strategy %q (expected copy, symlink or hardlink)", value)
+	}
+}
+
+// StrategyRule applies a strategy to files matching a gitignore-style pattern
+type StrategyRule struct {
+	Pattern  string
+	Strategy Strategy
+}
+
+// linkFile creates dst as a symlink to src.
+// The link is created under a temporary name and renamed into place so an
+// existing file at dst is replaced atomically.
+func linkFile(src, dst string, relative bool) error {
+	target := src
+	if relative {
+		rel, err := filepath.Rel(filepath.Dir(dst), src)
+		if err != nil {
+			return err
+		}
+		target = rel
+	}
+
+	tmpPath := dst + ".agentree-link"
+	_ = os.Remove(tmpPath)
+	if err := os.Symlink(target, tmpPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, dst); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// hardlinkFile creates dst as a hard link to src
+func hardlinkFile(src, dst string) error {
+	tmpPath := dst + ".agentree-link"
+	_ = os.Remove(tmpPath)
+	if err := os.Link(src, tmpPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, dst); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseStrategy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Strategy
+		wantErr bool
+	}{
+		{"", StrategyCopy, false},
+		{"copy", StrategyCopy, false},
+		{"Symlink", StrategySymlink, false},
+		{"hardlink", StrategyHardlink, false},
+		{"rsync", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseStrategy(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStrategy(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseStrategy(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvFileCopier_StrategyFor(t *testing.T) {
+	copier := NewEnvFileCopier(t.TempDir(), t.TempDir())
+	copier.AddStrategyRules([]StrategyRule{
+		{Pattern: ".env", Strategy: StrategySymlink},
+		{Pattern: "**/.env.local", Strategy: StrategyHardlink},
+		{Pattern: "*.db", Strategy: StrategyHardlink},
+	})
+
+	tests := map[string]Strategy{
+		".env":              StrategySymlink,
+		"packages/app/.env": StrategySymlink,
+		"data/dev.db":       StrategyHardlink,
+		".dev.vars":         StrategyCopy,
+		// Secrets are never hardlinked, so they can be narrowed to 0600
+		"packages/api/.env.local": StrategyCopy,
+	}
+
+	for file, want := range tests {
+		if got := copier.StrategyFor(file); got != want {
+			t.Errorf("StrategyFor(%q) = %q, want %q", file, got, want)
+		}
+	}
+}
+
+func TestEnvFileCopier_CopyFilesWithStrategies(t *testing.T) {
+	srcDir := t.TempDir()
+	destDir := t.TempDir()
+
+	for _, file := range []string{".env", "app/.dev.vars", "data/dev.db", ".env.local"} {
+		path := filepath.Join(srcDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("KEY=value"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	copier := NewEnvFileCopier(srcDir, destDir)
+	copier.SetRelativeSymlinks(true)
+	copier.AddStrategyRules([]StrategyRule{
+		{Pattern: ".env", Strategy: StrategySymlink},
+		{Pattern: ".dev.vars", Strategy: StrategyHardlink},
+		{Pattern: "*.db", Strategy: StrategyHardlink},
+	})
+
+	copied, err := copier.CopyFiles([]string{".env", "app/.dev.vars", "data/dev.db", ".env.local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(copied) != 4 {
+		t.Fatalf("Expected 4 files to be placed, got %v", copied)
+	}
+
+	// Symlink is relative and resolves to the source
+	target, err := os.Readlink(filepath.Join(destDir, ".env"))
+	if err != nil {
+		t.Fatalf(".env should be a symlink: %v", err)
+	}
+	if filepath.IsAbs(target) {
+		t.Errorf("Expected relative symlink, got %s", target)
+	}
+	if content, err := os.ReadFile(filepath.Join(destDir, ".env")); err != nil || string(content) != "KEY=value" {
+		t.Errorf("Symlink does not resolve to source: %q, %v", content, err)
+	}
+
+	// Hardlink shares the source inode
+	srcInfo, _ := os.Stat(filepath.Join(srcDir, "data/dev.db"))
+	destInfo, err := os.Stat(filepath.Join(destDir, "data/dev.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(srcInfo, destInfo) {
+		t.Error("Expected data/dev.db to be a hard link to the source")
+	}
+
+	// A secret matching a hardlink rule is copied with restricted permissions
+	srcInfo, _ = os.Stat(filepath.Join(srcDir, "app/.dev.vars"))
+	destInfo, err = os.Stat(filepath.Join(destDir, "app/.dev.vars"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os.SameFile(srcInfo, destInfo) {
+		t.Error("app/.dev.vars is a secret and should not be hard linked")
+	}
+	if perm := destInfo.Mode().Perm(); perm != 0600 {
+		t.Errorf("Expected app/.dev.vars permissions 0600, got %o", perm)
+	}
+
+	// Unmatched files are copied
+	info, err := os.Lstat(filepath.Join(destDir, ".env.local"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		t.Error(".env.local should be a regular copy")
+	}
+}
@@ -0,0 +1,61 @@
+// Package forge knows how code hosts publish pull requests as git refs
+package forge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Forge is a code host such as GitHub
+type Forge interface {
+	// PullRef is the ref holding the head of pull request n
+	PullRef(n int) string
+	// PullBranch names the local branch a pull request is checked out to
+	PullBranch(n int) string
+}
+
+// New returns the forge of the given kind. An empty kind is detected from
+// the URL of the remote pull requests are fetched from.
+func New(kind, remoteURL string) (Forge, error) {
+	if kind == "" {
+		kind = Detect(remoteURL)
+	}
+	switch kind {
+	case "github":
+		return GitHub{}, nil
+	case "gitlab":
+		return GitLab{}, nil
+	}
+	return nil, fmt.Errorf("unknown forge %q", kind)
+}
+
+// Detect guesses the forge from a remote URL. Hosts that don't look like
+// GitLab are taken for GitHub, whose refs Gitea and Forgejo use as well.
+func Detect(remoteURL string) string {
+	if strings.Contains(strings.ToLower(remoteURL), "gitlab") {
+		return "gitlab"
+	}
+	return "github"
+}
+
+// GitHub publishes pull requests as refs/pull/<n>/head
+type GitHub struct{}
+
+func (GitHub) PullRef(n int) string {
+	return fmt.Sprintf("refs/pull/%d/head", n)
+}
+
+func (GitHub) PullBranch(n int) string {
+	return fmt.Sprintf("pr/%d", n)
+}
+
+// GitLab publishes merge requests as refs/merge-requests/<n>/head
+type GitLab struct{}
+
+func (GitLab) PullRef(n int) string {
+	return fmt.Sprintf("refs/merge-requests/%d/head", n)
+}
+
+func (GitLab) PullBranch(n int) string {
+	return fmt.Sprintf("mr/%d", n)
+}
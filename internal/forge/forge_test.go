@@ -0,0 +1,33 @@
+package forge
+
+import "testing"
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		kind, url       string
+		wantRef, wantBr string
+		wantErr         bool
+	}{
+		{"", "git@github.com:AryaLabsHQ/agentree.git", "refs/pull/7/head", "pr/7", false},
+		{"", "https://gitlab.example.com/team/app.git", "refs/merge-requests/7/head", "mr/7", false},
+		{"", "https://git.example.com/team/app.git", "refs/pull/7/head", "pr/7", false},
+		{"gitlab", "https://code.example.com/team/app.git", "refs/merge-requests/7/head", "mr/7", false},
+		{"bitbucket", "", "", "", true},
+	}
+	for _, tt := range tests {
+		f, err := New(tt.kind, tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("New(%q, %q) error = %v, wantErr %v", tt.kind, tt.url, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if ref := f.PullRef(7); ref != tt.wantRef {
+			t.Errorf("New(%q, %q).PullRef(7) = %q, want %q", tt.kind, tt.url, ref, tt.wantRef)
+		}
+		if branch := f.PullBranch(7); branch != tt.wantBr {
+			t.Errorf("New(%q, %q).PullBranch(7) = %q, want %q", tt.kind, tt.url, branch, tt.wantBr)
+		}
+	}
+}
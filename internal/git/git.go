@@ -3,11 +3,15 @@
 package git
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/AryaLabsHQ/agentree/internal/lock"
 )
 
 // Repository represents a Git repository with methods for worktree operations.
@@ -17,93 +21,405 @@ type Repository struct {
 	Root string
 	// RepoName is the name of the repository (e.g., "agentree")
 	RepoName string
+
+	// runner runs every git command; nil means DefaultRunner
+	runner Runner
+	// ctx cancels running git commands; nil means context.Background
+	ctx context.Context
 }
 
-// NewRepository creates a new Repository instance by finding the Git root.
+// NewRepository creates a new Repository instance by finding the Git root
+// of the current directory.
 // Functions that create new instances often start with "New" in Go.
 func NewRepository() (*Repository, error) {
-	// exec.Command runs external commands (like subprocess in Python)
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-	
-	// Run the command and capture output
-	output, err := cmd.Output()
+	return Open(context.Background(), "", nil)
+}
+
+// Open returns the repository containing dir, or the current directory
+// when dir is empty. Its git commands go through runner, DefaultRunner
+// when nil, and are cancelled with ctx.
+func Open(ctx context.Context, dir string, runner Runner) (*Repository, error) {
+	if runner == nil {
+		runner = DefaultRunner
+	}
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		dir = wd
+	}
+	repo := &Repository{runner: runner, ctx: ctx}
+
+	output, err := repo.run(dir, "rev-parse", "--show-toplevel")
 	if err != nil {
 		// In Go, we return errors instead of throwing exceptions
 		return nil, fmt.Errorf("not in a git repository: %w", err)
 	}
 	
 	// strings.TrimSpace removes leading/trailing whitespace (including newlines)
-	root := strings.TrimSpace(string(output))
+	repo.Root = strings.TrimSpace(string(output))
 	
 	// filepath.Base gets the last element of a path (like basename in shell)
-	repoName := filepath.Base(root)
+	repo.RepoName = filepath.Base(repo.Root)
 	
-	repo := &Repository{
-		Root:     root,
-		RepoName: repoName,
+	// Fetching is up to the commands that need fresh remote refs
+	return repo, nil
+}
+
+// WithContext returns a copy of the repository whose git commands are
+// cancelled with ctx
+func (r *Repository) WithContext(ctx context.Context) *Repository {
+	copied := *r
+	copied.ctx = ctx
+	return &copied
+}
+
+// Context returns the context the repository's git commands are
+// cancelled with
+func (r *Repository) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
 	}
-	
-	// Fetch updates from remote
-	if err := repo.Fetch(); err != nil {
-		// Don't fail if fetch fails, just warn
-		fmt.Fprintf(os.Stderr, "Warning: git fetch failed: %v\n", err)
+	return r.ctx
+}
+
+// run runs git in dir through the repository's runner; an empty dir
+// is the repository root
+func (r *Repository) run(dir string, args ...string) ([]byte, error) {
+	runner, ctx := r.runner, r.ctx
+	if runner == nil {
+		runner = DefaultRunner
 	}
-	
-	return repo, nil
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if dir == "" {
+		dir = r.Root
+	}
+	return runner.Run(ctx, dir, args...)
 }
 
-// Fetch runs git fetch to update remote refs
+// FetchInterval is how long a full fetch is considered fresh
+const FetchInterval = 5 * time.Minute
+
+// fetchStamp is the file whose modification time records the last full
+// fetch, below the common git directory
+const fetchStamp = "agentree/last-fetch"
+
+// Fetch runs git fetch --prune for the default remote and records when
+// it did
 func (r *Repository) Fetch() error {
-	cmd := exec.Command("git", "fetch", "--prune")
-	cmd.Dir = r.Root
-	return cmd.Run()
+	if _, err := r.run("", "fetch", "--prune", "--quiet"); err != nil {
+		return err
+	}
+
+	commonDir, err := r.CommonDir()
+	if err != nil {
+		return err
+	}
+	stamp := filepath.Join(commonDir, filepath.FromSlash(fetchStamp))
+	if err := os.MkdirAll(filepath.Dir(stamp), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(stamp, []byte(time.Now().UTC().Format(time.RFC3339)+"\n"), 0644)
+}
+
+// LastFetch returns when Fetch last succeeded, or the zero time
+func (r *Repository) LastFetch() time.Time {
+	commonDir, err := r.CommonDir()
+	if err != nil {
+		return time.Time{}
+	}
+	info, err := os.Stat(filepath.Join(commonDir, filepath.FromSlash(fetchStamp)))
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// FetchRef updates the remote-tracking branch of one branch on remote
+func (r *Repository) FetchRef(remote, branch string) error {
+	refspec := fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", branch, remote, branch)
+	_, err := r.run("", "fetch", "--no-tags", "--quiet", remote, refspec)
+	return err
 }
 
 // CreateWorktree creates a new worktree for the given branch.
 // Methods in Go are functions with a receiver (r *Repository)
 func (r *Repository) CreateWorktree(branch, base, dest string) error {
 	// First, let's check if the branch already exists
-	checkCmd := exec.Command("git", "show-ref", "--verify", "--quiet", "refs/heads/"+branch)
-	if err := checkCmd.Run(); err == nil {
+	if r.BranchExists(branch) {
 		return fmt.Errorf("branch %s already exists", branch)
 	}
 	
-	// Create the branch
-	createCmd := exec.Command("git", "branch", branch, base)
-	createCmd.Dir = r.Root
-	if output, err := createCmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to create branch: %s", output)
+	// Create the branch; a remote base mustn't become its upstream, or
+	// pushing the agent's work would go to the base
+	if _, err := r.run("", "branch", "--no-track", branch, base); err != nil {
+		return fmt.Errorf("failed to create branch: %w", err)
 	}
 	
 	// Add the worktree
-	addCmd := exec.Command("git", "worktree", "add", dest, branch)
-	addCmd.Dir = r.Root
-	if output, err := addCmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to add worktree: %s", output)
+	if _, err := r.run("", "worktree", "add", dest, branch); err != nil {
+		return fmt.Errorf("failed to add worktree: %w", err)
 	}
 	
 	return nil
 }
 
+// CheckoutWorktree adds a worktree for an existing branch and returns the
+// local branch name. A local branch is checked out as it is; a remote one,
+// given as <remote>/<branch> or by a name only one remote has, gets a
+// local branch that tracks it.
+func (r *Repository) CheckoutWorktree(name, dest string) (string, error) {
+	branch := name
+	args := []string{"worktree", "add", dest, name}
+	if !r.BranchExists(name) {
+		remoteRef, remoteBranch, err := r.RemoteBranch(name)
+		if err != nil {
+			return "", err
+		}
+		if r.BranchExists(remoteBranch) {
+			return "", fmt.Errorf("local branch %s already exists; check it out instead of %s", remoteBranch, remoteRef)
+		}
+		branch = remoteBranch
+		args = []string{"worktree", "add", "--track", "-b", branch, dest, remoteRef}
+	}
+
+	if _, err := r.run("", args...); err != nil {
+		return "", fmt.Errorf("failed to add worktree: %w", err)
+	}
+	return branch, nil
+}
+
+// RemoteBranch finds the remote-tracking branch name refers to, either
+// as <remote>/<branch> or as a branch name that exactly one remote has.
+// It returns the remote-tracking name and the branch name on the remote.
+func (r *Repository) RemoteBranch(name string) (string, string, error) {
+	remotes, err := r.remoteBranches()
+	if err != nil {
+		return "", "", err
+	}
+
+	var matches [][2]string
+	for _, rb := range remotes {
+		if rb[0]+"/"+rb[1] == name {
+			return name, rb[1], nil
+		}
+		if rb[1] == name {
+			matches = append(matches, rb)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", "", fmt.Errorf("no local or remote branch named %q", name)
+	case 1:
+		return matches[0][0] + "/" + matches[0][1], name, nil
+	}
+	var names []string
+	for _, rb := range matches {
+		names = append(names, rb[0]+"/"+rb[1])
+	}
+	return "", "", fmt.Errorf("%q is on several remotes: %s", name, strings.Join(names, ", "))
+}
+
+// remoteBranches lists remote-tracking branches as remote and branch name
+// pairs, leaving out the remotes' HEAD aliases
+func (r *Repository) remoteBranches() ([][2]string, error) {
+	output, err := r.run("", "remote")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remotes: %w", err)
+	}
+	remotes := splitLines(string(output))
+
+	output, err = r.run("", "for-each-ref", "--format=%(refname) %(symref)", "refs/remotes")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote branches: %w", err)
+	}
+
+	var branches [][2]string
+	for _, line := range splitLines(string(output)) {
+		ref, symref, _ := strings.Cut(line, " ")
+		if symref != "" {
+			continue
+		}
+		name := strings.TrimPrefix(ref, "refs/remotes/")
+		// Remote names may contain slashes; the longest matching one wins
+		remote := ""
+		for _, candidate := range remotes {
+			if strings.HasPrefix(name, candidate+"/") && len(candidate) > len(remote) {
+				remote = candidate
+			}
+		}
+		if remote != "" {
+			branches = append(branches, [2]string{remote, strings.TrimPrefix(name, remote+"/")})
+		}
+	}
+	return branches, nil
+}
+
+// ResolveBase checks that rev names a commit: a branch, tag or SHA. A
+// branch that only exists on a remote resolves to its remote-tracking
+// name, e.g. feature to origin/feature.
+func (r *Repository) ResolveBase(rev string) (string, error) {
+	if _, err := r.run("", "rev-parse", "--verify", "--quiet", rev+"^{commit}"); err == nil {
+		return rev, nil
+	}
+	if remoteRef, _, err := r.RemoteBranch(rev); err == nil {
+		return remoteRef, nil
+	}
+	return "", fmt.Errorf("%q is not a branch, tag or commit", rev)
+}
+
+// RemoteURL returns the fetch URL of remote
+func (r *Repository) RemoteURL(remote string) (string, error) {
+	output, err := r.run("", "remote", "get-url", remote)
+	if err != nil {
+		return "", fmt.Errorf("no remote named %s", remote)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// FetchBranch fetches ref from remote into the local branch and makes
+// the ref its upstream, so git pull in the worktree picks up new commits.
+// An existing branch is only fast-forwarded.
+func (r *Repository) FetchBranch(remote, ref, branch string) error {
+	if _, err := r.run("", "fetch", "--no-tags", remote, ref+":refs/heads/"+branch); err != nil {
+		return fmt.Errorf("failed to fetch %s from %s: %w", ref, remote, err)
+	}
+
+	for key, value := range map[string]string{"remote": remote, "merge": ref} {
+		if _, err := r.run("", "config", "branch."+branch+"."+key, value); err != nil {
+			return fmt.Errorf("failed to set upstream of %s: %w", branch, err)
+		}
+	}
+	return nil
+}
+
+// Push pushes branch from the worktree at dir to remote and makes it the
+// branch's upstream
+func (r *Repository) Push(dir, remote, branch string) error {
+	_, err := r.run(dir, "push", "-u", remote, branch)
+	return err
+}
+
+// BranchExists reports whether a local branch with the given name exists
+func (r *Repository) BranchExists(branch string) bool {
+	_, err := r.run("", "show-ref", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// CheckBranchName validates a branch name with git check-ref-format
+func (r *Repository) CheckBranchName(branch string) error {
+	if _, err := r.run("", "check-ref-format", "--branch", branch); err != nil {
+		return fmt.Errorf("%q is not a valid branch name", branch)
+	}
+	return nil
+}
+
 // GetDefaultWorktreeDir returns the default directory for worktrees
 func (r *Repository) GetDefaultWorktreeDir() string {
 	parent := filepath.Dir(r.Root)
 	return filepath.Join(parent, r.RepoName+"-worktrees")
 }
 
+// CommonDir returns the absolute path of the git directory shared by all
+// worktrees, e.g. <root>/.git
+func (r *Repository) CommonDir() (string, error) {
+	output, err := r.run("", "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("failed to find git directory: %w", err)
+	}
+
+	dir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(r.Root, dir)
+	}
+	return dir, nil
+}
+
+// Lock takes the lock agentree processes working on this repository
+// share, waiting for as long as lock.Timeout and the repository's context
+// allow. Hold it while choosing names and paths and changing git state, not
+// during setup. waiting, if not nil, is told which process holds the lock
+// when it's another one than this.
+func (r *Repository) Lock(waiting func(holder string)) (*lock.Lock, error) {
+	timeout, err := lock.Timeout()
+	if err != nil {
+		return nil, err
+	}
+	commonDir, err := r.CommonDir()
+	if err != nil {
+		return nil, err
+	}
+	self := fmt.Sprintf("pid %d", os.Getpid())
+	return lock.Acquire(r.Context(), filepath.Join(commonDir, "agentree.lock"), timeout, func(holder string) {
+		// Creates running side by side in this process, such as the items
+		// of a batch, wait for each other quietly
+		if waiting == nil || holder == self || strings.HasPrefix(holder, self+":") {
+			return
+		}
+		waiting(holder)
+	})
+}
+
+// Exclude adds pattern to .git/info/exclude unless it is already listed, so
+// files that only exist locally don't show up as untracked. The check and
+// the append happen under a lock of their own, since worktrees being set
+// up add patterns without holding the repository lock.
+func (r *Repository) Exclude(pattern string) error {
+	commonDir, err := r.CommonDir()
+	if err != nil {
+		return err
+	}
+	timeout, err := lock.Timeout()
+	if err != nil {
+		return err
+	}
+	excludeLock, err := lock.Acquire(r.Context(), filepath.Join(commonDir, "agentree-exclude.lock"), timeout, nil)
+	if err != nil {
+		return err
+	}
+	defer excludeLock.Release()
+	path := filepath.Join(commonDir, "info", "exclude")
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == pattern {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	entry := pattern + "\n"
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		entry = "\n" + entry
+	}
+	if _, err := file.WriteString(entry); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
 // CurrentBranch returns the current branch name or HEAD commit
 func (r *Repository) CurrentBranch() (string, error) {
 	// Try to get symbolic ref first
-	cmd := exec.Command("git", "symbolic-ref", "--quiet", "--short", "HEAD")
-	cmd.Dir = r.Root
-	if output, err := cmd.Output(); err == nil {
+	if output, err := r.run("", "symbolic-ref", "--quiet", "--short", "HEAD"); err == nil {
 		return strings.TrimSpace(string(output)), nil
 	}
 	
 	// Fall back to commit hash
-	cmd = exec.Command("git", "rev-parse", "--short", "HEAD")
-	cmd.Dir = r.Root
-	output, err := cmd.Output()
+	output, err := r.run("", "rev-parse", "--short", "HEAD")
 	if err != nil {
 		return "", fmt.Errorf("failed to get current branch: %w", err)
 	}
@@ -111,24 +427,29 @@ func (r *Repository) CurrentBranch() (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
-// ListBranches returns a list of all local branches
-func (r *Repository) ListBranches() ([]string, error) {
-	cmd := exec.Command("git", "branch", "--format=%(refname:short)")
-	cmd.Dir = r.Root
-	output, err := cmd.Output()
+// LocalBranches returns the names of all local branches
+func (r *Repository) LocalBranches() ([]string, error) {
+	output, err := r.run("", "branch", "--format=%(refname:short)")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list branches: %w", err)
 	}
-	
-	// Split output into lines and filter empty ones
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	branches := make([]string, 0, len(lines))
-	for _, line := range lines {
-		if line = strings.TrimSpace(line); line != "" {
-			branches = append(branches, line)
-		}
+	return splitLines(string(output)), nil
+}
+
+// ListBranches returns the local branches followed by the remote-tracking
+// ones, e.g. origin/feature
+func (r *Repository) ListBranches() ([]string, error) {
+	branches, err := r.LocalBranches()
+	if err != nil {
+		return nil, err
+	}
+	remotes, err := r.remoteBranches()
+	if err != nil {
+		return nil, err
+	}
+	for _, rb := range remotes {
+		branches = append(branches, rb[0]+"/"+rb[1])
 	}
-	
 	return branches, nil
 }
 
@@ -141,9 +462,7 @@ type WorktreeInfo struct {
 // FindWorktree finds a worktree by branch name or path
 func (r *Repository) FindWorktree(target string) (*WorktreeInfo, error) {
 	// Get list of worktrees
-	cmd := exec.Command("git", "worktree", "list", "--porcelain")
-	cmd.Dir = r.Root
-	output, err := cmd.Output()
+	output, err := r.run("", "worktree", "list", "--porcelain")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list worktrees: %w", err)
 	}
@@ -205,10 +524,8 @@ func (r *Repository) RemoveWorktree(path string, force bool) error {
 	}
 	args = append(args, path)
 	
-	cmd := exec.Command("git", args...)
-	cmd.Dir = r.Root
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to remove worktree: %s", output)
+	if _, err := r.run("", args...); err != nil {
+		return fmt.Errorf("failed to remove worktree: %w", err)
 	}
 	
 	return nil
@@ -216,10 +533,8 @@ func (r *Repository) RemoveWorktree(path string, force bool) error {
 
 // DeleteBranch deletes a local branch
 func (r *Repository) DeleteBranch(branch string) error {
-	cmd := exec.Command("git", "branch", "-D", branch)
-	cmd.Dir = r.Root
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to delete branch: %s", output)
+	if _, err := r.run("", "branch", "-D", branch); err != nil {
+		return fmt.Errorf("failed to delete branch: %w", err)
 	}
 	
 	return nil
@@ -227,9 +542,7 @@ func (r *Repository) DeleteBranch(branch string) error {
 
 // ListWorktrees returns a list of all worktree paths
 func (r *Repository) ListWorktrees() ([]string, error) {
-	cmd := exec.Command("git", "worktree", "list", "--porcelain")
-	cmd.Dir = r.Root
-	output, err := cmd.Output()
+	output, err := r.run("", "worktree", "list", "--porcelain")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list worktrees: %w", err)
 	}
@@ -245,4 +558,274 @@ func (r *Repository) ListWorktrees() ([]string, error) {
 	}
 	
 	return worktrees, nil
-}
\ No newline at end of file
+}
+// Worktrees returns every worktree with its branch, starting with the main
+// checkout. Branch is empty for a detached HEAD.
+func (r *Repository) Worktrees() ([]WorktreeInfo, error) {
+	output, err := r.run("", "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+
+	var worktrees []WorktreeInfo
+	for _, line := range strings.Split(string(output), "\n") {
+		if path, ok := strings.CutPrefix(line, "worktree "); ok {
+			worktrees = append(worktrees, WorktreeInfo{Path: path})
+		} else if ref, ok := strings.CutPrefix(line, "branch "); ok && len(worktrees) > 0 {
+			worktrees[len(worktrees)-1].Branch = strings.TrimPrefix(ref, "refs/heads/")
+		}
+	}
+	return worktrees, nil
+}
+
+// RecentCommits returns the last n commits of ref as "<short hash> <subject>"
+func (r *Repository) RecentCommits(ref string, n int) ([]string, error) {
+	output, err := r.run("", "log", "--format=%h %s", fmt.Sprintf("-n%d", n), ref, "--")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commits of %s: %w", ref, err)
+	}
+	return splitLines(string(output)), nil
+}
+
+// TrackedFiles lists the files tracked in the worktree at dir, relative to it
+func (r *Repository) TrackedFiles(dir string) ([]string, error) {
+	output, err := r.run(dir, "ls-files", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	var files []string
+	for _, file := range strings.Split(string(output), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// IsTracked reports whether file is tracked in the worktree at dir
+func (r *Repository) IsTracked(dir, file string) bool {
+	_, err := r.run(dir, "ls-files", "--error-unmatch", "--", file)
+	return err == nil
+}
+
+// SkipWorktree makes git ignore local changes to a tracked file in the
+// worktree at dir, so they don't end up in a commit
+func (r *Repository) SkipWorktree(dir, file string) error {
+	if _, err := r.run(dir, "update-index", "--skip-worktree", "--", file); err != nil {
+		return fmt.Errorf("failed to hide changes to %s: %w", file, err)
+	}
+	return nil
+}
+
+// DiffStat sums up the changes in the worktree at dir since it forked
+// from base, uncommitted ones included. Untracked files aren't counted.
+type DiffStat struct {
+	Files      int
+	Insertions int
+	Deletions  int
+}
+
+// Diff returns the diffstat of the worktree at dir against base
+func (r *Repository) Diff(dir, base string) (*DiffStat, error) {
+	fork, err := r.mergeBase(dir, base)
+	if err != nil {
+		return nil, err
+	}
+	output, err := r.run(dir, "diff", "--numstat", fork)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff against %s: %w", base, err)
+	}
+
+	stat := &DiffStat{}
+	for _, line := range splitLines(string(output)) {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		stat.Files++
+		// Binary files show "-" instead of line counts
+		if n, err := strconv.Atoi(fields[0]); err == nil {
+			stat.Insertions += n
+		}
+		if n, err := strconv.Atoi(fields[1]); err == nil {
+			stat.Deletions += n
+		}
+	}
+	return stat, nil
+}
+
+// ChangedFiles lists the files changed in the worktree at dir since it
+// forked from base, followed by untracked files
+func (r *Repository) ChangedFiles(dir, base string) ([]string, error) {
+	fork, err := r.mergeBase(dir, base)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := r.run(dir, "diff", "--name-only", fork)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff against %s: %w", base, err)
+	}
+	untracked, err := r.run(dir, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list untracked files: %w", err)
+	}
+	return append(splitLines(string(changed)), splitLines(string(untracked))...), nil
+}
+
+// CommitsAhead counts the commits of the worktree at dir that base lacks
+func (r *Repository) CommitsAhead(dir, base string) (int, error) {
+	output, err := r.run(dir, "rev-list", "--count", base+"..HEAD")
+	if err != nil {
+		return 0, fmt.Errorf("failed to count commits ahead of %s: %w", base, err)
+	}
+	return strconv.Atoi(strings.TrimSpace(string(output)))
+}
+
+// CommitsBehind counts the commits of base that the worktree at dir lacks
+func (r *Repository) CommitsBehind(dir, base string) (int, error) {
+	output, err := r.run(dir, "rev-list", "--count", "HEAD.."+base)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count commits behind %s: %w", base, err)
+	}
+	return strconv.Atoi(strings.TrimSpace(string(output)))
+}
+
+// Dirty reports whether the worktree at dir has uncommitted changes to
+// tracked files
+func (r *Repository) Dirty(dir string) (bool, error) {
+	output, err := r.run(dir, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return false, fmt.Errorf("failed to read the status of %s: %w", dir, err)
+	}
+	return strings.TrimSpace(string(output)) != "", nil
+}
+
+// Upstream returns the remote branch branch tracks, e.g. origin/main
+func (r *Repository) Upstream(branch string) (string, error) {
+	output, err := r.run("", "rev-parse", "--abbrev-ref", "--symbolic-full-name", branch+"@{upstream}")
+	if err != nil {
+		return "", fmt.Errorf("%s has no upstream: %w", branch, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// IsAncestor reports whether commit ancestor is reachable from commit rev
+func (r *Repository) IsAncestor(ancestor, rev string) bool {
+	_, err := r.run("", "merge-base", "--is-ancestor", ancestor, rev)
+	return err == nil
+}
+
+// Rebase rebases the branch checked out at dir onto onto. A rebase that
+// stops on conflicts is aborted, leaving the worktree as it was.
+func (r *Repository) Rebase(dir, onto string) error {
+	if _, err := r.run(dir, "rebase", onto); err != nil {
+		_, _ = r.run(dir, "rebase", "--abort")
+		return fmt.Errorf("failed to rebase onto %s: %w", onto, err)
+	}
+	return nil
+}
+
+// mergeBase returns the commit where HEAD of the worktree at dir forked from base
+func (r *Repository) mergeBase(dir, base string) (string, error) {
+	output, err := r.run(dir, "merge-base", base, "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to find where HEAD forked from %s: %w", base, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// splitLines splits command output into its non-empty lines
+func splitLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// ResolveWorktree finds a worktree by branch or path like FindWorktree, and
+// otherwise by a fuzzy match on branch and directory names
+func (r *Repository) ResolveWorktree(query string) (*WorktreeInfo, error) {
+	if info, err := r.FindWorktree(query); err == nil {
+		return info, nil
+	}
+
+	worktrees, err := r.Worktrees()
+	if err != nil {
+		return nil, err
+	}
+	matches := MatchWorktrees(worktrees, query)
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no worktree matches %q", query)
+	case 1:
+		return &matches[0], nil
+	}
+
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = m.Branch
+		if names[i] == "" {
+			names[i] = m.Path
+		}
+	}
+	return nil, fmt.Errorf("%q matches several worktrees: %s", query, strings.Join(names, ", "))
+}
+
+// MatchWorktrees returns the worktrees that match query best. A worktree
+// matches on its branch, the last component of its branch, or its directory
+// name: exactly, then by containing query, then by containing the characters
+// of query in order. Case is ignored.
+func MatchWorktrees(worktrees []WorktreeInfo, query string) []WorktreeInfo {
+	query = strings.ToLower(query)
+	if query == "" {
+		return nil
+	}
+
+	var matches []WorktreeInfo
+	best := 0
+	for _, wt := range worktrees {
+		names := []string{filepath.Base(wt.Path)}
+		if wt.Branch != "" {
+			names = append(names, wt.Branch, wt.Branch[strings.LastIndex(wt.Branch, "/")+1:])
+		}
+
+		score := 0
+		for _, name := range names {
+			score = max(score, matchScore(strings.ToLower(name), query))
+		}
+		switch {
+		case score == 0 || score < best:
+		case score > best:
+			best = score
+			matches = []WorktreeInfo{wt}
+		default:
+			matches = append(matches, wt)
+		}
+	}
+	return matches
+}
+
+// matchScore rates how well name matches query: 3 for equal, 2 for a
+// substring, 1 for a subsequence, 0 for no match
+func matchScore(name, query string) int {
+	switch {
+	case name == query:
+		return 3
+	case strings.Contains(name, query):
+		return 2
+	}
+
+	rest := query
+	for _, r := range name {
+		if next, ok := strings.CutPrefix(rest, string(r)); ok {
+			rest = next
+		}
+		if rest == "" {
+			return 1
+		}
+	}
+	return 0
+}
@@ -1,10 +1,16 @@
 package git
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // setupTestRepo creates a temporary git repository for testing
@@ -124,17 +130,7 @@ func TestGetDefaultWorktreeDir(t *testing.T) {
 	tmpDir, cleanup := setupTestRepo(t)
 	defer cleanup()
 	
-	oldWd, _ := os.Getwd()
-	if err := os.Chdir(tmpDir); err != nil {
-		t.Fatalf("Failed to change directory: %v", err)
-	}
-	defer func() {
-		if err := os.Chdir(oldWd); err != nil {
-			t.Errorf("Failed to restore directory: %v", err)
-		}
-	}()
-	
-	repo, err := NewRepository()
+	repo, err := Open(context.Background(), tmpDir, nil)
 	if err != nil {
 		t.Fatalf("Failed to create repository: %v", err)
 	}
@@ -157,17 +153,7 @@ func TestCurrentBranch(t *testing.T) {
 	tmpDir, cleanup := setupTestRepo(t)
 	defer cleanup()
 	
-	oldWd, _ := os.Getwd()
-	if err := os.Chdir(tmpDir); err != nil {
-		t.Fatalf("Failed to change directory: %v", err)
-	}
-	defer func() {
-		if err := os.Chdir(oldWd); err != nil {
-			t.Errorf("Failed to restore directory: %v", err)
-		}
-	}()
-	
-	repo, err := NewRepository()
+	repo, err := Open(context.Background(), tmpDir, nil)
 	if err != nil {
 		t.Fatalf("Failed to create repository: %v", err)
 	}
@@ -205,17 +191,7 @@ func TestCreateWorktree(t *testing.T) {
 	tmpDir, cleanup := setupTestRepo(t)
 	defer cleanup()
 	
-	oldWd, _ := os.Getwd()
-	if err := os.Chdir(tmpDir); err != nil {
-		t.Fatalf("Failed to change directory: %v", err)
-	}
-	defer func() {
-		if err := os.Chdir(oldWd); err != nil {
-			t.Errorf("Failed to restore directory: %v", err)
-		}
-	}()
-	
-	repo, err := NewRepository()
+	repo, err := Open(context.Background(), tmpDir, nil)
 	if err != nil {
 		t.Fatalf("Failed to create repository: %v", err)
 	}
@@ -265,16 +241,6 @@ func TestListBranches(t *testing.T) {
 	tmpDir, cleanup := setupTestRepo(t)
 	defer cleanup()
 	
-	oldWd, _ := os.Getwd()
-	if err := os.Chdir(tmpDir); err != nil {
-		t.Fatalf("Failed to change directory: %v", err)
-	}
-	defer func() {
-		if err := os.Chdir(oldWd); err != nil {
-			t.Errorf("Failed to restore directory: %v", err)
-		}
-	}()
-	
 	// Create some test branches
 	cmd := exec.Command("git", "branch", "test-branch-1")
 	cmd.Dir = tmpDir
@@ -288,7 +254,7 @@ func TestListBranches(t *testing.T) {
 		t.Fatalf("Failed to create test-branch-2: %v", err)
 	}
 	
-	repo, err := NewRepository()
+	repo, err := Open(context.Background(), tmpDir, nil)
 	if err != nil {
 		t.Fatalf("Failed to create repository: %v", err)
 	}
@@ -318,17 +284,7 @@ func TestFindWorktree(t *testing.T) {
 	tmpDir, cleanup := setupTestRepo(t)
 	defer cleanup()
 	
-	oldWd, _ := os.Getwd()
-	if err := os.Chdir(tmpDir); err != nil {
-		t.Fatalf("Failed to change directory: %v", err)
-	}
-	defer func() {
-		if err := os.Chdir(oldWd); err != nil {
-			t.Errorf("Failed to restore directory: %v", err)
-		}
-	}()
-	
-	repo, err := NewRepository()
+	repo, err := Open(context.Background(), tmpDir, nil)
 	if err != nil {
 		t.Fatalf("Failed to create repository: %v", err)
 	}
@@ -390,17 +346,7 @@ func TestRemoveWorktree(t *testing.T) {
 	tmpDir, cleanup := setupTestRepo(t)
 	defer cleanup()
 	
-	oldWd, _ := os.Getwd()
-	if err := os.Chdir(tmpDir); err != nil {
-		t.Fatalf("Failed to change directory: %v", err)
-	}
-	defer func() {
-		if err := os.Chdir(oldWd); err != nil {
-			t.Errorf("Failed to restore directory: %v", err)
-		}
-	}()
-	
-	repo, err := NewRepository()
+	repo, err := Open(context.Background(), tmpDir, nil)
 	if err != nil {
 		t.Fatalf("Failed to create repository: %v", err)
 	}
@@ -428,16 +374,6 @@ func TestDeleteBranch(t *testing.T) {
 	tmpDir, cleanup := setupTestRepo(t)
 	defer cleanup()
 	
-	oldWd, _ := os.Getwd()
-	if err := os.Chdir(tmpDir); err != nil {
-		t.Fatalf("Failed to change directory: %v", err)
-	}
-	defer func() {
-		if err := os.Chdir(oldWd); err != nil {
-			t.Errorf("Failed to restore directory: %v", err)
-		}
-	}()
-	
 	// Create a test branch
 	cmd := exec.Command("git", "branch", "test-delete")
 	cmd.Dir = tmpDir
@@ -445,7 +381,7 @@ func TestDeleteBranch(t *testing.T) {
 		t.Fatalf("Failed to create test branch: %v", err)
 	}
 	
-	repo, err := NewRepository()
+	repo, err := Open(context.Background(), tmpDir, nil)
 	if err != nil {
 		t.Fatalf("Failed to create repository: %v", err)
 	}
@@ -463,4 +399,446 @@ func TestDeleteBranch(t *testing.T) {
 			t.Error("Expected branch to be deleted")
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestBranchExists(t *testing.T) {
+	tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	repo := &Repository{Root: tmpDir, RepoName: filepath.Base(tmpDir)}
+	if !repo.BranchExists("main") {
+		t.Error("Expected main to exist")
+	}
+	if repo.BranchExists("agent/missing") {
+		t.Error("Expected agent/missing not to exist")
+	}
+}
+
+func TestCheckBranchName(t *testing.T) {
+	repo := &Repository{Root: t.TempDir()}
+	tests := []struct {
+		branch  string
+		wantErr bool
+	}{
+		{"agent/fix-login", false},
+		{"ai/claude/PROJ-1-fix", false},
+		{"has space", true},
+		{"double..dot", true},
+		{"ends.lock", true},
+		{"-leading-dash", true},
+	}
+
+	for _, tt := range tests {
+		if err := repo.CheckBranchName(tt.branch); (err != nil) != tt.wantErr {
+			t.Errorf("CheckBranchName(%q) error = %v, wantErr %v", tt.branch, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExclude(t *testing.T) {
+	tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	repo := &Repository{Root: tmpDir, RepoName: filepath.Base(tmpDir)}
+	for i := 0; i < 2; i++ {
+		if err := repo.Exclude("/.worktrees/"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, ".git", "info", "exclude"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "/.worktrees/"); n != 1 {
+		t.Errorf("Expected the pattern once, found it %d times:\n%s", n, data)
+	}
+
+	// Excluded directories no longer show up as untracked
+	if err := os.MkdirAll(filepath.Join(tmpDir, ".worktrees", "a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, ".worktrees", "a", "file"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("git", "status", "--porcelain")
+	cmd.Dir = tmpDir
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(output), ".worktrees") {
+		t.Errorf("git status still lists the excluded directory:\n%s", output)
+	}
+
+	// Worktrees set up side by side don't lose or repeat each other's patterns
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, pattern := range []string{"/shared", fmt.Sprintf("/own-%d", i)} {
+				if err := repo.Exclude(pattern); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	data, err = os.ReadFile(filepath.Join(tmpDir, ".git", "info", "exclude"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(data), "\n")
+	for _, pattern := range []string{"/shared", "/own-0", "/own-9"} {
+		n := 0
+		for _, line := range lines {
+			if line == pattern {
+				n++
+			}
+		}
+		if n != 1 {
+			t.Errorf("Expected %s once, found it %d times:\n%s", pattern, n, data)
+		}
+	}
+}
+
+func TestWorktrees(t *testing.T) {
+	tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	repo := &Repository{Root: tmpDir, RepoName: filepath.Base(tmpDir)}
+	dest := filepath.Join(t.TempDir(), "with space")
+	if err := repo.CreateWorktree("agent/listed", "main", dest); err != nil {
+		t.Fatal(err)
+	}
+
+	worktrees, err := repo.Worktrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(worktrees) != 2 {
+		t.Fatalf("Expected 2 worktrees, got %+v", worktrees)
+	}
+	if worktrees[0].Branch != "main" {
+		t.Errorf("First worktree should be the main checkout, got %+v", worktrees[0])
+	}
+	if worktrees[1].Branch != "agent/listed" || filepath.Base(worktrees[1].Path) != "with space" {
+		t.Errorf("Unexpected worktree %+v", worktrees[1])
+	}
+}
+
+func TestMatchWorktrees(t *testing.T) {
+	worktrees := []WorktreeInfo{
+		{Path: "/src/app", Branch: "main"},
+		{Path: "/src/app-worktrees/agent-claude-login", Branch: "agent/claude/login"},
+		{Path: "/src/app-worktrees/agent-login-page", Branch: "agent/login-page"},
+		{Path: "/src/app-worktrees/agent-fix-api", Branch: "agent/fix-api"},
+		{Path: "/src/app-worktrees/detached"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"main", []string{"/src/app"}},
+		{"login", []string{"/src/app-worktrees/agent-claude-login"}},
+		{"LOGIN-P", []string{"/src/app-worktrees/agent-login-page"}},
+		{"detached", []string{"/src/app-worktrees/detached"}},
+		{"fxapi", []string{"/src/app-worktrees/agent-fix-api"}},
+		{"agent", []string{
+			"/src/app-worktrees/agent-claude-login",
+			"/src/app-worktrees/agent-login-page",
+			"/src/app-worktrees/agent-fix-api",
+		}},
+		{"zzz", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var got []string
+			for _, wt := range MatchWorktrees(worktrees, tt.query) {
+				got = append(got, wt.Path)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("MatchWorktrees(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveWorktree(t *testing.T) {
+	tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	repo := &Repository{Root: tmpDir, RepoName: filepath.Base(tmpDir)}
+	dest := filepath.Join(t.TempDir(), "agent-login-page")
+	if err := repo.CreateWorktree("agent/login-page", "main", dest); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{"agent/login-page", "login", dest} {
+		info, err := repo.ResolveWorktree(query)
+		if err != nil {
+			t.Fatalf("ResolveWorktree(%q): %v", query, err)
+		}
+		if info.Branch != "agent/login-page" {
+			t.Errorf("ResolveWorktree(%q) = %+v", query, info)
+		}
+	}
+
+	if _, err := repo.ResolveWorktree("nothing"); err == nil {
+		t.Error("Expected an error for a query without matches")
+	}
+}
+
+func TestContextHelpers(t *testing.T) {
+	tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+	repo := &Repository{Root: tmpDir, RepoName: filepath.Base(tmpDir)}
+
+	commits, err := repo.RecentCommits("main", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || !strings.HasSuffix(commits[0], " Initial commit") {
+		t.Errorf("RecentCommits() = %v", commits)
+	}
+
+	files, err := repo.TrackedFiles(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0] != "README.md" {
+		t.Errorf("TrackedFiles() = %v", files)
+	}
+
+	if !repo.IsTracked(tmpDir, "README.md") || repo.IsTracked(tmpDir, "missing.md") {
+		t.Error("IsTracked() reports the wrong files")
+	}
+
+	// Hidden changes don't show up in status
+	if err := repo.SkipWorktree(tmpDir, "README.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "README.md"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("git", "status", "--porcelain")
+	cmd.Dir = tmpDir
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output) != 0 {
+		t.Errorf("Status should be clean, got %q", output)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tmpDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+	repo := &Repository{Root: tmpDir, RepoName: filepath.Base(tmpDir)}
+	dest := filepath.Join(t.TempDir(), "wt")
+	if err := repo.CreateWorktree("agent/diff", "main", dest); err != nil {
+		t.Fatal(err)
+	}
+
+	// One commit, one uncommitted change and one untracked file
+	if err := os.WriteFile(filepath.Join(dest, "a.txt"), []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"add", "a.txt"}, {"commit", "-m", "Add a"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dest
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dest, "README.md"), []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dest, "new.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stat, err := repo.Diff(dest, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *stat != (DiffStat{Files: 2, Insertions: 3, Deletions: 1}) {
+		t.Errorf("Diff() = %+v", stat)
+	}
+
+	files, err := repo.ChangedFiles(dest, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(files, ",") != "README.md,a.txt,new.txt" {
+		t.Errorf("ChangedFiles() = %v", files)
+	}
+
+	if n, err := repo.CommitsAhead(dest, "main"); err != nil || n != 1 {
+		t.Errorf("CommitsAhead() = %d, %v", n, err)
+	}
+}
+
+// setupClone returns a clone of a repository whose origin has a branch
+// named feature and a pull request ref for #7
+func setupClone(t *testing.T) *Repository {
+	t.Helper()
+	upstream, cleanup := setupTestRepo(t)
+	t.Cleanup(cleanup)
+	clone := filepath.Join(t.TempDir(), "clone")
+
+	run := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	run(upstream, "branch", "feature")
+	run(upstream, "checkout", "-q", "-b", "contrib")
+	run(upstream, "commit", "-q", "--allow-empty", "-m", "Contribution")
+	run(upstream, "update-ref", "refs/pull/7/head", "contrib")
+	run(upstream, "checkout", "-q", "main")
+	run(upstream, "tag", "v1")
+	run(filepath.Dir(clone), "clone", "-q", upstream, clone)
+	run(clone, "config", "user.name", "Test User")
+	run(clone, "config", "user.email", "test@example.com")
+
+	return &Repository{Root: clone, RepoName: "clone"}
+}
+
+func TestListBranchesRemote(t *testing.T) {
+	repo := setupClone(t)
+
+	branches, err := repo.ListBranches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"main", "origin/contrib", "origin/feature", "origin/main"}
+	if !reflect.DeepEqual(branches, want) {
+		t.Errorf("ListBranches() = %v, want %v", branches, want)
+	}
+
+	local, err := repo.LocalBranches()
+	if err != nil || !reflect.DeepEqual(local, []string{"main"}) {
+		t.Errorf("LocalBranches() = %v, %v; want [main]", local, err)
+	}
+}
+
+func TestCheckoutWorktree(t *testing.T) {
+	repo := setupClone(t)
+
+	// A remote branch gets a local branch tracking it
+	dest := filepath.Join(t.TempDir(), "feature")
+	branch, err := repo.CheckoutWorktree("feature", dest)
+	if err != nil {
+		t.Fatalf("CheckoutWorktree(feature) error = %v", err)
+	}
+	if branch != "feature" {
+		t.Errorf("CheckoutWorktree(feature) = %q, want feature", branch)
+	}
+	upstream, err := exec.Command("git", "-C", dest, "rev-parse", "--abbrev-ref", "@{u}").Output()
+	if err != nil || strings.TrimSpace(string(upstream)) != "origin/feature" {
+		t.Errorf("upstream = %q, %v; want origin/feature", upstream, err)
+	}
+
+	// The local branch exists now, so origin/feature is refused
+	if _, err := repo.CheckoutWorktree("origin/feature", filepath.Join(t.TempDir(), "again")); err == nil {
+		t.Error("CheckoutWorktree(origin/feature) succeeded although feature exists")
+	}
+	if _, err := repo.CheckoutWorktree("missing", filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("CheckoutWorktree(missing) succeeded")
+	}
+
+	// Existing local branches are checked out as they are
+	if err := exec.Command("git", "-C", repo.Root, "branch", "local").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if branch, err := repo.CheckoutWorktree("local", filepath.Join(t.TempDir(), "local")); err != nil || branch != "local" {
+		t.Errorf("CheckoutWorktree(local) = %q, %v", branch, err)
+	}
+}
+
+func TestResolveBase(t *testing.T) {
+	repo := setupClone(t)
+	head, err := exec.Command("git", "-C", repo.Root, "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		rev, want string
+		wantErr   bool
+	}{
+		{"main", "main", false},
+		{"v1", "v1", false},
+		{strings.TrimSpace(string(head)), strings.TrimSpace(string(head)), false},
+		{"origin/feature", "origin/feature", false},
+		{"feature", "origin/feature", false},
+		{"nope", "", true},
+	}
+	for _, tt := range tests {
+		got, err := repo.ResolveBase(tt.rev)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ResolveBase(%q) = %q, %v; want %q, error %v", tt.rev, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFetchBranch(t *testing.T) {
+	repo := setupClone(t)
+
+	if err := repo.FetchBranch("origin", "refs/pull/7/head", "pr/7"); err != nil {
+		t.Fatalf("FetchBranch() error = %v", err)
+	}
+	subject, err := exec.Command("git", "-C", repo.Root, "log", "-1", "--format=%s", "pr/7").Output()
+	if err != nil || strings.TrimSpace(string(subject)) != "Contribution" {
+		t.Errorf("pr/7 is at %q, %v; want the contribution", subject, err)
+	}
+	merge, _ := exec.Command("git", "-C", repo.Root, "config", "branch.pr/7.merge").Output()
+	if strings.TrimSpace(string(merge)) != "refs/pull/7/head" {
+		t.Errorf("branch.pr/7.merge = %q, want refs/pull/7/head", merge)
+	}
+
+	if err := repo.FetchBranch("origin", "refs/pull/8/head", "pr/8"); err == nil {
+		t.Error("FetchBranch() of a missing pull request succeeded")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	repo := setupClone(t)
+
+	if !repo.LastFetch().IsZero() {
+		t.Error("LastFetch() is set before any fetch")
+	}
+	if err := repo.Fetch(); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if since := time.Since(repo.LastFetch()); since < 0 || since > time.Minute {
+		t.Errorf("LastFetch() is %s ago, want just now", since)
+	}
+
+	// A new branch upstream only shows up once its ref is fetched
+	upstream, err := exec.Command("git", "-C", repo.Root, "remote", "get-url", "origin").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Command("git", "-C", strings.TrimSpace(string(upstream)), "branch", "later").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := repo.RemoteBranch("later"); err == nil {
+		t.Fatal("RemoteBranch(later) found a branch that wasn't fetched yet")
+	}
+	if err := repo.FetchRef("origin", "later"); err != nil {
+		t.Fatalf("FetchRef() error = %v", err)
+	}
+	if ref, _, err := repo.RemoteBranch("later"); err != nil || ref != "origin/later" {
+		t.Errorf("RemoteBranch(later) = %q, %v after FetchRef", ref, err)
+	}
+}
@@ -0,0 +1,90 @@
+// Package gittest provides a fake git.Runner, so tests can answer git
+// commands without a repository and simulate failures
+package gittest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/AryaLabsHQ/agentree/internal/git"
+)
+
+// Call is a git command the Runner received
+type Call struct {
+	Dir  string
+	Args []string
+}
+
+// String returns the arguments joined with spaces, the form Responses
+// are keyed by
+func (c Call) String() string {
+	return strings.Join(c.Args, " ")
+}
+
+// Response is the answer to a faked command. A response with Stderr or
+// Err set fails with a *git.CommandError.
+type Response struct {
+	Output string
+	Stderr string
+	Err    error
+}
+
+// Runner answers git commands from Responses, keyed by the arguments
+// joined with spaces. Other commands go to Fallback, or fail when it is
+// nil. Every command is recorded, so wrapping a real runner records a
+// session that can later be replayed from Responses.
+type Runner struct {
+	Responses map[string]Response
+	Fallback  git.Runner
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// Run implements git.Runner
+func (r *Runner) Run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	call := Call{Dir: dir, Args: append([]string(nil), args...)}
+	r.mu.Lock()
+	r.calls = append(r.calls, call)
+	r.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, &git.CommandError{Args: args, Err: err}
+	}
+	response, ok := r.Responses[call.String()]
+	if !ok {
+		if r.Fallback != nil {
+			return r.Fallback.Run(ctx, dir, args...)
+		}
+		response = Response{Stderr: fmt.Sprintf("gittest: unexpected command git %s", call)}
+	}
+
+	if response.Stderr != "" || response.Err != nil {
+		err := response.Err
+		if err == nil {
+			err = errors.New("exit status 128")
+		}
+		return []byte(response.Output), &git.CommandError{Args: args, Stderr: response.Stderr, Err: err}
+	}
+	return []byte(response.Output), nil
+}
+
+// Calls returns the commands run so far
+func (r *Runner) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// Ran reports whether a command with the given arguments was run
+func (r *Runner) Ran(args string) bool {
+	for _, call := range r.Calls() {
+		if call.String() == args {
+			return true
+		}
+	}
+	return false
+}
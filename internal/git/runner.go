@@ -0,0 +1,101 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Runner runs git commands. Every command a Repository runs goes through
+// its Runner, so tests can answer them with a fake and --debug can trace
+// them.
+type Runner interface {
+	// Run executes git with args in dir and returns its standard output.
+	// A command that fails returns a *CommandError.
+	Run(ctx context.Context, dir string, args ...string) ([]byte, error)
+}
+
+// DefaultRunner is the Runner of repositories opened without one
+var DefaultRunner Runner = ExecRunner{}
+
+// ExecRunner runs the git binary
+type ExecRunner struct{}
+
+// Run implements Runner. The command is killed when ctx is cancelled.
+func (ExecRunner) Run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return output, &CommandError{Args: args, Stderr: stderr.String(), Err: err}
+	}
+	return output, nil
+}
+
+// CommandError is a git command that failed
+type CommandError struct {
+	Args []string
+	// Stderr is what git printed before it failed
+	Stderr string
+	// Err is the exit status, or the context error of a cancelled command
+	Err error
+}
+
+// Error returns what git said about the failure, or the exit status if
+// it said nothing
+func (e *CommandError) Error() string {
+	if msg := strings.TrimSpace(e.Stderr); msg != "" {
+		return msg
+	}
+	return fmt.Sprintf("git %s: %v", strings.Join(e.Args, " "), e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// Trace wraps runner so every git command is written to w with its
+// directory, duration and outcome
+func Trace(runner Runner, w io.Writer) Runner {
+	return &tracer{runner: runner, w: w}
+}
+
+type tracer struct {
+	runner Runner
+	w      io.Writer
+}
+
+func (t *tracer) Run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	start := time.Now()
+	output, err := t.runner.Run(ctx, dir, args...)
+
+	outcome := "ok"
+	if err != nil {
+		message := err.Error()
+		var cmdErr *CommandError
+		if errors.As(err, &cmdErr) && strings.TrimSpace(cmdErr.Stderr) == "" {
+			message = cmdErr.Err.Error()
+		}
+		message, _, _ = strings.Cut(message, "\n")
+		outcome = "failed: " + message
+	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'") {
+			quoted[i] = fmt.Sprintf("%q", arg)
+		}
+	}
+	fmt.Fprintf(t.w, "[git] %s (in %s, %s) %s\n", strings.Join(quoted, " "), dir, time.Since(start).Round(time.Millisecond), outcome)
+	return output, err
+}
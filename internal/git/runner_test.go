@@ -0,0 +1,132 @@
+package git_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/internal/git/gittest"
+)
+
+// fakeRepo opens a repository at /repo that only exists in the fake
+func fakeRepo(t *testing.T, responses map[string]gittest.Response) (*git.Repository, *gittest.Runner) {
+	t.Helper()
+	runner := &gittest.Runner{Responses: map[string]gittest.Response{
+		"rev-parse --show-toplevel": {Output: "/repo\n"},
+	}}
+	for args, response := range responses {
+		runner.Responses[args] = response
+	}
+	repo, err := git.Open(context.Background(), "/repo", runner)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return repo, runner
+}
+
+func TestOpenFake(t *testing.T) {
+	repo, runner := fakeRepo(t, nil)
+	if repo.Root != "/repo" || repo.RepoName != "repo" {
+		t.Errorf("Open() = %+v, want root /repo", repo)
+	}
+	if calls := runner.Calls(); len(calls) != 1 || calls[0].Dir != "/repo" {
+		t.Errorf("Open() ran %v", calls)
+	}
+
+	runner = &gittest.Runner{Responses: map[string]gittest.Response{
+		"rev-parse --show-toplevel": {Stderr: "fatal: not a git repository"},
+	}}
+	if _, err := git.Open(context.Background(), "/elsewhere", runner); err == nil || !strings.Contains(err.Error(), "not a git repository") {
+		t.Errorf("Open() outside a repository: err = %v", err)
+	}
+}
+
+func TestOpenDir(t *testing.T) {
+	dir := t.TempDir()
+	if output, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, output)
+	}
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// No chdir needed: the root is found from the given directory
+	repo, err := git.Open(context.Background(), sub, nil)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	want, _ := filepath.EvalSymlinks(dir)
+	if got, _ := filepath.EvalSymlinks(repo.Root); got != want {
+		t.Errorf("Root = %s, want %s", repo.Root, dir)
+	}
+}
+
+func TestCreateWorktreeFailure(t *testing.T) {
+	repo, runner := fakeRepo(t, map[string]gittest.Response{
+		"show-ref --verify --quiet refs/heads/agent/x": {Err: errors.New("exit status 1")},
+		"branch --no-track agent/x main":               {},
+		"worktree add /wt agent/x":                     {Stderr: "fatal: '/wt' already exists\n"},
+	})
+
+	err := repo.CreateWorktree("agent/x", "main", "/wt")
+	if err == nil || err.Error() != "failed to add worktree: fatal: '/wt' already exists" {
+		t.Errorf("CreateWorktree() error = %v", err)
+	}
+	var cmdErr *git.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.Args[0] != "worktree" {
+		t.Errorf("CreateWorktree() error doesn't wrap the failed command: %#v", err)
+	}
+	if !runner.Ran("branch --no-track agent/x main") {
+		t.Error("the branch wasn't created before the worktree")
+	}
+}
+
+func TestCancel(t *testing.T) {
+	repo, _ := fakeRepo(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := repo.WithContext(ctx).CurrentBranch(); !errors.Is(err, context.Canceled) {
+		t.Errorf("CurrentBranch() with a cancelled context: err = %v", err)
+	}
+	// The original keeps its own context
+	if _, err := repo.Worktrees(); errors.Is(err, context.Canceled) {
+		t.Error("WithContext changed the original repository")
+	}
+
+	// The real runner stops git as well
+	if _, err := (git.ExecRunner{}).Run(ctx, t.TempDir(), "version"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExecRunner.Run() with a cancelled context: err = %v", err)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	var out bytes.Buffer
+	runner := &gittest.Runner{Responses: map[string]gittest.Response{
+		"rev-parse --show-toplevel": {Output: "/repo\n"},
+		"branch -D agent/x":         {Stderr: "error: branch 'agent/x' not found\n"},
+	}}
+	repo, err := git.Open(context.Background(), "/repo", git.Trace(runner, &out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = repo.DeleteBranch("agent/x")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("trace = %q, want two lines", out.String())
+	}
+	if !strings.HasPrefix(lines[0], "[git] rev-parse --show-toplevel (in /repo, ") || !strings.HasSuffix(lines[0], " ok") {
+		t.Errorf("trace line = %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "failed: error: branch 'agent/x' not found") {
+		t.Errorf("trace line = %q", lines[1])
+	}
+}
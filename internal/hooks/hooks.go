@@ -0,0 +1,128 @@
+// Package hooks runs the commands configured for the lifecycle events of
+// worktrees, such as backing up a database before a worktree is removed
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/AryaLabsHQ/agentree/internal/launch"
+)
+
+// Event names a point in the lifecycle of a worktree
+type Event string
+
+const (
+	// PreCreate runs before a worktree is added
+	PreCreate Event = "pre_create"
+	// PostCreate runs once a new worktree is set up
+	PostCreate Event = "post_create"
+	// PreRemove runs before a worktree is removed
+	PreRemove Event = "pre_remove"
+	// PostRemove runs after a worktree was removed
+	PostRemove Event = "post_remove"
+	// PostSync runs after a worktree was synced with its base
+	PostSync Event = "post_sync"
+)
+
+// Vetoes reports whether a failing hook stops the operation it runs before
+func (e Event) Vetoes() bool {
+	return strings.HasPrefix(string(e), "pre_")
+}
+
+// Payload describes the worktree a hook runs for. Hooks read it as JSON
+// on stdin.
+type Payload struct {
+	Event  Event  `json:"event"`
+	Branch string `json:"branch"`
+	// Path is the worktree; it doesn't exist yet for pre_create and no
+	// longer for post_remove
+	Path string `json:"path"`
+	// RepoRoot is the main checkout
+	RepoRoot string `json:"repo_root"`
+	// Base is the branch the worktree was created from, or for post_sync
+	// the one it was synced with
+	Base  string `json:"base,omitempty"`
+	Agent string `json:"agent,omitempty"`
+	Task  string `json:"task,omitempty"`
+	Issue int    `json:"issue,omitempty"`
+	Group string `json:"group,omitempty"`
+}
+
+// maxReason is how much of a failing hook's error output explains why
+const maxReason = 512
+
+// Run runs commands with sh in order, each reading payload on stdin. They
+// run in the worktree, or the main checkout while the worktree doesn't
+// exist, and get the AGENTREE_* variables agents get plus AGENTREE_HOOK.
+// Hooks of an event that vetoes stop at the first one that fails; others
+// all run and their failures are joined.
+func Run(ctx context.Context, commands []string, payload Payload, stdout, stderr io.Writer) error {
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	input, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	dir := payload.Path
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		dir = payload.RepoRoot
+	}
+	target := launch.Target{
+		Dir:      payload.Path,
+		RepoRoot: payload.RepoRoot,
+		Branch:   payload.Branch,
+		Base:     payload.Base,
+		Agent:    payload.Agent,
+		Task:     payload.Task,
+	}
+	environ := append(target.Environ(), "AGENTREE_HOOK="+string(payload.Event))
+
+	var errs []error
+	for _, command := range commands {
+		// Keep the end of the error output so a veto can say why
+		reason := &tail{}
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Dir = dir
+		cmd.Env = environ
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Stdout = stdout
+		cmd.Stderr = io.MultiWriter(stderr, reason)
+
+		if err := cmd.Run(); err != nil {
+			err = fmt.Errorf("%s hook %q failed: %w", payload.Event, command, err)
+			if text := strings.TrimSpace(string(reason.buf)); text != "" {
+				err = fmt.Errorf("%w: %s", err, text)
+			}
+			if payload.Event.Vetoes() {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// tail keeps the last maxReason bytes written to it
+type tail struct {
+	buf []byte
+}
+
+func (t *tail) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > maxReason {
+		t.buf = t.buf[len(t.buf)-maxReason:]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,80 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	root := t.TempDir()
+	worktree := t.TempDir()
+	payload := Payload{Event: PostCreate, Branch: "agent/fix", Path: worktree, RepoRoot: root, Base: "main", Task: "Fix it", Issue: 7}
+
+	var stdout bytes.Buffer
+	err := Run(context.Background(), []string{
+		`printf '%s|%s|%s|%s\n' "$AGENTREE_HOOK" "$AGENTREE_BRANCH" "$AGENTREE_WORKTREE" "$PWD"`,
+		`cat`,
+	}, payload, &stdout, nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	lines := strings.SplitN(stdout.String(), "\n", 2)
+	fields := strings.Split(lines[0], "|")
+	if len(fields) != 4 || fields[0] != "post_create" || fields[1] != "agent/fix" || fields[2] != worktree {
+		t.Errorf("environment = %q", lines[0])
+	}
+	if resolved, _ := filepath.EvalSymlinks(worktree); fields[3] != worktree && fields[3] != resolved {
+		t.Errorf("hook ran in %s, want %s", fields[3], worktree)
+	}
+
+	var got Payload
+	if err := json.Unmarshal([]byte(lines[1]), &got); err != nil {
+		t.Fatalf("stdin = %q: %v", lines[1], err)
+	}
+	if got != payload {
+		t.Errorf("stdin = %+v, want %+v", got, payload)
+	}
+}
+
+func TestRunWithoutWorktree(t *testing.T) {
+	root := t.TempDir()
+	payload := Payload{Event: PreCreate, Branch: "agent/new", Path: filepath.Join(root, "missing"), RepoRoot: root}
+
+	var stdout bytes.Buffer
+	if err := Run(context.Background(), []string{"pwd"}, payload, &stdout, nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	resolved, _ := filepath.EvalSymlinks(root)
+	if got := strings.TrimSpace(stdout.String()); got != root && got != resolved {
+		t.Errorf("hook ran in %s, want the main checkout %s", got, root)
+	}
+}
+
+func TestVeto(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	commands := []string{"echo 'branch names need a ticket' >&2; exit 3", "touch " + marker}
+
+	err := Run(context.Background(), commands, Payload{Event: PreCreate, RepoRoot: dir}, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "branch names need a ticket") {
+		t.Errorf("Run() error = %v, want the hook's reason", err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("hooks after a failing pre hook ran")
+	}
+
+	// Post hooks can't veto, so they all run
+	err = Run(context.Background(), commands, Payload{Event: PostRemove, RepoRoot: dir}, nil, nil)
+	if err == nil {
+		t.Error("Run() should report the failing post hook")
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("post hooks after a failing one didn't run: %v", err)
+	}
+}
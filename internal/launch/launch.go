@@ -0,0 +1,117 @@
+// Package launch starts coding agents inside worktrees
+package launch
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Target describes the worktree an agent runs in. Its fields are exported
+// to the agent as AGENTREE_* environment variables, named so they never
+// match the override of a config key: an agent that runs agentree itself
+// must not have its worktree's values taken for settings.
+type Target struct {
+	// Dir is the worktree the agent runs in
+	Dir string
+	// RepoRoot is the main checkout the worktree was created from
+	RepoRoot string
+	Branch   string
+	Base     string
+	Agent    string
+	// Task is what the agent was asked to do, if anything
+	Task string
+}
+
+// Environ returns the current environment plus the variables describing t
+func (t Target) Environ() []string {
+	vars := []struct{ name, value string }{
+		{"AGENTREE_WORKTREE", t.Dir},
+		{"AGENTREE_REPO_ROOT", t.RepoRoot},
+		{"AGENTREE_BRANCH", t.Branch},
+		{"AGENTREE_BASE", t.Base},
+		{"AGENTREE_AGENT_NAME", t.Agent},
+		{"AGENTREE_TASK", t.Task},
+	}
+
+	// Drop values inherited from an agent that started this one
+	names := make(map[string]bool)
+	for _, v := range vars {
+		names[v.name] = true
+	}
+	var environ []string
+	for _, entry := range os.Environ() {
+		if name, _, _ := strings.Cut(entry, "="); !names[name] {
+			environ = append(environ, entry)
+		}
+	}
+
+	for _, v := range vars {
+		if v.value != "" {
+			environ = append(environ, v.name+"="+v.value)
+		}
+	}
+	return environ
+}
+
+// Command builds the command that runs launch in the worktree. launch is a
+// shell command line such as "aider --read CONVENTIONS.md"; args are
+// passed on as separate arguments without being interpreted by the shell.
+func Command(t Target, launch string, args []string) *exec.Cmd {
+	shellArgs := append([]string{"-c", launch + ` "$@"`, "agentree"}, args...)
+	cmd := exec.Command("sh", shellArgs...)
+	cmd.Dir = t.Dir
+	cmd.Env = t.Environ()
+	return cmd
+}
+
+// Exec runs cmd in the foreground with the terminal attached. Where the
+// platform allows it the current process is replaced, so signals and the
+// exit status go straight to the agent.
+func Exec(cmd *exec.Cmd) error {
+	path, err := exec.LookPath(cmd.Path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(cmd.Dir); err != nil {
+		return err
+	}
+	return execProcess(path, cmd.Args, cmd.Env)
+}
+
+// Start runs cmd in the background, detached from the terminal, with its
+// output appended to logPath. It returns the process ID.
+func Start(cmd *exec.Cmd, logPath string) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return 0, err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer logFile.Close()
+
+	cmd.Stdin = nil
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detach(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start agent: %w", err)
+	}
+	pid := cmd.Process.Pid
+	if err := cmd.Process.Release(); err != nil {
+		return pid, err
+	}
+	return pid, nil
+}
+
+// Running reports whether a process with the given ID is alive
+func Running(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	return processRunning(pid)
+}
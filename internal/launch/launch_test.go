@@ -0,0 +1,97 @@
+package launch
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AryaLabsHQ/agentree/internal/config"
+)
+
+func TestCommand(t *testing.T) {
+	t.Setenv("AGENTREE_BASE", "inherited")
+	dir := t.TempDir()
+	target := Target{Dir: dir, RepoRoot: "/src/repo", Branch: "agent/fix", Agent: "claude", Task: "Fix it"}
+
+	cmd := Command(target, `printf '%s|' "$PWD" "$AGENTREE_BRANCH" "$AGENTREE_AGENT_NAME" "$AGENTREE_TASK"`, []string{"two words", "$HOME"})
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, _ := filepath.EvalSymlinks(dir)
+	want := resolved + "|agent/fix|claude|Fix it|two words|$HOME|"
+	if got := string(output); got != want && got != strings.Replace(want, resolved, dir, 1) {
+		t.Errorf("Output = %q, want %q", got, want)
+	}
+
+	// Empty values aren't exported
+	for _, entry := range cmd.Env {
+		if strings.HasPrefix(entry, "AGENTREE_BASE=") {
+			t.Errorf("Unexpected %s", entry)
+		}
+	}
+}
+
+func TestEnvironNames(t *testing.T) {
+	target := Target{Dir: "/wt", RepoRoot: "/repo", Branch: "b", Base: "main", Agent: "claude", Task: "t"}
+	names := map[string]bool{"AGENTREE_HOOK": true}
+	for _, entry := range target.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		names[name] = true
+	}
+
+	// The variables describing a worktree aren't config overrides
+	for _, key := range config.Keys {
+		if name := config.EnvVarName(key); names[name] {
+			t.Errorf("%s is both exported to agents and the override of %s", name, key.Name)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "logs", "agent.log")
+
+	cmd := Command(Target{Dir: dir}, "echo started", []string{"in background"})
+	pid, err := Start(cmd, logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid <= 0 {
+		t.Fatalf("Start() pid = %d", pid)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := os.ReadFile(logPath)
+		if strings.Contains(string(data), "started in background") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Log never received the output, got %q", data)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestRunning(t *testing.T) {
+	if !Running(os.Getpid()) {
+		t.Error("The test process should be running")
+	}
+	if Running(0) {
+		t.Error("PID 0 is never an agent")
+	}
+
+	// A process that has exited and been reaped is gone
+	cmd := exec.Command("sh", "-c", "exit 0")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if Running(cmd.Process.Pid) {
+		t.Errorf("Process %d should have exited", cmd.Process.Pid)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package launch
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// execProcess replaces the current process with path
+func execProcess(path string, args, env []string) error {
+	return syscall.Exec(path, args, env)
+}
+
+// detach puts the process in its own session so it outlives the terminal
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// processRunning probes the process with signal 0
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,114 @@
+// Package lock serializes agentree processes that change the same
+// repository with an advisory file lock
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// TimeoutEnv overrides DefaultTimeout, e.g. AGENTREE_LOCK_TIMEOUT=30s.
+// 0 gives up at once while another process holds the lock.
+const TimeoutEnv = "AGENTREE_LOCK_TIMEOUT"
+
+// DefaultTimeout is how long Acquire waits for another process by default
+const DefaultTimeout = 2 * time.Minute
+
+// ErrTimeout is returned when the lock stays taken for the whole timeout
+var ErrTimeout = errors.New("timed out waiting for the repository lock")
+
+// pollInterval is how often a waiting Acquire tries again
+const pollInterval = 50 * time.Millisecond
+
+// Lock is a held lock; Release gives it up
+type Lock struct {
+	file *os.File
+}
+
+// Timeout returns the wait configured with TimeoutEnv, or DefaultTimeout
+func Timeout() (time.Duration, error) {
+	value := strings.TrimSpace(os.Getenv(TimeoutEnv))
+	if value == "" {
+		return DefaultTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected a duration such as 30s", TimeoutEnv, value)
+	}
+	return timeout, nil
+}
+
+// Acquire takes the exclusive lock on path, creating the file if needed.
+// While another process holds it, Acquire calls waiting once with a
+// description of that process and tries again until timeout has passed
+// or ctx is done, whose error it then returns.
+func Acquire(ctx context.Context, path string, timeout time.Duration, waiting func(holder string)) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for notified := false; ; notified = true {
+		locked, err := tryLock(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("%w after %s; held by %s", ErrTimeout, timeout, Holder(path))
+		}
+		if !notified && waiting != nil {
+			waiting(Holder(path))
+		}
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+
+	// Say who holds the lock so others can tell what they wait for
+	holder := fmt.Sprintf("pid %d", os.Getpid())
+	if len(os.Args) > 1 {
+		holder += ": agentree " + strings.Join(os.Args[1:], " ")
+	}
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(holder+"\n"), 0)
+	}
+	return &Lock{file: file}, nil
+}
+
+// Release gives up the lock. Releasing it again does nothing.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	file := l.file
+	l.file = nil
+	_ = file.Truncate(0)
+	err := unlock(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Holder describes the process holding the lock on path, as far as it
+// is known
+func Holder(path string) string {
+	data, err := os.ReadFile(path)
+	if holder := strings.TrimSpace(string(data)); err == nil && holder != "" {
+		return holder
+	}
+	return "another agentree process"
+}
@@ -0,0 +1,140 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agentree.lock")
+
+	held, err := Acquire(context.Background(), path, 0, nil)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if holder := Holder(path); !strings.HasPrefix(holder, "pid ") {
+		t.Errorf("Holder = %q, want the pid of the test", holder)
+	}
+
+	// A second lock gives up once the timeout has passed
+	start := time.Now()
+	if _, err := Acquire(context.Background(), path, 100*time.Millisecond, nil); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Acquire while held: err = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("gave up after %s, before the timeout", elapsed)
+	}
+
+	// ... or as soon as its context is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err := Acquire(ctx, path, time.Minute, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire with a cancelled context: err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("gave up after %s, long after the context was done", elapsed)
+	}
+
+	if err := held.Release(); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err := held.Release(); err != nil {
+		t.Errorf("second Release: %v", err)
+	}
+
+	again, err := Acquire(context.Background(), path, 0, nil)
+	if err != nil {
+		t.Fatalf("Acquire after Release: %v", err)
+	}
+	again.Release()
+}
+
+func TestAcquireWaits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agentree.lock")
+	held, err := Acquire(context.Background(), path, 0, nil)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	waited := 0
+	done := make(chan error)
+	go func() {
+		l, err := Acquire(context.Background(), path, 5*time.Second, func(holder string) { waited++ })
+		if err == nil {
+			l.Release()
+		}
+		done <- err
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	held.Release()
+	if err := <-done; err != nil {
+		t.Fatalf("waiting Acquire: %v", err)
+	}
+	if waited != 1 {
+		t.Errorf("waiting was called %d times, want 1", waited)
+	}
+}
+
+// TestExclusive checks that holders never overlap under contention
+func TestExclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agentree.lock")
+
+	var mu sync.Mutex
+	inside, maxInside := 0, 0
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, err := Acquire(context.Background(), path, 10*time.Second, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			inside++
+			maxInside = max(maxInside, inside)
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			inside--
+			mu.Unlock()
+			l.Release()
+		}()
+	}
+	wg.Wait()
+
+	if maxInside != 1 {
+		t.Errorf("%d holders at once, want 1", maxInside)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", DefaultTimeout, false},
+		{"30s", 30 * time.Second, false},
+		{"0", 0, false},
+		{"soon", 0, true},
+		{"-1s", 0, true},
+	}
+	for _, tt := range tests {
+		t.Setenv(TimeoutEnv, tt.value)
+		got, err := Timeout()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Timeout() with %q = %v, %v; want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
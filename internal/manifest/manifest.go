@@ -0,0 +1,96 @@
+// Package manifest reads files that describe many worktrees to create at once
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Item describes one worktree. Empty fields fall back to the create flags
+// and configuration.
+type Item struct {
+	Branch string `yaml:"branch" json:"branch"`
+	Base   string `yaml:"base" json:"base"`
+	Task   string `yaml:"task" json:"task"`
+	Agent  string `yaml:"agent" json:"agent"`
+	// Scripts replace the post-create scripts, like -S
+	Scripts []string `yaml:"scripts" json:"scripts"`
+}
+
+// Name returns a short label for the item in progress output
+func (i Item) Name() string {
+	if i.Branch != "" {
+		return i.Branch
+	}
+	title, _, _ := strings.Cut(i.Task, "\n")
+	return title
+}
+
+// Load reads a manifest. Files ending in .jsonl or .ndjson hold one JSON
+// object per line; anything else is YAML with a list of items at the top,
+// which also covers JSON arrays.
+func Load(path string) ([]Item, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		items, err = parseJSONLines(data)
+	default:
+		items, err = parseYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%s: no worktrees listed", path)
+	}
+	for n, item := range items {
+		if item.Branch == "" && strings.TrimSpace(item.Task) == "" {
+			return nil, fmt.Errorf("%s: item %d needs a branch or a task", path, n+1)
+		}
+	}
+	return items, nil
+}
+
+func parseYAML(data []byte) ([]Item, error) {
+	var items []Item
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&items); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return items, nil
+}
+
+func parseJSONLines(data []byte) ([]Item, error) {
+	var items []Item
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		var item Item
+		if err := decoder.Decode(&item); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		items = append(items, item)
+	}
+	return items, scanner.Err()
+}
@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeManifest(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	want := []Item{
+		{Branch: "fix-login", Base: "main", Task: "Fix the login redirect", Agent: "claude"},
+		{Task: "Add caching", Scripts: []string{"make deps"}},
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"yaml", "tasks.yaml", `
+- branch: fix-login
+  base: main
+  task: Fix the login redirect
+  agent: claude
+- task: Add caching
+  scripts: ["make deps"]
+`},
+		{"json lines", "tasks.jsonl", `{"branch": "fix-login", "base": "main", "task": "Fix the login redirect", "agent": "claude"}
+
+{"task": "Add caching", "scripts": ["make deps"]}
+`},
+		{"json array", "tasks.json", `[
+  {"branch": "fix-login", "base": "main", "task": "Fix the login redirect", "agent": "claude"},
+  {"task": "Add caching", "scripts": ["make deps"]}
+]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := Load(writeManifest(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(items, want) {
+				t.Errorf("Load() = %+v, want %+v", items, want)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := map[string]string{
+		"empty.yaml":   "",
+		"unknown.yaml": "- branch: x\n  colour: red\n",
+		"nothing.yaml": "- agent: claude\n",
+		"broken.jsonl": "{\"branch\": \"a\"}\n{not json}\n",
+	}
+	for file, content := range tests {
+		if _, err := Load(writeManifest(t, file, content)); err == nil {
+			t.Errorf("Load(%s) should fail", file)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	if name := (Item{Branch: "b", Task: "t"}).Name(); name != "b" {
+		t.Errorf("Name() = %q, want the branch", name)
+	}
+	if name := (Item{Task: "Title\n\nDetails"}).Name(); name != "Title" {
+		t.Errorf("Name() = %q, want the task title", name)
+	}
+}
@@ -0,0 +1,275 @@
+// Package mcp serves agentree to coding agents over the Model Context
+// Protocol, so an agent can spin off sub-tasks into worktrees of its own.
+//
+// Messages are JSON-RPC 2.0, one per line on stdin and stdout. The tools
+// call the same code as the CLI:
+//
+//	create_worktree   create and set up a worktree, up to mcp.max_worktrees
+//	list_worktrees    list the worktrees of the repository
+//	worktree_status   the state of a worktree's branch
+//	run_setup         run a worktree's setup scripts again
+//	remove_worktree   remove a worktree whose branch has mcp.branch_prefix
+//
+// Stdout carries nothing but protocol messages; script output and progress
+// go to Server.Log.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ProtocolVersion is the newest protocol revision the server speaks
+const ProtocolVersion = "2025-06-18"
+
+// supportedVersions lists the revisions a client may ask for
+var supportedVersions = []string{"2024-11-05", "2025-03-26", ProtocolVersion}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Server answers MCP requests for one repository
+type Server struct {
+	// Dir is a directory in the repository
+	Dir string
+	// Version is reported to clients as the server's version
+	Version string
+	// Log receives script output and progress meant for people; nil
+	// discards it
+	Log io.Writer
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// Serve reads requests from in and writes responses to out until in ends
+// or ctx is cancelled. Requests are handled one at a time.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.out = out
+	if s.Log == nil {
+		s.Log = io.Discard
+	}
+
+	reader := bufio.NewReader(in)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			s.handle(ctx, line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// handle answers one message. Notifications and responses to requests the
+// server never sends get no answer.
+func (s *Server) handle(ctx context.Context, line []byte) {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		s.reply(json.RawMessage("null"), nil, &rpcError{Code: codeParseError, Message: err.Error()})
+		return
+	}
+	if len(req.ID) == 0 {
+		return
+	}
+	if req.Method == "" || req.JSONRPC != "2.0" {
+		s.reply(req.ID, nil, &rpcError{Code: codeInvalidRequest, Message: "not a JSON-RPC 2.0 request"})
+		return
+	}
+
+	result, rpcErr := s.dispatch(ctx, req)
+	s.reply(req.ID, result, rpcErr)
+}
+
+func (s *Server) dispatch(ctx context.Context, req request) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		// Clients asking for a revision we don't know get the newest one
+		// and decide themselves whether to go on
+		version := ProtocolVersion
+		for _, supported := range supportedVersions {
+			if params.ProtocolVersion == supported {
+				version = supported
+			}
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": "agentree", "version": s.Version},
+			"instructions": "Create a worktree for each sub-task you hand off, so it runs on its own branch " +
+				"and directory. Remove worktrees you created once their work is merged or abandoned.",
+		}, nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return map[string]any{"tools": tools}, nil
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+			Meta      struct {
+				ProgressToken json.RawMessage `json:"progressToken"`
+			} `json:"_meta"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		t := findTool(params.Name)
+		if t == nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool %q", params.Name)}
+		}
+		return s.call(ctx, t, params.Arguments, params.Meta.ProgressToken), nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+}
+
+// call runs a tool. Failures are tool results flagged isError, so the
+// agent sees why and can try something else.
+func (s *Server) call(ctx context.Context, t *tool, arguments json.RawMessage, progressToken json.RawMessage) any {
+	c := &callContext{server: s}
+	if len(progressToken) > 0 {
+		c.progressToken = progressToken
+	}
+
+	result, err := t.run(ctx, s, decoder(arguments), c)
+	if err != nil {
+		return toolResult(true, err.Error())
+	}
+	encoded, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResult(true, err.Error())
+	}
+	texts := []string{string(encoded)}
+	if len(c.warnings) > 0 {
+		texts = append(texts, "Warnings:\n- "+strings.Join(c.warnings, "\n- "))
+	}
+	return toolResult(false, texts...)
+}
+
+func toolResult(isError bool, texts ...string) map[string]any {
+	content := make([]map[string]string, len(texts))
+	for i, text := range texts {
+		content[i] = map[string]string{"type": "text", "text": text}
+	}
+	return map[string]any{"content": content, "isError": isError}
+}
+
+// decoder returns a function that decodes tool arguments strictly, so a
+// misspelt argument is an error rather than silently ignored
+func decoder(arguments json.RawMessage) func(any) error {
+	return func(v any) error {
+		if len(arguments) == 0 || string(arguments) == "null" {
+			return nil
+		}
+		d := json.NewDecoder(bytes.NewReader(arguments))
+		d.DisallowUnknownFields()
+		if err := d.Decode(v); err != nil {
+			return fmt.Errorf("invalid arguments: %w", err)
+		}
+		return nil
+	}
+}
+
+func (s *Server) reply(id json.RawMessage, result any, err *rpcError) {
+	s.write(response{JSONRPC: "2.0", ID: id, Result: result, Error: err})
+}
+
+// write sends one message as a line; setup scripts report progress from
+// several goroutines
+func (s *Server) write(message any) {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		encoded, _ = json.Marshal(response{JSONRPC: "2.0", ID: json.RawMessage("null"),
+			Error: &rpcError{Code: codeInvalidRequest, Message: err.Error()}})
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = s.out.Write(append(encoded, '\n'))
+}
+
+// callContext collects what a tool reports while it runs
+type callContext struct {
+	server        *Server
+	progressToken json.RawMessage
+	progress      int
+	warnings      []string
+	mu            sync.Mutex
+}
+
+// report logs a message and, when the client asked for progress, sends it
+// as a progress notification
+func (c *callContext) report(message string) {
+	fmt.Fprintln(c.server.Log, message)
+	if c.progressToken == nil {
+		return
+	}
+	c.mu.Lock()
+	c.progress++
+	progress := c.progress
+	c.mu.Unlock()
+	c.server.write(notification{JSONRPC: "2.0", Method: "notifications/progress", Params: map[string]any{
+		"progressToken": c.progressToken,
+		"progress":      progress,
+		"message":       message,
+	}})
+}
+
+func (c *callContext) warn(message string) {
+	c.mu.Lock()
+	c.warnings = append(c.warnings, message)
+	c.mu.Unlock()
+	c.report("Warning: " + message)
+}
+
+// errMissingBranch is returned by tools called without a branch
+var errMissingBranch = errors.New("branch is required")
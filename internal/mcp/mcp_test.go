@@ -0,0 +1,262 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// client talks to a server over pipes, like an agent over stdio
+type client struct {
+	t      *testing.T
+	in     io.WriteCloser
+	out    *bufio.Scanner
+	nextID int
+}
+
+type message struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+	Params json.RawMessage `json:"params"`
+}
+
+type callResult struct {
+	Content []struct {
+		Text string `json:"text"`
+	} `json:"content"`
+	IsError bool `json:"isError"`
+}
+
+// setupClient serves a repository with one commit on main
+func setupClient(t *testing.T) (*client, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("AGENTREE_CONFIG", "")
+
+	dir := filepath.Join(t.TempDir(), "repo")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.name", "Test User"},
+		{"config", "user.email", "test@example.com"},
+		{"commit", "-q", "--allow-empty", "-m", "Initial commit"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- (&Server{Dir: dir, Version: "test"}).Serve(context.Background(), inReader, outWriter)
+		outWriter.Close()
+	}()
+	t.Cleanup(func() {
+		inWriter.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	})
+
+	c := &client{t: t, in: inWriter, out: bufio.NewScanner(outReader)}
+	c.out.Buffer(nil, 1<<20)
+	return c, dir
+}
+
+// request sends a request and returns its response, collecting the
+// notifications sent before it
+func (c *client) request(method string, params any) (message, []message) {
+	c.t.Helper()
+	c.nextID++
+	encoded, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := c.in.Write(append(encoded, '\n')); err != nil {
+		c.t.Fatal(err)
+	}
+
+	var notifications []message
+	for c.out.Scan() {
+		var msg message
+		if err := json.Unmarshal(c.out.Bytes(), &msg); err != nil {
+			c.t.Fatalf("stdout carries something else than a message: %s", c.out.Text())
+		}
+		if msg.ID == nil {
+			notifications = append(notifications, msg)
+			continue
+		}
+		if *msg.ID != c.nextID {
+			c.t.Fatalf("response id = %d, want %d", *msg.ID, c.nextID)
+		}
+		return msg, notifications
+	}
+	c.t.Fatalf("no response to %s", method)
+	return message{}, nil
+}
+
+// call calls a tool and returns its text and whether it failed
+func (c *client) call(name string, arguments map[string]any) (string, bool) {
+	c.t.Helper()
+	msg, _ := c.request("tools/call", map[string]any{"name": name, "arguments": arguments})
+	if msg.Error != nil {
+		c.t.Fatalf("%s: %s", name, msg.Error.Message)
+	}
+	var result callResult
+	if err := json.Unmarshal(msg.Result, &result); err != nil {
+		c.t.Fatal(err)
+	}
+	var texts []string
+	for _, content := range result.Content {
+		texts = append(texts, content.Text)
+	}
+	return strings.Join(texts, "\n"), result.IsError
+}
+
+func TestInitialize(t *testing.T) {
+	c, _ := setupClient(t)
+
+	msg, _ := c.request("initialize", map[string]any{"protocolVersion": "2025-03-26", "capabilities": map[string]any{}})
+	var result struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		ServerInfo      struct {
+			Name string `json:"name"`
+		} `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(msg.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.ProtocolVersion != "2025-03-26" || result.ServerInfo.Name != "agentree" {
+		t.Errorf("initialize = %s", msg.Result)
+	}
+
+	msg, _ = c.request("tools/list", nil)
+	var list struct {
+		Tools []struct {
+			Name string `json:"name"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(msg.Result, &list); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+	}
+	if got := strings.Join(names, " "); got != "create_worktree list_worktrees worktree_status run_setup remove_worktree" {
+		t.Errorf("tools = %s", got)
+	}
+
+	if msg, _ := c.request("resources/list", nil); msg.Error == nil || msg.Error.Code != codeMethodNotFound {
+		t.Errorf("resources/list error = %+v, want method not found", msg.Error)
+	}
+	if msg, _ := c.request("tools/call", map[string]any{"name": "rm_rf"}); msg.Error == nil || msg.Error.Code != codeInvalidParams {
+		t.Errorf("unknown tool error = %+v, want invalid params", msg.Error)
+	}
+}
+
+func TestTools(t *testing.T) {
+	c, dir := setupClient(t)
+
+	text, failed := c.call("create_worktree", map[string]any{"branch": "subtask", "skip_setup": true})
+	if failed || !strings.Contains(text, `"branch": "agent/subtask"`) {
+		t.Fatalf("create_worktree = %s", text)
+	}
+
+	text, failed = c.call("list_worktrees", nil)
+	if failed || !strings.Contains(text, "agent/subtask") {
+		t.Errorf("list_worktrees = %s", text)
+	}
+
+	text, failed = c.call("worktree_status", map[string]any{"branch": "agent/subtask"})
+	if failed || !strings.Contains(text, `"dirty": false`) {
+		t.Errorf("worktree_status = %s", text)
+	}
+
+	text, failed = c.call("run_setup", map[string]any{"branch": "agent/subtask"})
+	if failed || !strings.Contains(text, `"commands"`) {
+		t.Errorf("run_setup = %s", text)
+	}
+
+	if text, failed = c.call("worktree_status", map[string]any{"brnach": "agent/subtask"}); !failed {
+		t.Errorf("worktree_status with a misspelt argument = %s, want an error", text)
+	}
+
+	text, failed = c.call("remove_worktree", map[string]any{"branch": "agent/subtask", "delete_branch": true})
+	if failed || !strings.Contains(text, `"removed": "agent/subtask"`) {
+		t.Errorf("remove_worktree = %s", text)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "repo-worktrees", "agent-subtask")); !os.IsNotExist(err) {
+		t.Errorf("worktree still exists: %v", err)
+	}
+}
+
+func TestGuardrails(t *testing.T) {
+	c, dir := setupClient(t)
+	t.Setenv("AGENTREE_MCP_MAX_WORKTREES", "1")
+
+	// A worktree made by hand on a branch without the agent prefix
+	cmd := exec.Command("git", "worktree", "add", "-q", "-b", "feature", filepath.Join(filepath.Dir(dir), "feature"))
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git worktree add: %v\n%s", err, output)
+	}
+
+	text, failed := c.call("create_worktree", map[string]any{"branch": "one-too-many", "skip_setup": true})
+	if !failed || !strings.Contains(text, "mcp.max_worktrees") {
+		t.Errorf("create_worktree over the cap = %s, want an error", text)
+	}
+
+	text, failed = c.call("remove_worktree", map[string]any{"branch": "feature", "force": true})
+	if !failed || !strings.Contains(text, "not an agent branch") {
+		t.Errorf("remove_worktree of feature = %s, want an error", text)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "feature")); err != nil {
+		t.Errorf("feature worktree was removed: %v", err)
+	}
+
+	text, failed = c.call("remove_worktree", map[string]any{"branch": "main"})
+	if !failed {
+		t.Errorf("remove_worktree of the main checkout = %s, want an error", text)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	c, _ := setupClient(t)
+
+	_, notifications := c.request("tools/call", map[string]any{
+		"name":      "create_worktree",
+		"arguments": map[string]any{"task": "Fix the login redirect", "skip_setup": true},
+		"_meta":     map[string]any{"progressToken": "create-1"},
+	})
+	if len(notifications) == 0 {
+		t.Fatal("no progress notifications")
+	}
+	for _, n := range notifications {
+		var params struct {
+			ProgressToken string `json:"progressToken"`
+			Message       string `json:"message"`
+		}
+		if err := json.Unmarshal(n.Params, &params); err != nil {
+			t.Fatal(err)
+		}
+		if n.Method != "notifications/progress" || params.ProgressToken != "create-1" || params.Message == "" {
+			t.Errorf("notification = %s %s", n.Method, n.Params)
+		}
+	}
+}
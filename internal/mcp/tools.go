@@ -0,0 +1,287 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AryaLabsHQ/agentree/internal/config"
+	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/pkg/agentree"
+)
+
+// tool is an MCP tool and the function that runs it
+type tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+	Annotations map[string]any `json:"annotations,omitempty"`
+
+	run func(ctx context.Context, s *Server, decode func(any) error, c *callContext) (any, error)
+}
+
+// tools lists every tool in the order clients see them
+var tools = []*tool{
+	{
+		Name: "create_worktree",
+		Description: "Create a git worktree on a new branch for a sub-task and set it up like the main checkout: " +
+			"env files, artifacts and setup scripts. Returns the worktree's path; work on the sub-task there.",
+		InputSchema: object(map[string]any{
+			"branch":     str("Branch name; names without a slash get the configured prefix, e.g. fix-login becomes agent/fix-login"),
+			"task":       str("What the sub-task is; names the branch when branch is empty"),
+			"base":       str("Branch, tag or commit to start from; defaults to the current branch"),
+			"agent":      str("Agent profile to prepare the worktree for, e.g. claude"),
+			"issue":      integer("Tracker issue the task comes from"),
+			"skip_setup": boolean("Don't run setup scripts"),
+		}),
+		run: createWorktree,
+	},
+	{
+		Name:        "list_worktrees",
+		Description: "List the worktrees of the repository with their branch, path, base, task and background agent.",
+		InputSchema: object(map[string]any{}),
+		Annotations: map[string]any{"readOnlyHint": true},
+		run:         listWorktrees,
+	},
+	{
+		Name: "worktree_status",
+		Description: "Show the state of a worktree: uncommitted changes, commits ahead of and behind its base, " +
+			"and the size of its diff.",
+		InputSchema: object(map[string]any{
+			"branch": str("Branch of the worktree, or its path"),
+		}, "branch"),
+		Annotations: map[string]any{"readOnlyHint": true},
+		run:         worktreeStatus,
+	},
+	{
+		Name:        "run_setup",
+		Description: "Run the setup scripts of a worktree again, e.g. after dependencies changed.",
+		InputSchema: object(map[string]any{
+			"branch": str("Branch of the worktree, or its path"),
+		}, "branch"),
+		run: runSetup,
+	},
+	{
+		Name: "remove_worktree",
+		Description: "Remove a worktree created for an agent. Only branches with the agent prefix may be removed, " +
+			"and worktrees with uncommitted changes need force.",
+		InputSchema: object(map[string]any{
+			"branch":        str("Branch of the worktree, or its path"),
+			"force":         boolean("Remove the worktree even with uncommitted changes"),
+			"delete_branch": boolean("Delete the branch too"),
+		}, "branch"),
+		Annotations: map[string]any{"destructiveHint": true},
+		run:         removeWorktree,
+	},
+}
+
+func findTool(name string) *tool {
+	for _, t := range tools {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func object(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func str(description string) map[string]any {
+	return map[string]any{"type": "string", "description": description}
+}
+
+func integer(description string) map[string]any {
+	return map[string]any{"type": "integer", "description": description}
+}
+
+func boolean(description string) map[string]any {
+	return map[string]any{"type": "boolean", "description": description}
+}
+
+// events turns agentree events into progress and warnings
+func (c *callContext) events(event agentree.Event) {
+	if event.Type == agentree.EventWarning {
+		c.warn(event.Message)
+		return
+	}
+	c.report(event.Message)
+}
+
+func createWorktree(ctx context.Context, s *Server, decode func(any) error, c *callContext) (any, error) {
+	var args struct {
+		Branch    string `json:"branch"`
+		Task      string `json:"task"`
+		Base      string `json:"base"`
+		Agent     string `json:"agent"`
+		Issue     int    `json:"issue"`
+		SkipSetup bool   `json:"skip_setup"`
+	}
+	if err := decode(&args); err != nil {
+		return nil, err
+	}
+	if args.Branch == "" && args.Task == "" {
+		return nil, fmt.Errorf("branch or task is required")
+	}
+
+	limits, err := s.limits(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wt, err := agentree.Create(ctx, agentree.Options{
+		Dir:          s.Dir,
+		Branch:       args.Branch,
+		Task:         args.Task,
+		Base:         args.Base,
+		Agent:        args.Agent,
+		Issue:        args.Issue,
+		MaxWorktrees: limits.MaxWorktrees,
+		SkipSetup:    args.SkipSetup,
+		Stdout:       s.Log,
+		Stderr:       s.Log,
+		OnEvent:      c.events,
+	})
+	if errors.Is(err, agentree.ErrWorktreeLimit) {
+		return nil, fmt.Errorf("%w (mcp.max_worktrees); remove one first", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return wt, nil
+}
+
+func listWorktrees(ctx context.Context, s *Server, decode func(any) error, c *callContext) (any, error) {
+	if err := decode(&struct{}{}); err != nil {
+		return nil, err
+	}
+	worktrees, err := agentree.List(ctx, agentree.ListOptions{Dir: s.Dir, OnEvent: c.events})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"worktrees": worktrees}, nil
+}
+
+func worktreeStatus(ctx context.Context, s *Server, decode func(any) error, c *callContext) (any, error) {
+	branch, err := decodeBranch(decode)
+	if err != nil {
+		return nil, err
+	}
+	return agentree.Status(ctx, branch, agentree.StatusOptions{Dir: s.Dir})
+}
+
+func runSetup(ctx context.Context, s *Server, decode func(any) error, c *callContext) (any, error) {
+	branch, err := decodeBranch(decode)
+	if err != nil {
+		return nil, err
+	}
+	commands, err := agentree.Setup(ctx, branch, agentree.SetupOptions{
+		Dir:     s.Dir,
+		Stdout:  s.Log,
+		Stderr:  s.Log,
+		OnEvent: c.events,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"branch": branch, "commands": commands}, nil
+}
+
+func removeWorktree(ctx context.Context, s *Server, decode func(any) error, c *callContext) (any, error) {
+	var args struct {
+		Branch       string `json:"branch"`
+		Force        bool   `json:"force"`
+		DeleteBranch bool   `json:"delete_branch"`
+	}
+	if err := decode(&args); err != nil {
+		return nil, err
+	}
+	if args.Branch == "" {
+		return nil, errMissingBranch
+	}
+
+	// A path names the worktree too, so check the branch checked out there
+	limits, err := s.limits(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status, err := agentree.Status(ctx, args.Branch, agentree.StatusOptions{Dir: s.Dir})
+	if err != nil {
+		return nil, err
+	}
+	if limits.BranchPrefix == "" || !strings.HasPrefix(status.Branch, limits.BranchPrefix) {
+		return nil, fmt.Errorf("%s is not an agent branch; only branches starting with %q may be removed (mcp.branch_prefix)",
+			status.Branch, limits.BranchPrefix)
+	}
+	if dir, err := s.dir(); err == nil && within(dir, status.Path) {
+		return nil, fmt.Errorf("%s is the worktree agentree mcp runs in", status.Path)
+	}
+
+	err = agentree.Remove(ctx, status.Path, agentree.RemoveOptions{
+		Dir:          s.Dir,
+		Force:        args.Force,
+		DeleteBranch: args.DeleteBranch,
+		Stdout:       s.Log,
+		Stderr:       s.Log,
+		OnEvent:      c.events,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"removed": status.Branch, "path": status.Path}, nil
+}
+
+func decodeBranch(decode func(any) error) (string, error) {
+	var args struct {
+		Branch string `json:"branch"`
+	}
+	if err := decode(&args); err != nil {
+		return "", err
+	}
+	if args.Branch == "" {
+		return "", errMissingBranch
+	}
+	return args.Branch, nil
+}
+
+// limits loads the guardrails afresh for every call, so changing them
+// doesn't need a restart
+func (s *Server) limits(ctx context.Context) (*config.MCPConfig, error) {
+	repo, err := git.Open(ctx, s.Dir, nil)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := config.LoadLayers(repo.Root, "")
+	if err != nil {
+		return nil, err
+	}
+	return &config.MergeLayers(layers...).MCPConfig, nil
+}
+
+// dir is the absolute directory the server works in
+func (s *Server) dir() (string, error) {
+	if s.Dir == "" {
+		return os.Getwd()
+	}
+	return filepath.Abs(s.Dir)
+}
+
+// within reports whether path is root or inside it
+func within(path, root string) bool {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,172 @@
+// Package metadata remembers what agentree knows about the worktrees it
+// created, beyond what git itself records
+package metadata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when no record exists for a branch
+var ErrNotFound = errors.New("no metadata for worktree")
+
+// Worktree is the record kept for one worktree
+type Worktree struct {
+	Branch    string    `json:"branch"`
+	Path      string    `json:"path"`
+	Base      string    `json:"base,omitempty"`
+	Agent     string    `json:"agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Task describes what the worktree was created to do
+	Task string `json:"task,omitempty"`
+	// Issue is the tracker issue the task came from, 0 if none
+	Issue int `json:"issue,omitempty"`
+	// Group links the worktrees of one fanout, which share a task
+	Group string `json:"group,omitempty"`
+
+	// PID is the process of an agent started in the background, 0 if none
+	PID        int       `json:"pid,omitempty"`
+	LaunchedAt time.Time `json:"launched_at,omitempty"`
+}
+
+// Store keeps one JSON file per branch below a directory, so worktrees
+// created in parallel never write to the same file
+type Store struct {
+	dir string
+}
+
+// NewStore returns a store rooted at dir, usually .git/agentree
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the directory the store lives in
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// recordPath maps a branch to its file; slashes become directories just
+// like they do for git refs
+func (s *Store) recordPath(branch string) string {
+	return filepath.Join(s.dir, "worktrees", filepath.FromSlash(branch)+".json")
+}
+
+// LogPath returns where the output of a background agent for branch goes
+func (s *Store) LogPath(branch string) string {
+	return filepath.Join(s.dir, "logs", filepath.FromSlash(branch)+".log")
+}
+
+// Get returns the record for branch, or ErrNotFound
+func (s *Store) Get(branch string) (*Worktree, error) {
+	data, err := os.ReadFile(s.recordPath(branch))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var w Worktree
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("invalid metadata for %s: %w", branch, err)
+	}
+	return &w, nil
+}
+
+// Save writes the record for w.Branch atomically
+func (s *Store) Save(w *Worktree) error {
+	if w.Branch == "" {
+		return fmt.Errorf("metadata needs a branch")
+	}
+	path := s.recordPath(w.Branch)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Delete removes the record and log of branch. Missing files are fine.
+func (s *Store) Delete(branch string) error {
+	for _, path := range []string{s.recordPath(branch), s.LogPath(branch)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns every record sorted by branch
+func (s *Store) List() ([]*Worktree, error) {
+	root := filepath.Join(s.dir, "worktrees")
+
+	var records []*Worktree
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == root {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".json") || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		w, err := s.Get(strings.TrimSuffix(filepath.ToSlash(rel), ".json"))
+		if err != nil {
+			return err
+		}
+		records = append(records, w)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].Branch < records[j].Branch })
+	return records, nil
+}
+
+// Group returns the records of a fanout group sorted by branch
+func (s *Store) Group(id string) ([]*Worktree, error) {
+	records, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var group []*Worktree
+	for _, w := range records {
+		if w.Group == id {
+			group = append(group, w)
+		}
+	}
+	return group, nil
+}
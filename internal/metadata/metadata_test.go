@@ -0,0 +1,103 @@
+package metadata
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	if _, err := store.Get("agent/missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() error = %v, want ErrNotFound", err)
+	}
+	if records, err := store.List(); err != nil || len(records) != 0 {
+		t.Fatalf("List() on an empty store = %v, %v", records, err)
+	}
+
+	created := time.Date(2025, 3, 7, 12, 0, 0, 0, time.UTC)
+	for _, w := range []*Worktree{
+		{Branch: "agent/fix", Path: "/wt/agent-fix", Base: "main", Agent: "claude", CreatedAt: created},
+		{Branch: "agent/claude/docs", Path: "/wt/docs", CreatedAt: created},
+		{Branch: "feature", Path: "/wt/feature", CreatedAt: created},
+	} {
+		if err := store.Save(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := store.Get("agent/fix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Agent != "claude" || !w.CreatedAt.Equal(created) {
+		t.Errorf("Get() = %+v", w)
+	}
+
+	// Updates replace the record
+	w.PID = 4242
+	if err := store.Save(w); err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := store.Get("agent/fix"); w.PID != 4242 {
+		t.Errorf("PID = %d after update", w.PID)
+	}
+
+	records, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var branches []string
+	for _, r := range records {
+		branches = append(branches, r.Branch)
+	}
+	if len(branches) != 3 || branches[0] != "agent/claude/docs" || branches[1] != "agent/fix" || branches[2] != "feature" {
+		t.Errorf("List() branches = %v", branches)
+	}
+
+	if err := os.MkdirAll(store.Dir()+"/logs/agent", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.LogPath("agent/fix"), []byte("output"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("agent/fix"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("agent/fix"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Record still present after Delete: %v", err)
+	}
+	if _, err := os.Stat(store.LogPath("agent/fix")); !os.IsNotExist(err) {
+		t.Error("Log still present after Delete")
+	}
+	if err := store.Delete("agent/fix"); err != nil {
+		t.Errorf("Deleting twice should be fine: %v", err)
+	}
+}
+
+func TestGroup(t *testing.T) {
+	store := NewStore(t.TempDir())
+	for _, w := range []*Worktree{
+		{Branch: "agent/codex/fix-2", Group: "fix-1a2b"},
+		{Branch: "agent/claude/fix-1", Group: "fix-1a2b"},
+		{Branch: "agent/other", Group: "other-3c4d"},
+		{Branch: "feature"},
+	} {
+		if err := store.Save(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	group, err := store.Group("fix-1a2b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(group) != 2 || group[0].Branch != "agent/claude/fix-1" || group[1].Branch != "agent/codex/fix-2" {
+		t.Errorf("Group() = %+v", group)
+	}
+	if group, _ := store.Group("missing"); len(group) != 0 {
+		t.Errorf("Group() of an unknown ID = %+v", group)
+	}
+}
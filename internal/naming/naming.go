@@ -0,0 +1,251 @@
+// Package naming turns a short description of the work into a branch name
+package naming
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultTemplate reproduces the historic agent/<name> branches
+const DefaultTemplate = "agent/{slug}"
+
+// DefaultPathTemplate reproduces the historic <branch-with-dashes> directories
+const DefaultPathTemplate = "{branch}"
+
+// maxAttempts bounds the search for a free branch name
+const maxAttempts = 100
+
+// Vars are the values a branch template can refer to
+type Vars struct {
+	// Agent names the agent the branch is for, e.g. "claude"
+	Agent string
+	// User is the current user name
+	User string
+	// Ticket is an issue or ticket reference such as "PROJ-123"
+	Ticket string
+	// Slug is a short description of the work
+	Slug string
+	// Date is rendered as YYYY-MM-DD
+	Date time.Time
+	// Counter numbers otherwise identical names, starting at 1
+	Counter int
+
+	// Repo and Branch are only available to worktree path templates
+	Repo   string
+	Branch string
+}
+
+// branchVariables and pathVariables list what each kind of template may use
+var (
+	branchVariables = []string{"agent", "user", "ticket", "slug", "date", "counter"}
+	pathVariables   = []string{"agent", "user", "ticket", "slug", "date", "repo", "branch"}
+)
+
+// variablePattern matches {name} placeholders
+var variablePattern = regexp.MustCompile(`\{([a-z]*)\}`)
+
+// invalidChars matches runs of characters that don't belong in a name component
+var invalidChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Render expands the variables in template and cleans up the result:
+// components left empty by a missing variable are dropped, and separators
+// doubled by an empty value are collapsed.
+func Render(template string, vars Vars) (string, error) {
+	if err := Validate(template); err != nil {
+		return "", err
+	}
+
+	rendered := expand(template, vars)
+
+	var parts []string
+	for _, part := range strings.Split(rendered, "/") {
+		part = collapseSeparators(part)
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("branch template %q produced an empty name", template)
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// RenderPath expands a worktree path template such as
+// "~/worktrees/{repo}/{agent}/{slug}". Only variable values are sanitized;
+// literal parts like "~" or ".worktrees" are kept. Components left empty by
+// a missing variable are dropped.
+func RenderPath(template string, vars Vars) (string, error) {
+	if err := ValidatePath(template); err != nil {
+		return "", err
+	}
+
+	var parts []string
+	for i, part := range strings.Split(template, "/") {
+		if !variablePattern.MatchString(part) {
+			if part != "" || i == 0 {
+				parts = append(parts, part) // Keep a leading "/" of absolute paths
+			}
+			continue
+		}
+		rendered := expand(part, vars)
+		for strings.Contains(rendered, "--") {
+			rendered = strings.ReplaceAll(rendered, "--", "-")
+		}
+		if rendered = strings.Trim(rendered, "-"); rendered != "" {
+			parts = append(parts, rendered)
+		}
+	}
+
+	path := strings.Join(parts, "/")
+	if strings.Trim(path, "/") == "" {
+		return "", fmt.Errorf("worktree path template %q produced an empty path", template)
+	}
+	return path, nil
+}
+
+// Validate checks that a branch template only refers to known variables
+func Validate(template string) error {
+	return checkVariables("branch template", template, branchVariables)
+}
+
+// ValidatePath checks that a worktree path template only refers to known variables
+func ValidatePath(template string) error {
+	return checkVariables("worktree path template", template, pathVariables)
+}
+
+// checkVariables reports the first placeholder that isn't in allowed
+func checkVariables(kind, template string, allowed []string) error {
+	for _, m := range variablePattern.FindAllStringSubmatch(template, -1) {
+		known := false
+		for _, name := range allowed {
+			known = known || name == m[1]
+		}
+		if !known {
+			return fmt.Errorf("unknown variable {%s} in %s (expected %s)", m[1], kind, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// expand replaces every placeholder with its sanitized value
+func expand(template string, vars Vars) string {
+	values := map[string]string{
+		"agent":   component(vars.Agent),
+		"user":    component(vars.User),
+		"ticket":  component(vars.Ticket),
+		"slug":    component(vars.Slug),
+		"date":    vars.Date.Format("2006-01-02"),
+		"counter": strconv.Itoa(vars.Counter),
+		"repo":    component(vars.Repo),
+		"branch":  component(strings.ReplaceAll(vars.Branch, "/", "-")),
+	}
+
+	return variablePattern.ReplaceAllStringFunc(template, func(match string) string {
+		return values[strings.Trim(match, "{}")]
+	})
+}
+
+// HasCounter reports whether the template numbers names itself
+func HasCounter(template string) bool {
+	return strings.Contains(template, "{counter}")
+}
+
+// Sanitize makes free text usable in a branch name by replacing spaces and
+// characters git rejects with dashes, e.g. "fix login: bug!" becomes
+// "fix-login-bug". Case is preserved and slashes are kept as separators.
+func Sanitize(text string) string {
+	var parts []string
+	for _, part := range strings.Split(text, "/") {
+		if part = component(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// maxSlugLength bounds slugs derived from task descriptions
+const maxSlugLength = 40
+
+// Slugify turns a task description into a short slug for branch names,
+// e.g. "Fix the login redirect (#42)" becomes "fix-the-login-redirect-42".
+// Only the first line is used, and long slugs are cut at a word boundary.
+func Slugify(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	words := strings.FieldsFunc(strings.ToLower(line), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	slug := strings.Join(words, "-")
+	if len(slug) <= maxSlugLength {
+		return slug
+	}
+	slug = slug[:maxSlugLength+1]
+	if i := strings.LastIndex(slug, "-"); i > 0 {
+		return slug[:i]
+	}
+	return strings.ToValidUTF8(slug[:maxSlugLength], "")
+}
+
+// component sanitizes a variable value so it stays within one path component
+func component(value string) string {
+	return collapseSeparators(invalidChars.ReplaceAllString(value, "-"))
+}
+
+// collapseSeparators squeezes repeated dashes and dots and trims them from both ends
+func collapseSeparators(value string) string {
+	for strings.Contains(value, "--") {
+		value = strings.ReplaceAll(value, "--", "-")
+	}
+	for strings.Contains(value, "..") {
+		value = strings.ReplaceAll(value, "..", ".")
+	}
+	return strings.Trim(value, "-.")
+}
+
+// Unique returns the first candidate that isn't taken.
+// candidate is called with 1, 2, ... until a free name is found.
+func Unique(candidate func(n int) (string, error), taken func(string) bool) (string, error) {
+	for n := 1; n <= maxAttempts; n++ {
+		name, err := candidate(n)
+		if err != nil {
+			return "", err
+		}
+		if !taken(name) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no free branch name after %d attempts", maxAttempts)
+}
+
+// Suffixed returns name for n == 1 and name-n otherwise
+func Suffixed(name string, n int) string {
+	if n <= 1 {
+		return name
+	}
+	return fmt.Sprintf("%s-%d", name, n)
+}
+
+// CurrentUser returns the login name of the current user, or "" if unknown
+func CurrentUser() string {
+	name := ""
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if name == "" {
+		name = os.Getenv("USER")
+	}
+	if name == "" {
+		name = os.Getenv("USERNAME")
+	}
+
+	// Windows reports DOMAIN\user
+	if idx := strings.LastIndex(name, `\`); idx != -1 {
+		name = name[idx+1:]
+	}
+	return strings.ToLower(component(name))
+}
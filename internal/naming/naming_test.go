@@ -0,0 +1,170 @@
+package naming
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	date := time.Date(2025, 3, 7, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		template string
+		vars     Vars
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "default template",
+			template: DefaultTemplate,
+			vars:     Vars{Slug: "fix-login"},
+			want:     "agent/fix-login",
+		},
+		{
+			name:     "all variables",
+			template: "ai/{agent}/{user}/{date}-{ticket}-{slug}-{counter}",
+			vars:     Vars{Agent: "claude", User: "jdoe", Ticket: "PROJ-12", Slug: "fix", Date: date, Counter: 2},
+			want:     "ai/claude/jdoe/2025-03-07-PROJ-12-fix-2",
+		},
+		{
+			name:     "values are sanitized",
+			template: "bot/{user}/{slug}",
+			vars:     Vars{User: "j doe", Slug: "Fix login: bug!"},
+			want:     "bot/j-doe/Fix-login-bug",
+		},
+		{
+			name:     "slashes in values stay in one component",
+			template: "ai/{slug}",
+			vars:     Vars{Slug: "a/b"},
+			want:     "ai/a-b",
+		},
+		{
+			name:     "empty variables are dropped",
+			template: "ai/{agent}/{ticket}-{slug}",
+			vars:     Vars{Slug: "fix"},
+			want:     "ai/fix",
+		},
+		{
+			name:     "unknown variable",
+			template: "ai/{team}/{slug}",
+			vars:     Vars{Slug: "fix"},
+			wantErr:  true,
+		},
+		{
+			name:     "empty result",
+			template: "{ticket}",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.template, tt.vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderPath(t *testing.T) {
+	vars := Vars{Repo: "web", Branch: "agent/fix-login", Slug: "fix-login", User: "jdoe"}
+
+	tests := []struct {
+		template string
+		vars     Vars
+		want     string
+		wantErr  bool
+	}{
+		{DefaultPathTemplate, vars, "agent-fix-login", false},
+		{"~/worktrees/{repo}/{agent}/{slug}", vars, "~/worktrees/web/fix-login", false},
+		{"/srv/{repo}/{user}-{agent}-{slug}", vars, "/srv/web/jdoe-fix-login", false},
+		{".worktrees/{branch}", vars, ".worktrees/agent-fix-login", false},
+		{"{repo}/{team}", vars, "", true},
+		{"{agent}", vars, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := RenderPath(tt.template, tt.vars)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("RenderPath(%q) error = %v, wantErr %v", tt.template, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("RenderPath(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+
+	// Path-only variables are rejected in branch templates
+	if err := Validate("{repo}/{slug}"); err == nil {
+		t.Error("Expected {repo} to be rejected in a branch template")
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := map[string]string{
+		"feature/login":        "feature/login",
+		"feature/My Thing":     "feature/My-Thing",
+		"--weird..name--":      "weird.name",
+		"a//b":                 "a/b",
+		"fix: handle ~^ chars": "fix-handle-chars",
+	}
+	for input, want := range tests {
+		if got := Sanitize(input); got != want {
+			t.Errorf("Sanitize(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Fix the login redirect (#42)":                                       "fix-the-login-redirect-42",
+		"  Add caching\n\nDetails here":                                      "add-caching",
+		"Ünïcode & symbols!!":                                                "ünïcode-symbols",
+		"Refactor the session handling so tokens refresh before they expire": "refactor-the-session-handling-so-tokens",
+		"abcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyz":               "abcdefghijklmnopqrstuvwxyzabcdefghijklmn",
+		"": "",
+	}
+	for input, want := range tests {
+		if got := Slugify(input); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestUnique(t *testing.T) {
+	existing := map[string]bool{"agent/fix": true, "agent/fix-2": true}
+	taken := func(name string) bool { return existing[name] }
+
+	got, err := Unique(func(n int) (string, error) { return Suffixed("agent/fix", n), nil }, taken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "agent/fix-3" {
+		t.Errorf("Unique() = %q, want agent/fix-3", got)
+	}
+
+	// Templates with {counter} number themselves
+	existing = map[string]bool{"agent/fix-1": true}
+	got, err = Unique(func(n int) (string, error) { return Render("agent/{slug}-{counter}", Vars{Slug: "fix", Counter: n}) }, taken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "agent/fix-2" {
+		t.Errorf("Unique() = %q, want agent/fix-2", got)
+	}
+
+	// Errors from the candidate stop the search
+	if _, err := Unique(func(n int) (string, error) { return "", fmt.Errorf("boom") }, taken); err == nil {
+		t.Error("Expected the candidate error")
+	}
+
+	// The search gives up eventually
+	if _, err := Unique(func(n int) (string, error) { return "same", nil }, func(string) bool { return true }); err == nil {
+		t.Error("Expected an error when every name is taken")
+	}
+}
@@ -3,6 +3,7 @@ package scripts
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -22,11 +23,16 @@ var (
 // Runner executes scripts in a directory
 type Runner struct {
 	Dir string
+	// Stdout receives the progress and output of the scripts, Stderr their
+	// errors
+	Stdout io.Writer
+	Stderr io.Writer
 }
 
-// NewRunner creates a new script runner for the given directory
+// NewRunner creates a new script runner for the given directory that
+// writes to the terminal
 func NewRunner(dir string) *Runner {
-	return &Runner{Dir: dir}
+	return &Runner{Dir: dir, Stdout: os.Stdout, Stderr: os.Stderr}
 }
 
 // RunScripts executes a list of scripts in the runner's directory
@@ -35,17 +41,17 @@ func (r *Runner) RunScripts(scripts []string) error {
 		return nil
 	}
 
-	fmt.Println("🚀 Running post-create scripts...")
+	fmt.Fprintln(r.Stdout, "🚀 Running post-create scripts...")
 
 	for _, script := range scripts {
-		fmt.Printf("   → %s\n", scriptStyle.Render(script))
+		fmt.Fprintf(r.Stdout, "   → %s\n", scriptStyle.Render(script))
 
 		// Execute the script
 		if err := r.runScript(script); err != nil {
-			fmt.Printf("   %s Failed: %v\n", errorStyle.Render("✗"), err)
+			fmt.Fprintf(r.Stdout, "   %s Failed: %v\n", errorStyle.Render("✗"), err)
 			// Continue with other scripts even if one fails
 		} else {
-			fmt.Printf("   %s Success\n", successStyle.Render("✓"))
+			fmt.Fprintf(r.Stdout, "   %s Success\n", successStyle.Render("✓"))
 		}
 	}
 
@@ -57,8 +63,8 @@ func (r *Runner) runScript(script string) error {
 	// Use sh -c to run the script, allowing for complex commands
 	cmd := exec.Command("sh", "-c", script)
 	cmd.Dir = r.Dir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = r.Stdout
+	cmd.Stderr = r.Stderr
 
 	return cmd.Run()
 }
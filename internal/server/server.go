@@ -0,0 +1,319 @@
+// Package server serves the agentree API as JSON over HTTP, so editors and
+// dashboards can manage worktrees without running the CLI for every action.
+//
+//	GET    /v1/worktrees             list worktrees
+//	POST   /v1/worktrees             create a worktree from agentree.Options
+//	GET    /v1/worktrees/{branch}    the status of a worktree
+//	DELETE /v1/worktrees/{branch}    remove a worktree; ?force=1&delete_branch=1
+//	POST   /v1/sync/{branch}         rebase a worktree onto its base
+//	GET    /v1/logs/{branch}         the log of a background agent; ?follow=1
+//
+// Requests that send "Accept: text/event-stream" get their progress as
+// server-sent events, named after the agentree event types, followed by a
+// "result" or "error" event. Branch names keep their slashes.
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AryaLabsHQ/agentree/internal/launch"
+	"github.com/AryaLabsHQ/agentree/pkg/agentree"
+)
+
+// pollInterval is how often a followed log is checked for new output
+const pollInterval = 500 * time.Millisecond
+
+// Server handles API requests for one repository
+type Server struct {
+	// Dir is a directory in the repository
+	Dir string
+	// Token, when set, must be sent with every request as
+	// "Authorization: Bearer <token>"
+	Token string
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/worktrees", s.list)
+	mux.HandleFunc("POST /v1/worktrees", s.create)
+	mux.HandleFunc("GET /v1/worktrees/{branch...}", s.status)
+	mux.HandleFunc("DELETE /v1/worktrees/{branch...}", s.remove)
+	mux.HandleFunc("POST /v1/sync/{branch...}", s.sync)
+	mux.HandleFunc("GET /v1/logs/{branch...}", s.logs)
+	return s.authenticate(mux)
+}
+
+// authenticate rejects requests without the server's token
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("missing or wrong token"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	worktrees, err := agentree.List(r.Context(), agentree.ListOptions{Dir: s.Dir})
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, worktrees)
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	status, err := agentree.Status(r.Context(), r.PathValue("branch"), agentree.StatusOptions{Dir: s.Dir})
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	var opts agentree.Options
+	if err := decodeBody(r, &opts); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts.Dir = s.Dir
+
+	run(w, r, http.StatusCreated, func(events func(agentree.Event), output io.Writer) (any, error) {
+		opts.OnEvent, opts.Stdout, opts.Stderr = events, output, output
+		return agentree.Create(r.Context(), opts)
+	})
+}
+
+func (s *Server) remove(w http.ResponseWriter, r *http.Request) {
+	opts := agentree.RemoveOptions{Dir: s.Dir}
+	query := r.URL.Query()
+	opts.Force, _ = strconv.ParseBool(query.Get("force"))
+	opts.DeleteBranch, _ = strconv.ParseBool(query.Get("delete_branch"))
+
+	run(w, r, http.StatusOK, func(events func(agentree.Event), output io.Writer) (any, error) {
+		opts.OnEvent, opts.Stdout, opts.Stderr = events, output, output
+		if err := agentree.Remove(r.Context(), r.PathValue("branch"), opts); err != nil {
+			return nil, err
+		}
+		return map[string]string{"removed": r.PathValue("branch")}, nil
+	})
+}
+
+func (s *Server) sync(w http.ResponseWriter, r *http.Request) {
+	var opts agentree.SyncOptions
+	if err := decodeBody(r, &opts); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts.Dir = s.Dir
+
+	run(w, r, http.StatusOK, func(events func(agentree.Event), output io.Writer) (any, error) {
+		opts.OnEvent, opts.Stdout, opts.Stderr = events, output, output
+		return agentree.Sync(r.Context(), r.PathValue("branch"), opts)
+	})
+}
+
+// logs sends the log of a background agent. With follow, the log is sent
+// as "log" events as it grows, until the agent stops or the client goes.
+func (s *Server) logs(w http.ResponseWriter, r *http.Request) {
+	status, err := agentree.Status(r.Context(), r.PathValue("branch"), agentree.StatusOptions{Dir: s.Dir})
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	if status.Log == "" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no agent ran in the background for %s", status.Branch))
+		return
+	}
+	file, err := os.Open(status.Log)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer file.Close()
+
+	if follow, _ := strconv.ParseBool(r.URL.Query().Get("follow")); !follow {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = io.Copy(w, file)
+		return
+	}
+
+	events, err := newEventStream(w)
+	if err != nil {
+		writeError(w, http.StatusNotAcceptable, err)
+		return
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			events.send("log", map[string]string{"text": string(buf[:n])})
+			continue
+		}
+		if err != nil && err != io.EOF {
+			events.send("error", errorBody(err))
+			return
+		}
+		// Everything was sent; a stopped agent writes nothing more
+		if !launch.Running(status.PID) {
+			events.send("end", map[string]bool{"running": false})
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run runs an operation and sends its outcome: as JSON with status code
+// success, or as events when the client asked for an event stream
+func run(w http.ResponseWriter, r *http.Request, success int, operation func(events func(agentree.Event), output io.Writer) (any, error)) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		result, err := operation(nil, nil)
+		if err != nil {
+			writeError(w, errorStatus(err), err)
+			return
+		}
+		writeJSON(w, success, result)
+		return
+	}
+
+	events, err := newEventStream(w)
+	if err != nil {
+		writeError(w, http.StatusNotAcceptable, err)
+		return
+	}
+	result, err := operation(func(event agentree.Event) {
+		events.send(string(event.Type), event)
+	}, outputWriter{events})
+	if err != nil {
+		events.send("error", errorBody(err))
+		return
+	}
+	events.send("result", result)
+}
+
+// eventStream writes server-sent events. Setup scripts write their output
+// from several goroutines, so sending is serialized.
+type eventStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newEventStream(w http.ResponseWriter) (*eventStream, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming is not supported")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &eventStream{w: w, flusher: flusher}, nil
+}
+
+func (s *eventStream) send(name string, data any) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		encoded, _ = json.Marshal(errorBody(err))
+		name = "error"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, encoded)
+	s.flusher.Flush()
+}
+
+// outputWriter sends the output of setup scripts as "output" events
+type outputWriter struct {
+	events *eventStream
+}
+
+func (o outputWriter) Write(p []byte) (int, error) {
+	o.events.send("output", map[string]string{"text": string(p)})
+	return len(p), nil
+}
+
+// decodeBody decodes a JSON request body into v; an empty body leaves v
+// as it is
+func decodeBody(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && err != io.EOF {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	return nil
+}
+
+func errorStatus(err error) int {
+	if errors.Is(err, agentree.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusUnprocessableEntity
+}
+
+func errorBody(err error) map[string]string {
+	return map[string]string{"error": err.Error()}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, errorBody(err))
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// LoadToken returns the token in the file at path, creating the file with
+// a random token, readable only by its owner, when it doesn't exist
+func LoadToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(random)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
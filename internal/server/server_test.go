@@ -0,0 +1,184 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AryaLabsHQ/agentree/pkg/agentree"
+)
+
+// setupServer serves a repository with one commit on main
+func setupServer(t *testing.T, token string) (*httptest.Server, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("AGENTREE_CONFIG", "")
+
+	dir := filepath.Join(t.TempDir(), "repo")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.name", "Test User"},
+		{"config", "user.email", "test@example.com"},
+		{"commit", "-q", "--allow-empty", "-m", "Initial commit"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+
+	srv := httptest.NewServer((&Server{Dir: dir, Token: token}).Handler())
+	t.Cleanup(srv.Close)
+	return srv, dir
+}
+
+func do(t *testing.T, method, url, body string, header http.Header) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestWorktrees(t *testing.T) {
+	srv, _ := setupServer(t, "")
+
+	resp := do(t, "POST", srv.URL+"/v1/worktrees", `{"branch": "api", "skip_setup": true, "offline": true}`, nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create status = %d", resp.StatusCode)
+	}
+	var created agentree.Worktree
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Branch != "agent/api" || created.Base != "main" {
+		t.Errorf("created = %+v", created)
+	}
+
+	resp = do(t, "GET", srv.URL+"/v1/worktrees", "", nil)
+	var worktrees []agentree.Worktree
+	if err := json.NewDecoder(resp.Body).Decode(&worktrees); err != nil {
+		t.Fatal(err)
+	}
+	if len(worktrees) != 1 || worktrees[0].Path != created.Path {
+		t.Errorf("list = %+v", worktrees)
+	}
+
+	resp = do(t, "GET", srv.URL+"/v1/worktrees/agent/api", "", nil)
+	var status agentree.WorktreeStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || status.Branch != "agent/api" || status.Dirty {
+		t.Errorf("status = %d %+v", resp.StatusCode, status)
+	}
+
+	resp = do(t, "DELETE", srv.URL+"/v1/worktrees/agent/api?delete_branch=1", "", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("remove status = %d", resp.StatusCode)
+	}
+	if _, err := os.Stat(created.Path); !os.IsNotExist(err) {
+		t.Errorf("worktree still exists: %v", err)
+	}
+
+	resp = do(t, "GET", srv.URL+"/v1/worktrees/agent/api", "", nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status of a removed worktree = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestBadRequest(t *testing.T) {
+	srv, _ := setupServer(t, "")
+	resp := do(t, "POST", srv.URL+"/v1/worktrees", `{"brnach": "typo"}`, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+	resp = do(t, "POST", srv.URL+"/v1/worktrees", `{"offline": true}`, nil)
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("status without a branch = %d, want 422", resp.StatusCode)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	srv, _ := setupServer(t, "")
+	header := http.Header{"Accept": {"text/event-stream"}}
+	resp := do(t, "POST", srv.URL+"/v1/worktrees", `{"branch": "streamed", "scripts": ["echo set up"], "offline": true}`, header)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %s", ct)
+	}
+
+	var names []string
+	var output, result string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if name, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			names = append(names, name)
+		}
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			switch names[len(names)-1] {
+			case "output":
+				output += data
+			case "result":
+				result = data
+			}
+		}
+	}
+
+	joined := strings.Join(names, " ")
+	if !strings.Contains(joined, "progress") || !strings.Contains(joined, "created") || names[len(names)-1] != "result" {
+		t.Errorf("events = %s", joined)
+	}
+	if !strings.Contains(output, "set up") {
+		t.Errorf("output events = %s, want the script's output", output)
+	}
+	if !strings.Contains(result, `"branch":"agent/streamed"`) {
+		t.Errorf("result = %s", result)
+	}
+}
+
+func TestToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	token, err := LoadToken(path)
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("token file = %v, %v; want mode 0600", info, err)
+	}
+	if again, err := LoadToken(path); err != nil || again != token {
+		t.Errorf("LoadToken() again = %q, %v; want %q", again, err, token)
+	}
+
+	srv, _ := setupServer(t, token)
+	if resp := do(t, "GET", srv.URL+"/v1/worktrees", "", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status without token = %d, want 401", resp.StatusCode)
+	}
+	header := http.Header{"Authorization": {"Bearer wrong"}}
+	if resp := do(t, "GET", srv.URL+"/v1/worktrees", "", header); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status with a wrong token = %d, want 401", resp.StatusCode)
+	}
+	header = http.Header{"Authorization": {"Bearer " + token}}
+	if resp := do(t, "GET", srv.URL+"/v1/worktrees", "", header); resp.StatusCode != http.StatusOK {
+		t.Errorf("status with the token = %d, want 200", resp.StatusCode)
+	}
+}
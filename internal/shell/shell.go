@@ -0,0 +1,78 @@
+// Package shell generates the wrapper function that lets agentree change
+// the directory of the calling shell
+package shell
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CDFileEnv names the file the wrapper function passes to agentree. A
+// directory written to it becomes the shell's working directory once
+// agentree exits.
+const CDFileEnv = "AGENTREE_CD_FILE"
+
+// Shells lists the shells Init supports
+var Shells = []string{"bash", "zsh", "fish"}
+
+const posixInit = `# agentree shell integration
+# Lets 'agentree cd' and 'agentree create --cd' change the current directory.
+agentree() {
+  local __agentree_file __agentree_status __agentree_dir
+  __agentree_file="$(mktemp -t agentree-cd.XXXXXX)" || { command agentree "$@"; return; }
+  AGENTREE_CD_FILE="$__agentree_file" command agentree "$@"
+  __agentree_status=$?
+  __agentree_dir="$(cat "$__agentree_file")"
+  rm -f "$__agentree_file"
+  if [ -n "$__agentree_dir" ] && [ -d "$__agentree_dir" ]; then
+    cd -- "$__agentree_dir" || return
+  fi
+  return $__agentree_status
+}
+`
+
+const fishInit = `# agentree shell integration
+# Lets 'agentree cd' and 'agentree create --cd' change the current directory.
+function agentree
+    set -l agentree_file (mktemp -t agentree-cd.XXXXXX)
+    or begin
+        command agentree $argv
+        return
+    end
+    AGENTREE_CD_FILE=$agentree_file command agentree $argv
+    set -l agentree_status $status
+    set -l agentree_dir (cat $agentree_file)
+    rm -f $agentree_file
+    if test -n "$agentree_dir"; and test -d "$agentree_dir"
+        cd $agentree_dir
+    end
+    return $agentree_status
+end
+`
+
+// Init returns the wrapper function for shell
+func Init(shell string) (string, error) {
+	switch shell {
+	case "bash", "zsh":
+		return posixInit, nil
+	case "fish":
+		return fishInit, nil
+	}
+	return "", fmt.Errorf("unsupported shell %q (supported: %s)", shell, strings.Join(Shells, ", "))
+}
+
+// Active reports whether agentree runs under the wrapper function
+func Active() bool {
+	return os.Getenv(CDFileEnv) != ""
+}
+
+// ChangeDir asks the wrapper function to switch to dir once agentree exits.
+// It fails when agentree wasn't started through the wrapper.
+func ChangeDir(dir string) error {
+	file := os.Getenv(CDFileEnv)
+	if file == "" {
+		return fmt.Errorf("shell integration is not set up; add 'eval \"$(agentree shell-init bash)\"' (or zsh, fish) to your shell's rc file")
+	}
+	return os.WriteFile(file, []byte(dir+"\n"), 0600)
+}
@@ -0,0 +1,88 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInit(t *testing.T) {
+	for _, name := range Shells {
+		script, err := Init(name)
+		if err != nil {
+			t.Fatalf("Init(%s): %v", name, err)
+		}
+		if !strings.Contains(script, CDFileEnv) {
+			t.Errorf("Init(%s) doesn't pass %s", name, CDFileEnv)
+		}
+
+		// Check the syntax with the shell itself where it is installed
+		if _, err := exec.LookPath(name); err != nil {
+			continue
+		}
+		cmd := exec.Command(name, "-n")
+		cmd.Stdin = strings.NewReader(script)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("%s rejects its init script: %v\n%s", name, err, output)
+		}
+	}
+
+	if _, err := Init("tcsh"); err == nil {
+		t.Error("Expected an error for an unsupported shell")
+	}
+}
+
+func TestWrapperChangesDirectory(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+
+	// A stand-in agentree that asks the wrapper to move to its argument
+	bin := t.TempDir()
+	fake := "#!/bin/sh\nprintf '%s\\n' \"$2\" > \"$AGENTREE_CD_FILE\"\nexit 3\n"
+	if err := os.WriteFile(filepath.Join(bin, "agentree"), []byte(fake), 0755); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(t.TempDir(), "with space")
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	script, _ := Init("bash")
+	cmd := exec.Command("bash", "--noprofile", "--norc", "-c", script+`
+agentree cd "$1"
+echo "status=$?"
+pwd`, "bash", target)
+	cmd.Env = append(os.Environ(), "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash failed: %v\n%s", err, output)
+	}
+
+	want := "status=3\n" + target + "\n"
+	if string(output) != want {
+		t.Errorf("Output = %q, want %q", output, want)
+	}
+}
+
+func TestChangeDir(t *testing.T) {
+	t.Setenv(CDFileEnv, "")
+	if err := ChangeDir("/tmp"); err == nil {
+		t.Error("Expected an error without the wrapper")
+	}
+
+	file := filepath.Join(t.TempDir(), "cd")
+	t.Setenv(CDFileEnv, file)
+	if !Active() {
+		t.Error("Active() = false with the variable set")
+	}
+	if err := ChangeDir("/some/dir"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(file)
+	if string(data) != "/some/dir\n" {
+		t.Errorf("File content = %q", data)
+	}
+}
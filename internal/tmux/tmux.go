@@ -0,0 +1,164 @@
+// Package tmux lays out worktrees as tmux sessions and windows
+package tmux
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Client runs tmux commands against one tmux server
+type Client struct {
+	// Socket names a private server (tmux -L); empty uses the default one
+	Socket string
+}
+
+// New returns a client for the server behind socket
+func New(socket string) *Client {
+	return &Client{Socket: socket}
+}
+
+// Available reports whether tmux is installed
+func Available() bool {
+	_, err := exec.LookPath("tmux")
+	return err == nil
+}
+
+// command builds a tmux invocation for the client's server
+func (c *Client) command(args ...string) *exec.Cmd {
+	if c.Socket != "" {
+		args = append([]string{"-L", c.Socket}, args...)
+	}
+	return exec.Command("tmux", args...)
+}
+
+// run executes a tmux command and returns its trimmed output
+func (c *Client) run(args ...string) (string, error) {
+	output, err := c.command(args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("tmux %s: %s", args[0], strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// HasSession reports whether a session called name exists
+func (c *Client) HasSession(name string) bool {
+	return c.command("has-session", "-t", "="+name).Run() == nil
+}
+
+// Windows lists the window names of a session
+func (c *Client) Windows(session string) ([]string, error) {
+	output, err := c.run("list-windows", "-t", "="+session, "-F", "#{window_name}")
+	if err != nil {
+		return nil, err
+	}
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
+
+// PaneDirs lists the working directory of every pane in a window
+func (c *Client) PaneDirs(target string) ([]string, error) {
+	output, err := c.run("list-panes", "-t", target, "-F", "#{pane_current_path}")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(output, "\n"), nil
+}
+
+// Window describes a window with one pane per command
+type Window struct {
+	Session string
+	Name    string
+	// Dir is the working directory of every pane
+	Dir string
+	// Commands are typed into the panes in order; "" leaves a plain shell.
+	// The first pane gets the most room.
+	Commands []string
+}
+
+// Target returns the tmux target of the window
+func (w Window) Target() string {
+	return "=" + w.Session + ":" + w.Name
+}
+
+// Ensure creates the window, and its session if needed. An existing window
+// is left untouched. It reports whether the window was created.
+func (c *Client) Ensure(w Window) (bool, error) {
+	if !c.HasSession(w.Session) {
+		if _, err := c.run("new-session", "-d", "-s", w.Session, "-n", w.Name, "-c", w.Dir); err != nil {
+			return false, err
+		}
+	} else {
+		windows, err := c.Windows(w.Session)
+		if err != nil {
+			return false, err
+		}
+		for _, name := range windows {
+			if name == w.Name {
+				return false, nil
+			}
+		}
+		if _, err := c.run("new-window", "-d", "-t", "="+w.Session+":", "-n", w.Name, "-c", w.Dir); err != nil {
+			return false, err
+		}
+	}
+
+	// Address panes by ID so pane-base-index settings don't matter
+	first, err := c.run("display-message", "-p", "-t", w.Target(), "#{pane_id}")
+	if err != nil {
+		return true, err
+	}
+	panes := []string{first}
+	for i := 1; i < len(w.Commands); i++ {
+		pane, err := c.run("split-window", "-d", "-P", "-F", "#{pane_id}", "-t", w.Target(), "-c", w.Dir)
+		if err != nil {
+			return true, err
+		}
+		panes = append(panes, pane)
+	}
+	if len(panes) > 1 {
+		if _, err := c.run("select-layout", "-t", w.Target(), "main-vertical"); err != nil {
+			return true, err
+		}
+	}
+
+	for i, command := range w.Commands {
+		if command == "" {
+			continue
+		}
+		if _, err := c.run("send-keys", "-t", panes[i], command, "Enter"); err != nil {
+			return true, err
+		}
+	}
+	_, err = c.run("select-pane", "-t", first)
+	return true, err
+}
+
+// Attach shows the window: inside tmux the client switches to it, outside
+// tmux the terminal attaches to its session
+func (c *Client) Attach(w Window) error {
+	if _, err := c.run("select-window", "-t", w.Target()); err != nil {
+		return err
+	}
+
+	// $TMUX only says something about the default server
+	if os.Getenv("TMUX") != "" && c.Socket == "" {
+		_, err := c.run("switch-client", "-t", w.Target())
+		return err
+	}
+
+	cmd := c.command("attach-session", "-t", "="+w.Session)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Name makes a session or window name safe to use in a tmux target, where
+// "." and ":" separate panes and windows
+func Name(name string) string {
+	return strings.NewReplacer(".", "-", ":", "-").Replace(name)
+}
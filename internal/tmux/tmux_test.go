@@ -0,0 +1,96 @@
+package tmux
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient starts from an empty tmux server on a private socket
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	if !Available() {
+		t.Skip("tmux is not installed")
+	}
+
+	// A plain shell starts quickly whatever the user's profile does
+	t.Setenv("SHELL", "/bin/sh")
+
+	client := New(fmt.Sprintf("agentree-test-%d", os.Getpid()))
+	t.Cleanup(func() { _ = client.command("kill-server").Run() })
+	return client
+}
+
+func TestEnsure(t *testing.T) {
+	client := newTestClient(t)
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	marker := filepath.Join(dir, "marker")
+
+	window := Window{
+		Session:  "repo",
+		Name:     "agent/fix",
+		Dir:      dir,
+		Commands: []string{"touch " + marker, "", "true"},
+	}
+	created, err := client.Ensure(window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Error("Expected the window to be created")
+	}
+
+	paneDirs, err := client.PaneDirs(window.Target())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{dir, dir, dir}; !reflect.DeepEqual(paneDirs, want) {
+		t.Errorf("Pane dirs = %v, want %v", paneDirs, want)
+	}
+
+	// The first command was typed into its pane
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(marker); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			out, _ := client.run("capture-pane", "-p", "-t", window.Target())
+			t.Fatalf("The agent pane never ran its command:\n%s", out)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// A second window joins the session, and existing ones are kept
+	if created, err := client.Ensure(window); err != nil || created {
+		t.Errorf("Ensure() on an existing window = %v, %v", created, err)
+	}
+	other := Window{Session: "repo", Name: "agent/docs", Dir: dir, Commands: []string{""}}
+	if created, err := client.Ensure(other); err != nil || !created {
+		t.Fatalf("Ensure() second window = %v, %v", created, err)
+	}
+
+	windows, err := client.Windows("repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(windows, ",") != "agent/fix,agent/docs" {
+		t.Errorf("Windows = %v", windows)
+	}
+	if paneDirs, _ := client.PaneDirs(other.Target()); len(paneDirs) != 1 {
+		t.Errorf("Expected a single pane, got %v", paneDirs)
+	}
+}
+
+func TestName(t *testing.T) {
+	if got := Name("agent/v1.2:x"); got != "agent/v1-2-x" {
+		t.Errorf("Name() = %q", got)
+	}
+}
@@ -0,0 +1,108 @@
+// Package tracker looks up issues that worktrees are created for
+package tracker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Issue is a unit of work in an issue tracker
+type Issue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	URL    string `json:"url"`
+}
+
+// Task returns the issue as a task description: the title, then the body
+func (i *Issue) Task() string {
+	body := strings.TrimSpace(i.Body)
+	if body == "" {
+		return i.Title
+	}
+	return i.Title + "\n\n" + body
+}
+
+// Tracker finds issues by number
+type Tracker interface {
+	Issue(number int) (*Issue, error)
+}
+
+// New returns the tracker of the given kind for the repository at root.
+// An empty kind means GitHub.
+func New(kind, dir, root string) (Tracker, error) {
+	switch kind {
+	case "", "github":
+		return &GitHub{Dir: root}, nil
+	case "file":
+		if dir == "" {
+			return nil, errors.New("the file tracker needs tracker.dir")
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		return &Files{Dir: dir}, nil
+	}
+	return nil, fmt.Errorf("unknown issue tracker %q", kind)
+}
+
+// GitHub reads issues with the gh CLI
+type GitHub struct {
+	// Dir is a checkout of the repository whose issues are read
+	Dir string
+}
+
+// Issue implements Tracker
+func (g *GitHub) Issue(number int) (*Issue, error) {
+	if _, err := exec.LookPath("gh"); err != nil {
+		return nil, errors.New("gh CLI not found; install it or set tracker.kind")
+	}
+	cmd := exec.Command("gh", "issue", "view", strconv.Itoa(number), "--json", "number,title,body,url")
+	cmd.Dir = g.Dir
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("failed to read issue #%d: %s", number, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to read issue #%d: %w", number, err)
+	}
+
+	var issue Issue
+	if err := json.Unmarshal(output, &issue); err != nil {
+		return nil, fmt.Errorf("failed to parse issue #%d: %w", number, err)
+	}
+	return &issue, nil
+}
+
+// Files reads issues from markdown files named <number>.md. The first
+// line is the title, with any leading '#' removed; the rest is the body.
+type Files struct {
+	Dir string
+}
+
+// Issue implements Tracker
+func (f *Files) Issue(number int) (*Issue, error) {
+	path := filepath.Join(f.Dir, strconv.Itoa(number)+".md")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("issue #%d not found in %s", number, f.Dir)
+		}
+		return nil, err
+	}
+
+	title, body, _ := strings.Cut(strings.TrimSpace(string(data)), "\n")
+	return &Issue{
+		Number: number,
+		Title:  strings.TrimSpace(strings.TrimLeft(title, "#")),
+		Body:   strings.TrimSpace(body),
+		URL:    path,
+	}, nil
+}
@@ -0,0 +1,59 @@
+package tracker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFiles(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "issues")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "# Fix the login redirect\n\nUsers land on /home after logging in.\n"
+	if err := os.WriteFile(filepath.Join(dir, "42.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := New("file", "issues", root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue, err := tr.Issue(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Number != 42 || issue.Title != "Fix the login redirect" || issue.Body != "Users land on /home after logging in." {
+		t.Errorf("Issue() = %+v", issue)
+	}
+	if want := "Fix the login redirect\n\nUsers land on /home after logging in."; issue.Task() != want {
+		t.Errorf("Task() = %q, want %q", issue.Task(), want)
+	}
+
+	if _, err := tr.Issue(7); err == nil {
+		t.Error("Expected an error for a missing issue")
+	}
+}
+
+func TestNew(t *testing.T) {
+	if tr, err := New("", "", "/repo"); err != nil {
+		t.Error(err)
+	} else if _, ok := tr.(*GitHub); !ok {
+		t.Errorf("New(\"\") = %T, want *GitHub", tr)
+	}
+	if _, err := New("file", "", "/repo"); err == nil {
+		t.Error("Expected an error for the file tracker without a directory")
+	}
+	if _, err := New("jira", "", "/repo"); err == nil {
+		t.Error("Expected an error for an unknown tracker")
+	}
+}
+
+func TestTaskWithoutBody(t *testing.T) {
+	issue := &Issue{Title: "Bump deps", Body: "  \n"}
+	if issue.Task() != "Bump deps" {
+		t.Errorf("Task() = %q", issue.Task())
+	}
+}
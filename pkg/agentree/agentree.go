@@ -0,0 +1,226 @@
+// Package agentree creates and manages the worktrees of a repository the
+// same way the agentree command does, for programs that embed it.
+//
+// Every function works on the repository containing Options.Dir, or the
+// current directory, and reports its progress and warnings as Events.
+// Setup scripts, hooks and verbose output write to the writers in Options;
+// nothing else is printed.
+package agentree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/AryaLabsHQ/agentree/internal/config"
+	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/internal/hooks"
+	"github.com/AryaLabsHQ/agentree/internal/launch"
+	"github.com/AryaLabsHQ/agentree/internal/metadata"
+)
+
+// ErrNotFound is returned for a branch or path that has no worktree
+var ErrNotFound = errors.New("worktree not found")
+
+// ErrWorktreeLimit is returned by Create when the repository already has
+// Options.MaxWorktrees worktrees
+var ErrWorktreeLimit = errors.New("worktree limit reached")
+
+// findWorktree finds the worktree of a branch or at a path
+func findWorktree(repo *git.Repository, target string) (*git.WorktreeInfo, error) {
+	info, err := repo.FindWorktree(target)
+	if err != nil {
+		if _, listErr := repo.Worktrees(); listErr != nil {
+			return nil, listErr
+		}
+		return nil, fmt.Errorf("%w for %s", ErrNotFound, target)
+	}
+	return info, nil
+}
+
+// Worktree is a worktree of the repository and what agentree knows about it
+type Worktree struct {
+	Branch string `json:"branch"`
+	Path   string `json:"path"`
+	// Base is the branch, tag or commit the worktree was created from
+	Base      string    `json:"base,omitempty"`
+	Agent     string    `json:"agent,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+
+	// Task describes what the worktree was created to do
+	Task string `json:"task,omitempty"`
+	// Issue is the tracker issue the task came from, 0 if none
+	Issue int `json:"issue,omitempty"`
+	// Group links the worktrees of one fanout
+	Group string `json:"group,omitempty"`
+
+	// PID is the process of an agent started in the background, 0 if none
+	PID int `json:"pid,omitempty"`
+	// Running reports whether that agent is still running
+	Running bool `json:"running,omitempty"`
+}
+
+// EventType says what an Event reports
+type EventType string
+
+const (
+	// EventProgress announces a step, e.g. "Creating worktree..."
+	EventProgress EventType = "progress"
+	// EventCreated is sent once a new worktree exists, before it is set
+	// up; the event carries the worktree
+	EventCreated EventType = "created"
+	// EventFile reports a file copied, linked or written into a worktree
+	EventFile EventType = "file"
+	// EventDone reports a step that succeeded, e.g. a push
+	EventDone EventType = "done"
+	// EventWarning reports a problem that didn't stop the operation
+	EventWarning EventType = "warning"
+)
+
+// Event reports the progress of an operation
+type Event struct {
+	Type EventType `json:"type"`
+	// Message describes the event for people
+	Message  string    `json:"message"`
+	Worktree *Worktree `json:"worktree,omitempty"`
+}
+
+// reporter sends events to an optional callback
+type reporter func(Event)
+
+func (r reporter) send(event Event) {
+	if r != nil {
+		r(event)
+	}
+}
+
+func (r reporter) progress(format string, args ...any) {
+	r.send(Event{Type: EventProgress, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r reporter) file(format string, args ...any) {
+	r.send(Event{Type: EventFile, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r reporter) done(format string, args ...any) {
+	r.send(Event{Type: EventDone, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r reporter) warn(format string, args ...any) {
+	r.send(Event{Type: EventWarning, Message: fmt.Sprintf(format, args...)})
+}
+
+// waiting reports that another process holds the repository lock
+func (r reporter) waiting(holder string) {
+	r.warn("waiting for %s to finish...", holder)
+}
+
+// runHooks runs the hooks configured for the payload's event. A failing
+// pre hook is returned as an error; failing post hooks are warnings.
+func runHooks(repo *git.Repository, cfg *config.Config, payload hooks.Payload, stdout, stderr io.Writer, events reporter) error {
+	commands := cfg.HooksConfig.Commands(string(payload.Event))
+	if len(commands) == 0 {
+		return nil
+	}
+	events.progress("🪝 Running %s hooks...", payload.Event)
+	err := hooks.Run(repo.Context(), commands, payload, stdout, stderr)
+	if err != nil && !payload.Event.Vetoes() {
+		events.warn("%v", err)
+		return nil
+	}
+	return err
+}
+
+// hookPayload describes a worktree to hooks from its metadata record,
+// which may be nil
+func hookPayload(event hooks.Event, repo *git.Repository, branch, path string, record *metadata.Worktree) hooks.Payload {
+	payload := hooks.Payload{Event: event, Branch: branch, Path: path, RepoRoot: mainRoot(repo)}
+	if record != nil {
+		payload.Base = record.Base
+		payload.Agent = record.Agent
+		payload.Task = record.Task
+		payload.Issue = record.Issue
+		payload.Group = record.Group
+	}
+	return payload
+}
+
+// mainRoot returns the main checkout of the repository
+func mainRoot(repo *git.Repository) string {
+	if worktrees, err := repo.Worktrees(); err == nil && len(worktrees) > 0 {
+		return worktrees[0].Path
+	}
+	return repo.Root
+}
+
+// openStore returns the metadata store kept in the repository's git directory
+func openStore(repo *git.Repository) (*metadata.Store, error) {
+	commonDir, err := repo.CommonDir()
+	if err != nil {
+		return nil, err
+	}
+	return metadata.NewStore(filepath.Join(commonDir, "agentree")), nil
+}
+
+// newWorktree describes the worktree at path from its metadata record,
+// which may be nil
+func newWorktree(branch, path string, record *metadata.Worktree) *Worktree {
+	wt := &Worktree{Branch: branch, Path: path}
+	if record == nil {
+		return wt
+	}
+	wt.Base = record.Base
+	wt.Agent = record.Agent
+	wt.CreatedAt = record.CreatedAt
+	wt.Task = record.Task
+	wt.Issue = record.Issue
+	wt.Group = record.Group
+	wt.PID = record.PID
+	wt.Running = record.PID != 0 && launch.Running(record.PID)
+	return wt
+}
+
+// ListOptions configures List
+type ListOptions struct {
+	// Dir is a directory in the repository; empty means the current directory
+	Dir string
+	// OnEvent receives warnings about unreadable metadata
+	OnEvent func(Event)
+}
+
+// List returns the worktrees of the repository, leaving out the main
+// checkout. Worktrees agentree didn't create have no metadata.
+func List(ctx context.Context, opts ListOptions) ([]Worktree, error) {
+	events := reporter(opts.OnEvent)
+	repo, err := git.Open(ctx, opts.Dir, nil)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := repo.Worktrees()
+	if err != nil {
+		return nil, err
+	}
+	store, err := openStore(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	worktrees := []Worktree{}
+	for i, info := range infos {
+		if i == 0 {
+			continue
+		}
+		var record *metadata.Worktree
+		if info.Branch != "" {
+			record, err = store.Get(info.Branch)
+			if err != nil && !errors.Is(err, metadata.ErrNotFound) {
+				events.warn("%v", err)
+			}
+		}
+		worktrees = append(worktrees, *newWorktree(info.Branch, info.Path, record))
+	}
+	return worktrees, nil
+}
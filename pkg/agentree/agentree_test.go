@@ -0,0 +1,384 @@
+package agentree_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AryaLabsHQ/agentree/pkg/agentree"
+)
+
+// setupRepo creates a repository with one commit on main and a config
+// home of its own
+func setupRepo(t *testing.T) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("AGENTREE_CONFIG", "")
+
+	dir := filepath.Join(t.TempDir(), "repo")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "init", "-q", "-b", "main")
+	git(t, dir, "config", "user.name", "Test User")
+	git(t, dir, "config", "user.email", "test@example.com")
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "add", ".")
+	git(t, dir, "commit", "-q", "-m", "Initial commit")
+	return dir
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestCreate(t *testing.T) {
+	dir := setupRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("KEY=value\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte(".env\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []agentree.Event
+	wt, err := agentree.Create(context.Background(), agentree.Options{
+		Dir:       dir,
+		Branch:    "feature",
+		Task:      "Add a feature",
+		SkipSetup: true,
+		Offline:   true,
+		OnEvent:   func(event agentree.Event) { events = append(events, event) },
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if wt.Branch != "agent/feature" || wt.Base != "main" || wt.Task != "Add a feature" {
+		t.Errorf("Create() = %+v", wt)
+	}
+	if _, err := os.Stat(filepath.Join(wt.Path, ".env")); err != nil {
+		t.Errorf(".env wasn't copied: %v", err)
+	}
+
+	var created, copied bool
+	for _, event := range events {
+		switch {
+		case event.Type == agentree.EventCreated:
+			created = event.Worktree != nil && event.Worktree.Path == wt.Path
+		case event.Type == agentree.EventFile && strings.Contains(event.Message, ".env"):
+			copied = true
+		}
+	}
+	if !created || !copied {
+		t.Errorf("events = %+v, want created and .env copied", events)
+	}
+
+	// A taken name gets a suffix
+	again, err := agentree.Create(context.Background(), agentree.Options{Dir: dir, Branch: "feature", SkipEnv: true, SkipSetup: true, Offline: true})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if again.Branch != "agent/feature-2" {
+		t.Errorf("second Create() branch = %s, want agent/feature-2", again.Branch)
+	}
+}
+
+func TestCreateInsideRepo(t *testing.T) {
+	dir := setupRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("KEY=value\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte(".env\n*.log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "build.log"), []byte("ok\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Worktrees under the repository must not be copied into each other
+	settings := map[string][]string{
+		"worktree_root":      {".worktrees"},
+		"artifacts.patterns": {"**/*.log"},
+	}
+	var paths []string
+	for _, branch := range []string{"one", "two"} {
+		wt, err := agentree.Create(context.Background(), agentree.Options{Dir: dir, Branch: branch, SkipSetup: true, Offline: true, Settings: settings})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		paths = append(paths, wt.Path)
+	}
+
+	if want := filepath.Join(dir, ".worktrees"); filepath.Dir(paths[1]) != want {
+		t.Fatalf("worktree = %s, want it in %s", paths[1], want)
+	}
+	for _, file := range []string{".env", "build.log"} {
+		if _, err := os.Stat(filepath.Join(paths[1], file)); err != nil {
+			t.Errorf("%s wasn't copied: %v", file, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(paths[1], ".worktrees")); !os.IsNotExist(err) {
+		t.Errorf("second worktree has a .worktrees directory: %v", err)
+	}
+}
+
+func TestCreateMaxWorktrees(t *testing.T) {
+	dir := setupRepo(t)
+
+	// Creates running at the same time still respect the cap
+	errs := make(chan error, 3)
+	for _, branch := range []string{"one", "two", "three"} {
+		go func() {
+			_, err := agentree.Create(context.Background(), agentree.Options{Dir: dir, Branch: branch, MaxWorktrees: 2, SkipSetup: true, Offline: true})
+			errs <- err
+		}()
+	}
+	var created, refused int
+	for range 3 {
+		switch err := <-errs; {
+		case err == nil:
+			created++
+		case errors.Is(err, agentree.ErrWorktreeLimit):
+			refused++
+		default:
+			t.Errorf("Create() error = %v", err)
+		}
+	}
+	if created != 2 || refused != 1 {
+		t.Errorf("created %d and refused %d worktrees, want 2 and 1", created, refused)
+	}
+}
+
+func TestCreateNeedsBranch(t *testing.T) {
+	dir := setupRepo(t)
+	if _, err := agentree.Create(context.Background(), agentree.Options{Dir: dir, Offline: true}); err == nil {
+		t.Error("Create() without a branch or task succeeded")
+	}
+}
+
+func TestCreateScripts(t *testing.T) {
+	dir := setupRepo(t)
+	var stdout strings.Builder
+	wt, err := agentree.Create(context.Background(), agentree.Options{
+		Dir:     dir,
+		Branch:  "scripted",
+		Scripts: []string{"echo hello > setup.txt"},
+		Offline: true,
+		Stdout:  &stdout,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(wt.Path, "setup.txt")); err != nil {
+		t.Errorf("script didn't run: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "echo hello") {
+		t.Errorf("Stdout = %q, want the script's progress", stdout.String())
+	}
+}
+
+func TestCreateCheckout(t *testing.T) {
+	dir := setupRepo(t)
+	ctx := context.Background()
+	git(t, dir, "branch", "feature")
+
+	// A branch of someone else's isn't based on the current branch
+	wt, err := agentree.Create(ctx, agentree.Options{Dir: dir, Checkout: "feature", SkipSetup: true, Offline: true})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if wt.Branch != "feature" || wt.Base != "" {
+		t.Errorf("Create() = %+v, want feature without a base", wt)
+	}
+	if _, err := agentree.Sync(ctx, wt.Branch, agentree.SyncOptions{Dir: dir, Offline: true}); err == nil || !strings.Contains(err.Error(), "no base") {
+		t.Errorf("Sync() without a base error = %v", err)
+	}
+	if _, err := agentree.Sync(ctx, wt.Branch, agentree.SyncOptions{Dir: dir, Onto: "main", Offline: true}); err != nil {
+		t.Errorf("Sync() onto main error = %v", err)
+	}
+
+	// A remote branch syncs with its upstream
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	git(t, dir, "init", "-q", "--bare", remote)
+	git(t, dir, "remote", "add", "origin", remote)
+	git(t, dir, "push", "-q", "origin", "main:shared")
+	git(t, dir, "fetch", "-q", "origin")
+	wt, err = agentree.Create(ctx, agentree.Options{Dir: dir, Checkout: "shared", SkipSetup: true, Offline: true})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if wt.Branch != "shared" || wt.Base != "origin/shared" {
+		t.Errorf("Create() = %+v, want shared based on origin/shared", wt)
+	}
+}
+
+func TestListRemove(t *testing.T) {
+	dir := setupRepo(t)
+	ctx := context.Background()
+	wt, err := agentree.Create(ctx, agentree.Options{Dir: dir, Branch: "listed", Agent: "generic", SkipSetup: true, Offline: true})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	worktrees, err := agentree.List(ctx, agentree.ListOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(worktrees) != 1 || worktrees[0].Branch != wt.Branch || worktrees[0].Agent != "generic" {
+		t.Fatalf("List() = %+v", worktrees)
+	}
+
+	if err := agentree.Remove(ctx, wt.Branch, agentree.RemoveOptions{Dir: dir, DeleteBranch: true}); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := os.Stat(wt.Path); !os.IsNotExist(err) {
+		t.Errorf("worktree still exists: %v", err)
+	}
+	if branches := git(t, dir, "branch", "--list", wt.Branch); branches != "" {
+		t.Errorf("branch %s wasn't deleted", wt.Branch)
+	}
+	if worktrees, _ := agentree.List(ctx, agentree.ListOptions{Dir: dir}); len(worktrees) != 0 {
+		t.Errorf("List() after Remove() = %+v", worktrees)
+	}
+}
+
+func TestSync(t *testing.T) {
+	dir := setupRepo(t)
+	ctx := context.Background()
+	wt, err := agentree.Create(ctx, agentree.Options{Dir: dir, Branch: "synced", SkipSetup: true, Offline: true})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	git(t, wt.Path, "commit", "-q", "--allow-empty", "-m", "Agent work")
+	git(t, dir, "commit", "-q", "--allow-empty", "-m", "Upstream work")
+
+	if _, err := agentree.Sync(ctx, wt.Branch, agentree.SyncOptions{Dir: dir, Offline: true}); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if log := git(t, wt.Path, "log", "--format=%s", "-n3"); log != "Agent work\nUpstream work\nInitial commit" {
+		t.Errorf("log after Sync() = %q", log)
+	}
+
+	// Uncommitted changes are left alone
+	if err := os.WriteFile(filepath.Join(wt.Path, "README.md"), []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "commit", "-q", "--allow-empty", "-m", "More upstream work")
+	if _, err := agentree.Sync(ctx, wt.Branch, agentree.SyncOptions{Dir: dir, Offline: true}); err == nil {
+		t.Error("Sync() of a dirty worktree succeeded")
+	}
+}
+
+func TestSyncConflict(t *testing.T) {
+	dir := setupRepo(t)
+	ctx := context.Background()
+	wt, err := agentree.Create(ctx, agentree.Options{Dir: dir, Branch: "conflicted", SkipSetup: true, Offline: true})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	for _, d := range []string{wt.Path, dir} {
+		if err := os.WriteFile(filepath.Join(d, "README.md"), []byte(d+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git(t, d, "commit", "-q", "-am", "Edit README")
+	}
+	head := git(t, wt.Path, "rev-parse", "HEAD")
+
+	if _, err := agentree.Sync(ctx, wt.Branch, agentree.SyncOptions{Dir: dir, Offline: true}); err == nil {
+		t.Fatal("Sync() with conflicts succeeded")
+	}
+	if after := git(t, wt.Path, "rev-parse", "HEAD"); after != head {
+		t.Errorf("HEAD moved from %s to %s", head, after)
+	}
+	if status := git(t, wt.Path, "status", "--porcelain"); status != "" {
+		t.Errorf("worktree left dirty: %s", status)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	dir := setupRepo(t)
+	ctx := context.Background()
+	log := filepath.Join(t.TempDir(), "hooks.log")
+	record := `echo "$AGENTREE_HOOK $0 $AGENTREE_BRANCH" >> ` + log
+
+	// Global hooks run before the project's
+	global := filepath.Join(t.TempDir(), "config.toml")
+	config := "[hooks]\npre_create = ['%s']\npost_create = ['%s']\npre_remove = ['%s']\npost_remove = ['%s']\npost_sync = ['%s']\n"
+	globalHook := strings.ReplaceAll(record, "$0", "global")
+	if err := os.WriteFile(global, []byte(strings.ReplaceAll(config, "%s", globalHook)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AGENTREE_CONFIG", global)
+	projectHook := strings.ReplaceAll(record, "$0", "project")
+	if err := os.WriteFile(filepath.Join(dir, ".agentree.toml"), []byte("[hooks]\npost_create = ['"+projectHook+"']\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wt, err := agentree.Create(ctx, agentree.Options{Dir: dir, Branch: "hooked", SkipSetup: true, Offline: true})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := agentree.Sync(ctx, wt.Branch, agentree.SyncOptions{Dir: dir, Offline: true}); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if err := agentree.Remove(ctx, wt.Branch, agentree.RemoveOptions{Dir: dir}); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "pre_create global agent/hooked\npost_create global agent/hooked\npost_create project agent/hooked\n" +
+		"post_sync global agent/hooked\npre_remove global agent/hooked\npost_remove global agent/hooked\n"
+	if string(data) != want {
+		t.Errorf("hooks ran as\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestHookVeto(t *testing.T) {
+	dir := setupRepo(t)
+	ctx := context.Background()
+	veto := map[string][]string{"hooks.pre_create": {"echo 'no worktrees on Fridays' >&2; exit 1"}}
+
+	_, err := agentree.Create(ctx, agentree.Options{Dir: dir, Branch: "vetoed", SkipSetup: true, Offline: true, Settings: veto})
+	if err == nil || !strings.Contains(err.Error(), "no worktrees on Fridays") {
+		t.Fatalf("Create() error = %v, want the hook's veto", err)
+	}
+	if branches := git(t, dir, "branch", "--list", "agent/vetoed"); branches != "" {
+		t.Error("a vetoed worktree's branch was created")
+	}
+	if worktrees, _ := agentree.List(ctx, agentree.ListOptions{Dir: dir}); len(worktrees) != 0 {
+		t.Errorf("List() after a veto = %+v", worktrees)
+	}
+
+	// A pre-remove hook that fails keeps the worktree
+	wt, err := agentree.Create(ctx, agentree.Options{Dir: dir, Branch: "kept", SkipSetup: true, Offline: true})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".agentree.toml"), []byte("[hooks]\npre_remove = ['exit 1']\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := agentree.Remove(ctx, wt.Branch, agentree.RemoveOptions{Dir: dir}); err == nil {
+		t.Error("Remove() despite a failing pre-remove hook succeeded")
+	}
+	if _, err := os.Stat(wt.Path); err != nil {
+		t.Errorf("worktree was removed: %v", err)
+	}
+}
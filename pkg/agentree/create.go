@@ -0,0 +1,798 @@
+package agentree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AryaLabsHQ/agentree/internal/agent"
+	"github.com/AryaLabsHQ/agentree/internal/config"
+	"github.com/AryaLabsHQ/agentree/internal/detector"
+	"github.com/AryaLabsHQ/agentree/internal/env"
+	"github.com/AryaLabsHQ/agentree/internal/forge"
+	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/internal/hooks"
+	"github.com/AryaLabsHQ/agentree/internal/metadata"
+	"github.com/AryaLabsHQ/agentree/internal/naming"
+	"github.com/AryaLabsHQ/agentree/internal/scripts"
+)
+
+// Options configures Create. The zero value creates nothing: a worktree
+// needs a Branch, a Task to name the branch after, a Checkout or a
+// PullRequest. Options decode from JSON, except for the fields about the
+// calling process.
+type Options struct {
+	// Dir is a directory in the repository; empty means the current
+	// directory
+	Dir string `json:"-"`
+	// Scope is the subdirectory, relative to the repository root, whose
+	// nested config files apply; only the root's apply without it
+	Scope string `json:"scope,omitempty"`
+
+	// Branch names the new branch. The branch template applies, and a
+	// name that is taken gets a numeric suffix.
+	Branch string `json:"branch,omitempty"`
+	// Base is the branch, tag or commit to fork from; empty means the
+	// current branch. Checked out branches record their upstream as the
+	// base instead, and pull requests none, unless Base is set.
+	Base string `json:"base,omitempty"`
+	// Checkout checks out an existing local or remote branch instead of
+	// creating one
+	Checkout string `json:"checkout,omitempty"`
+	// PullRequest checks out a pull request, fetched from origin
+	PullRequest int `json:"pull_request,omitempty"`
+	// Dest is where the worktree goes; empty means the configured layout
+	Dest string `json:"dest,omitempty"`
+
+	// Task describes what the worktree is for; it names the branch when
+	// Branch is empty
+	Task string `json:"task,omitempty"`
+	// Issue is the tracker issue the task came from
+	Issue int `json:"issue,omitempty"`
+	// Ticket fills the {ticket} template variable; it defaults to Issue
+	Ticket string `json:"ticket,omitempty"`
+	// Agent is the agent profile to prepare the worktree for; empty means
+	// the configured one
+	Agent string `json:"agent,omitempty"`
+	// Group links the worktrees of one fanout
+	Group string `json:"group,omitempty"`
+	// MaxWorktrees makes Create fail with ErrWorktreeLimit once the
+	// repository has this many worktrees besides the main checkout
+	// (0 = no limit)
+	MaxWorktrees int `json:"max_worktrees,omitempty"`
+
+	// SkipEnv, SkipArtifacts and SkipSetup leave out copying env files,
+	// copying artifacts and running setup scripts
+	SkipEnv       bool `json:"skip_env,omitempty"`
+	SkipArtifacts bool `json:"skip_artifacts,omitempty"`
+	SkipSetup     bool `json:"skip_setup,omitempty"`
+	// Scripts replace the configured and detected setup scripts; they run
+	// even with SkipSetup
+	Scripts []string `json:"scripts,omitempty"`
+	// Push pushes the new branch to origin
+	Push bool `json:"push,omitempty"`
+
+	// Offline skips fetching; Fetch fetches even if that happened recently
+	Offline bool `json:"offline,omitempty"`
+	Fetch   bool `json:"fetch,omitempty"`
+
+	// Settings override configuration keys on top of every config file,
+	// like 'agentree config set <key> <values>' would
+	Settings map[string][]string `json:"settings,omitempty"`
+
+	// Verbose describes env file and artifact discovery in detail on
+	// Stdout; without Stdout it has no effect
+	Verbose bool `json:"-"`
+	// Stdout and Stderr receive the output of setup scripts and hooks; nil
+	// discards it
+	Stdout io.Writer `json:"-"`
+	Stderr io.Writer `json:"-"`
+	// OnEvent receives the progress of the creation
+	OnEvent func(Event) `json:"-"`
+}
+
+// Create creates a worktree and sets it up: it copies env files and
+// artifacts, runs setup scripts and writes the agent's context files.
+// Pre-create hooks that fail stop it before the worktree is added.
+// Setup problems are reported as warnings; once EventCreated was sent,
+// only a failed push makes Create fail.
+func Create(ctx context.Context, opts Options) (*Worktree, error) {
+	events := reporter(opts.OnEvent)
+	repo, err := git.Open(ctx, opts.Dir, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load configuration up front so an invalid file fails before anything is created
+	cfg, err := loadConfig(repo, opts)
+	if err != nil {
+		return nil, err
+	}
+	var profile *agent.Profile
+	if cfg.Agent != "" {
+		if profile, err = agent.Resolve(cfg.Agent, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// Values shared by the branch and worktree path templates
+	vars := naming.Vars{
+		User:   naming.CurrentUser(),
+		Ticket: opts.Ticket,
+		Date:   time.Now(),
+	}
+	if vars.Ticket == "" && opts.Issue != 0 {
+		vars.Ticket = strconv.Itoa(opts.Issue)
+	}
+	if profile != nil {
+		vars.Agent = profile.Name
+	}
+	layout, err := newWorktreeLayout(repo, cfg, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a branch name the task names the branch
+	existing := opts.Checkout != "" || opts.PullRequest != 0
+	branch, base, dest := opts.Branch, opts.Base, opts.Dest
+	if branch == "" && !existing {
+		branch = naming.Slugify(opts.Task)
+	}
+	if branch == "" && !existing {
+		return nil, errors.New("a branch name or task is required")
+	}
+	if base == "" && !existing {
+		if base, err = repo.CurrentBranch(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Bring the base or the branch to check out up to date; pull requests
+	// are fetched when their worktree is added
+	if !opts.Offline {
+		switch {
+		case opts.PullRequest != 0:
+		case opts.Checkout != "":
+			refreshRemotes(repo, opts.Checkout, opts.Fetch, events)
+		default:
+			refreshRemotes(repo, base, opts.Fetch, events)
+		}
+	}
+	if !existing {
+		if base, err = repo.ResolveBase(base); err != nil {
+			return nil, err
+		}
+	}
+
+	// Other agentree processes could take the same name or path until
+	// the worktree exists, so they wait here
+	repoLock, err := repo.Lock(events.waiting)
+	if err != nil {
+		return nil, err
+	}
+	defer repoLock.Release()
+
+	// Count under the lock, so concurrent creates can't all get under the cap
+	if opts.MaxWorktrees > 0 {
+		infos, err := repo.Worktrees()
+		if err != nil {
+			return nil, err
+		}
+		if linked := len(infos) - 1; linked >= opts.MaxWorktrees {
+			return nil, fmt.Errorf("%w: the repository already has %d worktrees, at most %d are allowed",
+				ErrWorktreeLimit, linked, opts.MaxWorktrees)
+		}
+	}
+
+	// Expand the branch template and avoid names or paths that are already taken
+	template := cfg.BranchTemplate
+	if template == "" && profile != nil {
+		template = profile.BranchTemplate()
+	}
+	pathLayout := layout
+	if dest != "" {
+		pathLayout = nil
+	}
+	var pulls forge.Forge
+	switch {
+	case opts.PullRequest != 0:
+		originURL, _ := repo.RemoteURL("origin")
+		if pulls, err = forge.New(cfg.Forge, originURL); err == nil {
+			branch = pulls.PullBranch(opts.PullRequest)
+		}
+	case opts.Checkout != "":
+		// A remote branch is checked out to a local one of the same name.
+		// The branch isn't ours to rebase onto the current one, so it
+		// syncs with its upstream, or only with a base given explicitly.
+		branch = opts.Checkout
+		upstream := ""
+		if repo.BranchExists(branch) {
+			upstream, _ = repo.Upstream(branch)
+		} else {
+			upstream, branch, err = repo.RemoteBranch(opts.Checkout)
+		}
+		if base == "" {
+			base = upstream
+		}
+	default:
+		branch, err = resolveBranchName(repo, branch, template, vars, pathLayout, events)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	laidOut := dest == ""
+	if laidOut {
+		if dest, err = layout.path(branch); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := os.Stat(dest); err == nil {
+		return nil, fmt.Errorf("destination %s already exists", dest)
+	}
+
+	stdout, stderr := opts.Stdout, opts.Stderr
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	// Pre-create hooks may veto the worktree before anything is written
+	payload := hooks.Payload{
+		Event:    hooks.PreCreate,
+		Branch:   branch,
+		Path:     dest,
+		RepoRoot: mainRoot(repo),
+		Base:     base,
+		Task:     opts.Task,
+		Issue:    opts.Issue,
+		Group:    opts.Group,
+	}
+	if absDest, err := filepath.Abs(dest); err == nil {
+		payload.Path = absDest
+	}
+	if profile != nil {
+		payload.Agent = profile.Name
+	}
+	if err := runHooks(repo, cfg, payload, stdout, stderr, events); err != nil {
+		return nil, err
+	}
+
+	if laidOut {
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create the worktree directory: %w", err)
+		}
+	}
+	// Keep worktrees inside the repository out of git status
+	if err := excludeWorktree(repo, dest); err != nil {
+		events.warn("could not add %s to .git/info/exclude: %v", dest, err)
+	}
+
+	switch {
+	case opts.PullRequest != 0:
+		events.progress("Fetching pull request #%d...", opts.PullRequest)
+		err = repo.FetchBranch("origin", pulls.PullRef(opts.PullRequest), branch)
+		if err == nil {
+			_, err = repo.CheckoutWorktree(branch, dest)
+		}
+	case opts.Checkout != "":
+		events.progress("Checking out %s...", opts.Checkout)
+		_, err = repo.CheckoutWorktree(opts.Checkout, dest)
+	default:
+		events.progress("Creating worktree...")
+		err = repo.CreateWorktree(branch, base, dest)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Remember how the worktree was made for run, ls and rm
+	record := &metadata.Worktree{Branch: branch, Path: dest, Base: base, CreatedAt: time.Now()}
+	if absDest, err := filepath.Abs(dest); err == nil {
+		record.Path = absDest
+	}
+	if profile != nil {
+		record.Agent = profile.Name
+	}
+	record.Task = opts.Task
+	record.Group = opts.Group
+	record.Issue = opts.Issue
+	store, err := openStore(repo)
+	if err == nil {
+		err = store.Save(record)
+	}
+	if err != nil {
+		events.warn("could not record worktree metadata: %v", err)
+	}
+	wt := newWorktree(branch, record.Path, record)
+	events.send(Event{Type: EventCreated, Message: "✅ Worktree ready", Worktree: wt})
+	repoLock.Release()
+
+	skip := worktreeDirs(repo, layout.root)
+	if !opts.SkipEnv {
+		if err := copyEnvFiles(repo, cfg, dest, skip, opts, events); err != nil {
+			return wt, err
+		}
+	}
+	if !opts.SkipArtifacts {
+		if err := copyArtifacts(repo, cfg, dest, skip, opts, events); err != nil {
+			return wt, err
+		}
+	}
+
+	// Copy the agent's local files, e.g. personal settings that aren't committed
+	if profile != nil && len(profile.Files) > 0 {
+		copier, err := newCopier(repo, cfg, dest, nil, opts, events)
+		var copied []string
+		if err == nil {
+			copied, err = profile.CopyFiles(copier)
+		}
+		if err != nil {
+			events.warn("Some %s files couldn't be copied: %v", profile.Name, err)
+		}
+		for _, file := range copied {
+			events.file("📋 Copied %s", file)
+		}
+	}
+
+	var setupCommands []string
+	if !opts.SkipSetup || len(opts.Scripts) > 0 {
+		setupCommands = runSetup(cfg, profile, dest, opts.Scripts, stdout, stderr, events)
+	}
+
+	// Give the agent its bearings
+	if profile != nil {
+		info := agent.ContextInfo{Task: opts.Task, Branch: branch, Base: base, Setup: setupCommands}
+		if err := writeContextFiles(repo, profile, cfg, dest, info, events); err != nil {
+			events.warn("Could not write %s context files: %v", profile.Name, err)
+		}
+	}
+	_ = runHooks(repo, cfg, hookPayload(hooks.PostCreate, repo, branch, record.Path, record), stdout, stderr, events)
+
+	if opts.Push {
+		events.progress("Pushing to origin...")
+		if err := repo.Push(dest, "origin", branch); err != nil {
+			return wt, fmt.Errorf("failed to push %s: %w", branch, err)
+		}
+		events.done("✓ Pushed to origin")
+	}
+	return wt, nil
+}
+
+// Layout describes where Create puts worktrees, e.g.
+// ../repo-worktrees/{branch}, with the path template unexpanded
+func Layout(ctx context.Context, opts Options) (string, error) {
+	repo, err := git.Open(ctx, opts.Dir, nil)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := loadConfig(repo, opts)
+	if err != nil {
+		return "", err
+	}
+	layout, err := newWorktreeLayout(repo, cfg, naming.Vars{})
+	if err != nil {
+		return "", err
+	}
+	return layout.describe(), nil
+}
+
+// loadConfig merges the configuration that applies to opts: every config
+// file, the environment, then opts.Settings, Agent and Scripts
+func loadConfig(repo *git.Repository, opts Options) (*config.Config, error) {
+	scopeDir, err := config.ResolveScope(repo.Root, opts.Scope)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := config.LoadLayers(repo.Root, scopeDir)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := config.NewOverrideLayer(config.LayerFlag)
+	for key, values := range opts.Settings {
+		if err := overrides.Set(key, values...); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.Scripts) > 0 {
+		if err := overrides.Set("post_create_scripts", opts.Scripts...); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Agent != "" {
+		if err := overrides.Set("agent", opts.Agent); err != nil {
+			return nil, err
+		}
+	}
+	return config.MergeLayers(append(layers, overrides)...), nil
+}
+
+// worktreeDirs returns the worktree root and the linked worktrees, which
+// env and artifact discovery must not enter when they sit inside the
+// repository
+func worktreeDirs(repo *git.Repository, root string) []string {
+	dirs := []string{root}
+	if worktrees, err := repo.Worktrees(); err == nil {
+		for _, wt := range worktrees {
+			dirs = append(dirs, wt.Path)
+		}
+	}
+
+	var skip []string
+	for _, dir := range dirs {
+		if filepath.Clean(dir) != filepath.Clean(repo.Root) {
+			skip = append(skip, dir)
+		}
+	}
+	return skip
+}
+
+// newCopier returns the env file copier for dest. It skips the directories
+// in skip, describes discovery on opts.Stdout if opts.Verbose is set and
+// sends its warnings to events.
+func newCopier(repo *git.Repository, cfg *config.Config, dest string, skip []string, opts Options, events reporter) (*env.EnvFileCopier, error) {
+	copier, err := env.NewConfiguredCopier(repo.Root, dest, &cfg.EnvConfig)
+	if err != nil {
+		return nil, err
+	}
+	stdout := opts.Stdout
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	copier.SetOutput(stdout, events.warn)
+	copier.SetVerbose(opts.Verbose)
+	copier.SetSkipDirs(skip)
+	return copier, nil
+}
+
+// copyEnvFiles copies the env files the configuration selects into dest,
+// skipping the directories in skip
+func copyEnvFiles(repo *git.Repository, cfg *config.Config, dest string, skip []string, opts Options, events reporter) error {
+	if !cfg.EnvConfig.Enabled {
+		events.progress("Environment file copying disabled by configuration")
+		return nil
+	}
+	copier, err := newCopier(repo, cfg, dest, skip, opts, events)
+	if err != nil {
+		return err
+	}
+
+	events.progress("Discovering environment files...")
+	files, err := copier.DiscoverFiles()
+	if err != nil {
+		events.warn("Error discovering files: %v", err)
+	}
+	files = env.ExcludeFiles(files, cfg.EnvConfig.ExcludePatterns)
+	if len(files) == 0 {
+		events.progress("No environment files found to copy")
+		return nil
+	}
+
+	copied, err := copier.CopyFiles(files)
+	if err != nil {
+		events.warn("Some files couldn't be copied: %v", err)
+	}
+	for _, file := range copied {
+		switch copier.StrategyFor(file) {
+		case env.StrategySymlink:
+			events.file("🔗 Symlinked %s", file)
+		case env.StrategyHardlink:
+			events.file("🔗 Hardlinked %s", file)
+		default:
+			events.file("📋 Copied %s", file)
+		}
+	}
+	return nil
+}
+
+// copyArtifacts copies the untracked artifacts matching the configured
+// patterns into dest, skipping the directories in skip
+func copyArtifacts(repo *git.Repository, cfg *config.Config, dest string, skip []string, opts Options, events reporter) error {
+	artifactConfig := cfg.ArtifactConfig
+	if len(artifactConfig.Patterns) == 0 {
+		return nil
+	}
+	copier, err := newCopier(repo, cfg, dest, skip, opts, events)
+	if err != nil {
+		return err
+	}
+
+	tracked, err := repo.TrackedFiles(repo.Root)
+	if err != nil {
+		return err
+	}
+
+	events.progress("Discovering artifacts...")
+	files, err := copier.DiscoverArtifacts(env.ArtifactOptions{
+		Patterns:     artifactConfig.Patterns,
+		MaxFileSize:  artifactConfig.MaxFileSize,
+		MaxTotalSize: artifactConfig.MaxTotalSize,
+		Tracked:      tracked,
+	})
+	if err != nil {
+		events.warn("Error discovering artifacts: %v", err)
+	}
+	if len(files) == 0 {
+		events.progress("No artifacts found to copy")
+		return nil
+	}
+
+	copied, err := copier.CopyFiles(files)
+	if err != nil {
+		events.warn("Some artifacts couldn't be copied: %v", err)
+	}
+	events.file("📦 Copied %d artifact files", len(copied))
+	return nil
+}
+
+// runSetup runs the setup scripts for the worktree at dest and returns
+// them: custom scripts replace configured ones, which replace the ones
+// detected from the project. Scripts of nested config files and of the
+// agent profile follow.
+func runSetup(cfg *config.Config, profile *agent.Profile, dest string, custom []string, stdout, stderr io.Writer, events reporter) []string {
+	detectedScripts := detector.DetectSetupCommands(dest)
+
+	var globalOverride string
+	if len(detectedScripts) > 0 {
+		if strings.Contains(detectedScripts[0], "pnpm") && cfg.PnpmSetup != "" {
+			globalOverride = cfg.PnpmSetup
+		} else if strings.Contains(detectedScripts[0], "npm") && cfg.NpmSetup != "" {
+			globalOverride = cfg.NpmSetup
+		} else if strings.Contains(detectedScripts[0], "yarn") && cfg.YarnSetup != "" {
+			globalOverride = cfg.YarnSetup
+		} else if cfg.DefaultSetup != "" {
+			globalOverride = cfg.DefaultSetup
+		}
+	}
+
+	scriptsToRun := scripts.DetermineScripts(
+		custom,
+		cfg.PostCreateScripts,
+		detectedScripts,
+		globalOverride,
+	)
+
+	runner := &scripts.Runner{Dir: dest, Stdout: stdout, Stderr: stderr}
+	if err := runner.RunScripts(scriptsToRun); err != nil {
+		events.warn("Some post-create scripts failed: %v", err)
+	}
+	setupCommands := append([]string(nil), scriptsToRun...)
+
+	// Scripts from nested configs run in their package, unless custom ones replaced them
+	if len(custom) == 0 {
+		for _, pkg := range cfg.PackageScripts {
+			dir := filepath.Join(dest, filepath.FromSlash(pkg.Dir))
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				events.warn("skipping scripts for %s: directory not found in worktree", pkg.Dir)
+				continue
+			}
+
+			events.progress("📦 %s", pkg.Dir)
+			runner := &scripts.Runner{Dir: dir, Stdout: stdout, Stderr: stderr}
+			if err := runner.RunScripts(pkg.Scripts); err != nil {
+				events.warn("Some post-create scripts failed in %s: %v", pkg.Dir, err)
+			}
+		}
+	}
+
+	// The agent's own setup runs last
+	if profile != nil && len(profile.Setup) > 0 {
+		if err := runner.RunScripts(profile.Setup); err != nil {
+			events.warn("Some %s setup steps failed: %v", profile.Name, err)
+		}
+		setupCommands = append(setupCommands, profile.Setup...)
+	}
+	return setupCommands
+}
+
+// resolveBranchName turns the requested name into the branch to create.
+// Names without a slash go through the branch template; names with one are
+// used as given. Characters git rejects are replaced, and a numeric suffix is
+// added when the branch, or its worktree path under layout, already exists.
+// layout is nil when the destination was given explicitly.
+func resolveBranchName(repo *git.Repository, name, template string, vars naming.Vars, layout *worktreeLayout, events reporter) (string, error) {
+	candidate := func(n int) (string, error) {
+		return naming.Suffixed(naming.Sanitize(name), n), nil
+	}
+
+	if !strings.Contains(name, "/") {
+		if template == "" {
+			template = naming.DefaultTemplate
+		}
+		vars.Slug = name
+		candidate = func(n int) (string, error) {
+			if naming.HasCounter(template) {
+				vars.Counter = n
+				return naming.Render(template, vars)
+			}
+			rendered, err := naming.Render(template, vars)
+			return naming.Suffixed(rendered, n), err
+		}
+	}
+
+	first, err := candidate(1)
+	if err != nil {
+		return "", err
+	}
+	if err := repo.CheckBranchName(first); err != nil {
+		return "", err
+	}
+
+	taken := func(branch string) bool {
+		if repo.BranchExists(branch) {
+			return true
+		}
+		if layout == nil {
+			return false
+		}
+		path, err := layout.path(branch)
+		if err != nil {
+			return false // Reported when the path is computed for real
+		}
+		_, err = os.Stat(path)
+		return err == nil
+	}
+
+	unique, err := naming.Unique(candidate, taken)
+	if err != nil {
+		return "", err
+	}
+	if unique != first {
+		events.progress("Branch %s is taken, using %s", first, unique)
+	}
+	return unique, nil
+}
+
+// worktreeLayout places new worktrees according to the worktree_root and
+// worktree_path_template settings
+type worktreeLayout struct {
+	root     string
+	template string
+	vars     naming.Vars
+}
+
+// newWorktreeLayout resolves the worktree root of cfg: "~" expands to the
+// home directory and relative roots are taken from the repository root.
+// Without a root, worktrees go to ../<repo>-worktrees as before.
+func newWorktreeLayout(repo *git.Repository, cfg *config.Config, vars naming.Vars) (*worktreeLayout, error) {
+	root := repo.GetDefaultWorktreeDir()
+	if cfg.WorktreeRoot != "" {
+		expanded, err := expandHome(cfg.WorktreeRoot)
+		if err != nil {
+			return nil, err
+		}
+		root = expanded
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(repo.Root, root)
+		}
+	}
+
+	template := cfg.WorktreePathTemplate
+	if template == "" {
+		template = naming.DefaultPathTemplate
+	}
+	if err := naming.ValidatePath(template); err != nil {
+		return nil, err
+	}
+
+	vars.Repo = repo.RepoName
+	return &worktreeLayout{root: root, template: template, vars: vars}, nil
+}
+
+// path returns where the worktree for branch goes. {slug} is the last
+// component of the branch, so suffixed branches get distinct directories.
+func (l *worktreeLayout) path(branch string) (string, error) {
+	vars := l.vars
+	vars.Branch = branch
+	vars.Slug = branch[strings.LastIndex(branch, "/")+1:]
+
+	rendered, err := naming.RenderPath(l.template, vars)
+	if err != nil {
+		return "", err
+	}
+	if rendered, err = expandHome(rendered); err != nil {
+		return "", err
+	}
+	if filepath.IsAbs(rendered) {
+		return filepath.Clean(rendered), nil
+	}
+	return filepath.Join(l.root, filepath.FromSlash(rendered)), nil
+}
+
+// describe shows the unexpanded destination, e.g. for the wizard
+func (l *worktreeLayout) describe() string {
+	if strings.HasPrefix(l.template, "~") || filepath.IsAbs(l.template) {
+		return l.template
+	}
+	return filepath.Join(l.root, filepath.FromSlash(l.template))
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+// excludeWorktree adds the top-level directory holding dest to
+// .git/info/exclude when dest lies inside the repository
+func excludeWorktree(repo *git.Repository, dest string) error {
+	abs, err := filepath.Abs(dest)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(repo.Root, abs)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+
+	top := strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]
+	return repo.Exclude("/" + top + "/")
+}
+
+// writeContextFiles renders the profile's context files into the worktree
+// at dest and keeps them out of commits: new files through
+// .git/info/exclude, extended tracked files by hiding their changes
+func writeContextFiles(repo *git.Repository, profile *agent.Profile, cfg *config.Config, dest string, info agent.ContextInfo, events reporter) error {
+	if len(profile.ContextFiles) == 0 {
+		return nil
+	}
+
+	var tmpl string
+	if cfg.ContextTemplate != "" {
+		path, err := expandHome(cfg.ContextTemplate)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(repo.Root, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read context template: %w", err)
+		}
+		tmpl = string(data)
+	}
+
+	// The repository overview and history are best effort
+	if commits, err := repo.RecentCommits(info.Base, 10); err == nil {
+		info.Commits = commits
+	}
+	if files, err := repo.TrackedFiles(dest); err == nil {
+		info.Files = agent.FileOverview(files, 40)
+	}
+
+	written, err := profile.WriteContextFiles(dest, info, tmpl)
+	for _, file := range written {
+		var hideErr error
+		if file.Extended && repo.IsTracked(dest, file.Name) {
+			hideErr = repo.SkipWorktree(dest, file.Name)
+		} else {
+			hideErr = repo.Exclude("/" + file.Name)
+		}
+		if hideErr != nil {
+			events.warn("%v", hideErr)
+		}
+
+		if file.Extended {
+			events.file("📝 Added worktree context to %s", file.Name)
+		} else {
+			events.file("📝 Wrote %s", file.Name)
+		}
+	}
+	return err
+}
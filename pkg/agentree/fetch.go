@@ -0,0 +1,67 @@
+package agentree
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/AryaLabsHQ/agentree/internal/git"
+)
+
+// FetchOptions configures Fetch
+type FetchOptions struct {
+	// Dir is a directory in the repository; empty means the current directory
+	Dir string
+	// Ref is the branch or revision that should be up to date afterwards;
+	// empty means all of them
+	Ref string
+	// Force fetches even if that happened recently
+	Force bool
+	// OnEvent receives warnings about failed fetches
+	OnEvent func(Event)
+}
+
+// Fetch brings the remote refs a worktree starts from up to date. A branch
+// that exists on a remote but not locally is fetched on its own; anything
+// else needs a full fetch, which runs at most once per git.FetchInterval
+// unless opts.Force is set or the ref is unknown so far. Failures only
+// warn, the local refs may well be good enough.
+func Fetch(ctx context.Context, opts FetchOptions) error {
+	repo, err := git.Open(ctx, opts.Dir, nil)
+	if err != nil {
+		return err
+	}
+	refreshRemotes(repo, opts.Ref, opts.Force, reporter(opts.OnEvent))
+	return nil
+}
+
+func refreshRemotes(repo *git.Repository, ref string, force bool, events reporter) {
+	repoLock, err := repo.Lock(events.waiting)
+	if err != nil {
+		events.warn("skipped git fetch: %v", err)
+		return
+	}
+	defer repoLock.Release()
+
+	if ref != "" && !repo.BranchExists(ref) {
+		if remoteRef, remoteBranch, err := repo.RemoteBranch(ref); err == nil {
+			remote := strings.TrimSuffix(remoteRef, "/"+remoteBranch)
+			if err := repo.FetchRef(remote, remoteBranch); err != nil {
+				events.warn("could not fetch %s: %v", remoteRef, err)
+			}
+			return
+		}
+	}
+
+	known := ref == ""
+	if !known {
+		_, err := repo.ResolveBase(ref)
+		known = err == nil
+	}
+	if known && !force && time.Since(repo.LastFetch()) < git.FetchInterval {
+		return
+	}
+	if err := repo.Fetch(); err != nil {
+		events.warn("git fetch failed: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package agentree
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/internal/hooks"
+	"github.com/AryaLabsHQ/agentree/internal/launch"
+	"github.com/AryaLabsHQ/agentree/internal/metadata"
+)
+
+// RemoveOptions configures Remove
+type RemoveOptions struct {
+	// Dir is a directory in the repository; empty means the current directory
+	Dir string `json:"-"`
+	// Force also removes worktrees with uncommitted changes
+	Force bool `json:"force,omitempty"`
+	// DeleteBranch also deletes the worktree's local branch
+	DeleteBranch bool `json:"delete_branch,omitempty"`
+	// Stdout and Stderr receive the output of hooks; nil discards it
+	Stdout io.Writer `json:"-"`
+	Stderr io.Writer `json:"-"`
+	// OnEvent receives the progress of the removal
+	OnEvent func(Event) `json:"-"`
+}
+
+// Remove removes the worktree of a branch or at a path, and its metadata.
+// Pre-remove hooks that fail stop it.
+func Remove(ctx context.Context, target string, opts RemoveOptions) error {
+	events := reporter(opts.OnEvent)
+	repo, err := git.Open(ctx, opts.Dir, nil)
+	if err != nil {
+		return err
+	}
+	info, err := findWorktree(repo, target)
+	if err != nil {
+		return err
+	}
+	// Work from the main checkout, the worktree's own directory goes away
+	if worktrees, err := repo.Worktrees(); err == nil && len(worktrees) > 0 && worktrees[0].Path != repo.Root {
+		if repo, err = git.Open(ctx, worktrees[0].Path, nil); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := loadConfig(repo, Options{Dir: opts.Dir})
+	if err != nil {
+		return err
+	}
+	var record *metadata.Worktree
+	if store, err := openStore(repo); err == nil {
+		record, err = store.Get(info.Branch)
+		if err != nil && !errors.Is(err, metadata.ErrNotFound) {
+			events.warn("%v", err)
+		}
+	}
+	if err := runHooks(repo, cfg, hookPayload(hooks.PreRemove, repo, info.Branch, info.Path, record), opts.Stdout, opts.Stderr, events); err != nil {
+		return err
+	}
+
+	if err := removeWorktree(repo, info, opts.Force, opts.DeleteBranch, events); err != nil {
+		return err
+	}
+	_ = runHooks(repo, cfg, hookPayload(hooks.PostRemove, repo, info.Branch, info.Path, record), opts.Stdout, opts.Stderr, events)
+	return nil
+}
+
+func removeWorktree(repo *git.Repository, info *git.WorktreeInfo, force, deleteBranch bool, events reporter) error {
+	repoLock, err := repo.Lock(events.waiting)
+	if err != nil {
+		return err
+	}
+	defer repoLock.Release()
+
+	// git worktree remove deletes symlinks without following them, so
+	// files linked from the main checkout are safe
+	if err := repo.RemoveWorktree(info.Path, force); err != nil {
+		return err
+	}
+	events.done("✅ Removed worktree %s", info.Path)
+
+	// Forget the worktree, mentioning an agent that is still running in it
+	if store, err := openStore(repo); err == nil {
+		if record, err := store.Get(info.Branch); err == nil && launch.Running(record.PID) {
+			events.warn("%s is still running (pid %d)", record.Agent, record.PID)
+		}
+		if err := store.Delete(info.Branch); err != nil {
+			events.warn("could not remove worktree metadata: %v", err)
+		}
+	}
+
+	if deleteBranch && info.Branch != "" {
+		if err := repo.DeleteBranch(info.Branch); err != nil {
+			events.warn("%v", err)
+		} else {
+			events.done("🗑️  Deleted branch %s", info.Branch)
+		}
+	}
+	return nil
+}
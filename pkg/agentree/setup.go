@@ -0,0 +1,70 @@
+package agentree
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/AryaLabsHQ/agentree/internal/agent"
+	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/internal/metadata"
+)
+
+// SetupOptions configures Setup
+type SetupOptions struct {
+	// Dir is a directory in the repository; empty means the current directory
+	Dir string `json:"-"`
+	// Scripts replace the configured and detected setup scripts
+	Scripts []string `json:"scripts,omitempty"`
+	// Stdout and Stderr receive the output of setup scripts; nil discards it
+	Stdout io.Writer `json:"-"`
+	Stderr io.Writer `json:"-"`
+	// OnEvent receives the progress of the setup
+	OnEvent func(Event) `json:"-"`
+}
+
+// Setup runs the setup scripts of the worktree of a branch or at a path
+// again, the way Create does, and returns the commands it ran. Scripts
+// that fail are reported as warnings.
+func Setup(ctx context.Context, target string, opts SetupOptions) ([]string, error) {
+	events := reporter(opts.OnEvent)
+	repo, err := git.Open(ctx, opts.Dir, nil)
+	if err != nil {
+		return nil, err
+	}
+	info, err := findWorktree(repo, target)
+	if err != nil {
+		return nil, err
+	}
+
+	// The agent the worktree was made for brings its own setup steps
+	var agentName string
+	if store, err := openStore(repo); err == nil {
+		record, err := store.Get(info.Branch)
+		if err != nil && !errors.Is(err, metadata.ErrNotFound) {
+			events.warn("%v", err)
+		}
+		if record != nil {
+			agentName = record.Agent
+		}
+	}
+	cfg, err := loadConfig(repo, Options{Dir: opts.Dir, Scripts: opts.Scripts, Agent: agentName})
+	if err != nil {
+		return nil, err
+	}
+	var profile *agent.Profile
+	if cfg.Agent != "" {
+		if profile, err = agent.Resolve(cfg.Agent, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	stdout, stderr := opts.Stdout, opts.Stderr
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	return runSetup(cfg, profile, info.Path, opts.Scripts, stdout, stderr, events), nil
+}
@@ -0,0 +1,79 @@
+package agentree
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/internal/metadata"
+)
+
+// WorktreeStatus is a worktree with the state of its branch
+type WorktreeStatus struct {
+	Worktree
+	// Dirty reports uncommitted changes to tracked files
+	Dirty bool `json:"dirty"`
+	// Ahead counts the commits the base lacks, Behind the commits of the
+	// base the branch lacks; both are 0 without a base
+	Ahead  int `json:"ahead"`
+	Behind int `json:"behind"`
+	// Files, Insertions and Deletions sum up the changes since the branch
+	// forked from its base, including uncommitted ones
+	Files      int `json:"files"`
+	Insertions int `json:"insertions"`
+	Deletions  int `json:"deletions"`
+	// Log is the output file of the background agent, empty if none ran
+	Log string `json:"log,omitempty"`
+}
+
+// StatusOptions configures Status
+type StatusOptions struct {
+	// Dir is a directory in the repository; empty means the current directory
+	Dir string
+}
+
+// Status describes the worktree of a branch or at a path
+func Status(ctx context.Context, target string, opts StatusOptions) (*WorktreeStatus, error) {
+	repo, err := git.Open(ctx, opts.Dir, nil)
+	if err != nil {
+		return nil, err
+	}
+	info, err := findWorktree(repo, target)
+	if err != nil {
+		return nil, err
+	}
+	store, err := openStore(repo)
+	if err != nil {
+		return nil, err
+	}
+	record, err := store.Get(info.Branch)
+	if err != nil && !errors.Is(err, metadata.ErrNotFound) {
+		return nil, err
+	}
+
+	status := &WorktreeStatus{Worktree: *newWorktree(info.Branch, info.Path, record)}
+	if status.Dirty, err = repo.Dirty(info.Path); err != nil {
+		return nil, err
+	}
+	if logPath := store.LogPath(info.Branch); fileExists(logPath) {
+		status.Log = logPath
+	}
+
+	// A base that is gone leaves the comparison out
+	if base := status.Base; base != "" {
+		if _, err := repo.ResolveBase(base); err == nil {
+			status.Ahead, _ = repo.CommitsAhead(info.Path, base)
+			status.Behind, _ = repo.CommitsBehind(info.Path, base)
+			if diff, err := repo.Diff(info.Path, base); err == nil {
+				status.Files, status.Insertions, status.Deletions = diff.Files, diff.Insertions, diff.Deletions
+			}
+		}
+	}
+	return status, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
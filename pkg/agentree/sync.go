@@ -0,0 +1,106 @@
+package agentree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/AryaLabsHQ/agentree/internal/git"
+	"github.com/AryaLabsHQ/agentree/internal/hooks"
+	"github.com/AryaLabsHQ/agentree/internal/metadata"
+)
+
+// SyncOptions configures Sync
+type SyncOptions struct {
+	// Dir is a directory in the repository; empty means the current directory
+	Dir string `json:"-"`
+	// Onto is the branch or revision to rebase onto; empty means the base
+	// the worktree was created from
+	Onto string `json:"onto,omitempty"`
+	// Offline skips fetching; Fetch fetches even if that happened recently
+	Offline bool `json:"offline,omitempty"`
+	Fetch   bool `json:"fetch,omitempty"`
+	// Stdout and Stderr receive the output of hooks; nil discards it
+	Stdout io.Writer `json:"-"`
+	Stderr io.Writer `json:"-"`
+	// OnEvent receives the progress of the sync
+	OnEvent func(Event) `json:"-"`
+}
+
+// Sync rebases the branch of the worktree of a branch or at a path onto
+// its base, after fetching it. A base branch that tracks a remote one is
+// replaced by its upstream unless it has commits of its own, so the
+// worktree gets what was pushed there.
+// Worktrees with uncommitted changes are left alone, and a rebase that
+// runs into conflicts is undone. Post-sync hooks run once the worktree is
+// up to date.
+func Sync(ctx context.Context, target string, opts SyncOptions) (*Worktree, error) {
+	events := reporter(opts.OnEvent)
+	repo, err := git.Open(ctx, opts.Dir, nil)
+	if err != nil {
+		return nil, err
+	}
+	info, err := findWorktree(repo, target)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := loadConfig(repo, Options{Dir: opts.Dir})
+	if err != nil {
+		return nil, err
+	}
+
+	var record *metadata.Worktree
+	if store, err := openStore(repo); err == nil {
+		record, err = store.Get(info.Branch)
+		if err != nil && !errors.Is(err, metadata.ErrNotFound) {
+			events.warn("%v", err)
+		}
+	}
+	wt := newWorktree(info.Branch, info.Path, record)
+
+	onto := opts.Onto
+	if onto == "" {
+		onto = wt.Base
+	}
+	if onto == "" {
+		return nil, fmt.Errorf("no base recorded for %s; say what to sync with", target)
+	}
+
+	dirty, err := repo.Dirty(info.Path)
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return nil, fmt.Errorf("%s has uncommitted changes; commit or stash them first", info.Path)
+	}
+
+	if !opts.Offline {
+		refreshRemotes(repo, onto, opts.Fetch, events)
+	}
+	if repo.BranchExists(onto) {
+		// Local commits that weren't pushed yet win over the upstream
+		if upstream, err := repo.Upstream(onto); err == nil && repo.IsAncestor(onto, upstream) {
+			onto = upstream
+		}
+	}
+
+	behind, err := repo.CommitsBehind(info.Path, onto)
+	if err != nil {
+		return nil, err
+	}
+	if behind == 0 {
+		events.done("✓ %s is up to date with %s", wt.Branch, onto)
+	} else {
+		events.progress("Rebasing %s onto %s...", wt.Branch, onto)
+		if err := repo.Rebase(info.Path, onto); err != nil {
+			return nil, err
+		}
+		events.done("✅ Rebased %s onto %s (%d new commits)", wt.Branch, onto, behind)
+	}
+
+	payload := hookPayload(hooks.PostSync, repo, info.Branch, info.Path, record)
+	payload.Base = onto
+	_ = runHooks(repo, cfg, payload, opts.Stdout, opts.Stderr, events)
+	return wt, nil
+}